@@ -4,15 +4,21 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	"github.com/razvan/library-app/internal/domain"
 	"github.com/razvan/library-app/internal/handlers"
 	"github.com/razvan/library-app/internal/middleware"
 	"github.com/razvan/library-app/internal/repository"
 	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/auth"
 	"github.com/razvan/library-app/pkg/database"
+	"github.com/razvan/library-app/pkg/mailer"
 )
 
 func main() {
@@ -21,6 +27,8 @@ func main() {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	utils.TrustProxyHeaders = getEnv("TRUST_PROXY_HEADERS", "false") == "true"
+
 	// Database configuration
 	dbConfig := database.Config{
 		Host:     getEnv("DB_HOST", "localhost"),
@@ -43,23 +51,104 @@ func main() {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	bookRepo := repository.NewBookRepository(db)
+	taxonomyRepo := repository.NewTaxonomyRepository(db)
+	seriesRepo := repository.NewSeriesRepository(db)
+	editionRepo := repository.NewEditionRepository(db)
 	userBookRepo := repository.NewUserBookRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	webAuthnRepo := repository.NewWebAuthnRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	lockoutRepo := repository.NewLockoutRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
+	catalogImportRepo := repository.NewCatalogImportRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+
+	// Signing keys. DEV_MODE allows the default secret and a throwaway key.
+	devMode := getEnv("DEV_MODE", "false") == "true"
+	jwtSecret := loadJWTSecret(devMode)
+	signingKeys := loadSigningKeys(devMode)
 
 	// Initialize services
-	jwtSecret := getEnv("JWT_SECRET", "your-secret-key-change-in-production")
 	appName := getEnv("APP_NAME", "LibraryApp")
-	authService := service.NewAuthService(userRepo, jwtSecret, appName)
-	bookService := service.NewBookService(bookRepo)
-	userBookService := service.NewUserBookService(userBookRepo, bookRepo)
+	frontendURL := strings.TrimRight(getEnv("FRONTEND_URL", "http://localhost:3000"), "/")
+	mail, err := mailer.New(mailer.Config{
+		Driver:   getEnv("MAIL_DRIVER", "log"),
+		From:     getEnv("MAIL_FROM", "no-reply@localhost"),
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     getEnv("SMTP_PORT", "587"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+	verificationPolicy, err := service.ParseEmailVerificationPolicy(getEnv("EMAIL_VERIFICATION_POLICY", "off"))
+	if err != nil {
+		log.Fatalf("Invalid EMAIL_VERIFICATION_POLICY: %v", err)
+	}
+	sessionService := service.NewSessionService(sessionRepo, refreshTokenRepo)
+	emailVerificationService := service.NewEmailVerificationService(userRepo, mail, verificationPolicy, jwtSecret, appName, frontendURL)
+	lockoutService := service.NewLockoutService(lockoutRepo, service.LockoutPolicy{
+		AccountThreshold: getEnvInt("LOCKOUT_ACCOUNT_THRESHOLD", 5),
+		IPThreshold:      getEnvInt("LOCKOUT_IP_THRESHOLD", 20),
+		Window:           getEnvDuration("LOCKOUT_WINDOW", 15*time.Minute),
+		BaseLockout:      getEnvDuration("LOCKOUT_DURATION", time.Minute),
+		MaxLockout:       getEnvDuration("LOCKOUT_MAX_DURATION", time.Hour),
+	})
+	auditService := service.NewAuditService(auditRepo, userRepo)
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, twoFactorRepo, webAuthnRepo, sessionService, emailVerificationService, lockoutService, auditService, loadPasswordPolicy(), signingKeys, jwtSecret, appName)
+	bookService := service.NewBookService(bookRepo, auditService)
+	taxonomyService := service.NewTaxonomyService(taxonomyRepo, auditService)
+	seriesService := service.NewSeriesService(seriesRepo, bookRepo, auditService)
+	editionService := service.NewEditionService(editionRepo, bookRepo, auditService)
+	catalogImportService := service.NewCatalogImportService(catalogImportRepo, bookRepo, editionRepo, auditService, getEnv("CATALOG_IMPORT_PATH", "./imports"))
+	webAuthnService, err := service.NewWebAuthnService(service.WebAuthnConfig{
+		RPID:          getEnv("WEBAUTHN_RP_ID", "localhost"),
+		RPDisplayName: appName,
+		RPOrigins:     strings.Split(getEnv("WEBAUTHN_RP_ORIGINS", "http://localhost:3000"), ","),
+	}, webAuthnRepo, userRepo, authService, auditService)
+	if err != nil {
+		log.Fatalf("Failed to configure WebAuthn: %v", err)
+	}
+	roleService := service.NewRoleService(roleRepo, userRepo, sessionService, auditService)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, auditService)
+	passwordService := service.NewPasswordService(userRepo, passwordResetRepo, sessionService, authService, auditService, mail, appName, frontendURL)
+	profileService := service.NewProfileService(userRepo, sessionService, authService, emailVerificationService, auditService, mail,
+		getEnvDuration("ACCOUNT_DELETION_GRACE_PERIOD", 7*24*time.Hour), appName)
+	userAdminService := service.NewUserAdminService(userRepo, roleRepo, sessionService, passwordService, auditService)
+	oidcService := service.NewOIDCService(loadOIDCProviders(frontendURL), identityRepo, userRepo, authService)
+	userBookService := service.NewUserBookService(userBookRepo, bookRepo, auditService)
+	dataExportService := service.NewDataExportService(dataExportRepo, userRepo, userBookRepo, sessionRepo, identityRepo, webAuthnRepo, apiKeyRepo, auditRepo, mail,
+		getEnv("DATA_EXPORT_PATH", "./exports"), getEnvDuration("DATA_EXPORT_TTL", 24*time.Hour), appName, frontendURL)
 
 	// Initialize handlers
 	uploadDir := getEnv("UPLOAD_PATH", "./uploads")
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(authService, sessionService, emailVerificationService)
 	bookHandler := handlers.NewBookHandler(bookService, uploadDir)
+	taxonomyHandler := handlers.NewTaxonomyHandler(taxonomyService, bookService)
+	seriesHandler := handlers.NewSeriesHandler(seriesService, bookService)
+	editionHandler := handlers.NewEditionHandler(editionService, uploadDir)
+	catalogImportHandler := handlers.NewCatalogImportHandler(catalogImportService)
 	userBookHandler := handlers.NewUserBookHandler(userBookService)
+	webAuthnHandler := handlers.NewWebAuthnHandler(webAuthnService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService)
+	passwordHandler := handlers.NewPasswordHandler(passwordService)
+	roleHandler := handlers.NewRoleHandler(roleService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	jwksHandler := handlers.NewJWKSHandler(signingKeys)
+	userAdminHandler := handlers.NewUserAdminHandler(userAdminService)
+	profileHandler := handlers.NewProfileHandler(profileService, uploadDir)
+	dataExportHandler := handlers.NewDataExportHandler(dataExportService)
+	auditHandler := handlers.NewAuditHandler(auditService)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwtSecret)
+	authMiddleware := middleware.NewAuthMiddleware(signingKeys, sessionService, apiKeyService)
+	emailVerificationMiddleware := middleware.NewEmailVerificationMiddleware(emailVerificationService)
 	corsMiddleware := middleware.NewCORSMiddleware(getEnv("ALLOWED_ORIGINS", "http://localhost:3000"))
 
 	// Setup router
@@ -70,6 +159,9 @@ func main() {
 	r.Use(corsMiddleware.Handler)
 	r.Use(middleware.SecurityHeaders)
 
+	// Public keys for services that verify access tokens
+	r.HandleFunc("/.well-known/jwks.json", jwksHandler.GetJWKS).Methods("GET")
+
 	// API routes
 	api := r.PathPrefix("/api").Subrouter()
 
@@ -80,46 +172,184 @@ func main() {
 	auth := api.PathPrefix("/auth").Subrouter()
 	auth.HandleFunc("/register", authHandler.Register).Methods("POST")
 	auth.HandleFunc("/login", authHandler.Login).Methods("POST")
+	auth.HandleFunc("/verify-email", authHandler.VerifyEmail).Methods("POST")
+	auth.HandleFunc("/verify-email/resend", authHandler.ResendVerification).Methods("POST")
+	auth.HandleFunc("/password/forgot", passwordHandler.ForgotPassword).Methods("POST")
+	auth.HandleFunc("/password/reset", passwordHandler.ResetPassword).Methods("POST")
 	auth.HandleFunc("/2fa/verify-login", authHandler.VerifyTwoFactorLogin).Methods("POST")
-
-	// Protected auth routes
+	auth.HandleFunc("/refresh", authHandler.RefreshToken).Methods("POST")
+	auth.HandleFunc("/webauthn/2fa/begin", webAuthnHandler.BeginSecondFactor).Methods("POST")
+	auth.HandleFunc("/webauthn/2fa/finish", webAuthnHandler.FinishSecondFactor).Methods("POST")
+	auth.HandleFunc("/webauthn/login/begin", webAuthnHandler.BeginLogin).Methods("POST")
+	auth.HandleFunc("/webauthn/login/finish", webAuthnHandler.FinishLogin).Methods("POST")
+	auth.HandleFunc("/oidc/providers", oidcHandler.GetProviders).Methods("GET")
+	auth.HandleFunc("/oidc/link/confirm", oidcHandler.ConfirmLink).Methods("POST")
+	auth.HandleFunc("/oidc/{provider}/start", oidcHandler.Start).Methods("GET")
+	auth.HandleFunc("/oidc/{provider}/callback", oidcHandler.Callback).Methods("GET")
+
+	auth.Handle("/me", authMiddleware.Authenticate(http.HandlerFunc(profileHandler.GetMe))).Methods("GET")
+
+	// Protected auth routes (login sessions only, not API keys)
 	authProtected := auth.PathPrefix("").Subrouter()
 	authProtected.Use(authMiddleware.Authenticate)
-	authProtected.HandleFunc("/me", authHandler.GetMe).Methods("GET")
+	authProtected.Use(authMiddleware.RequireSession)
+	authProtected.HandleFunc("/me", profileHandler.UpdateMe).Methods("PATCH")
+	authProtected.HandleFunc("/me", profileHandler.DeleteMe).Methods("DELETE")
+	authProtected.HandleFunc("/me/avatar", profileHandler.UploadAvatar).Methods("POST")
+	authProtected.HandleFunc("/me/exports", dataExportHandler.GetExports).Methods("GET")
+	authProtected.HandleFunc("/me/exports", dataExportHandler.RequestExport).Methods("POST")
+	authProtected.HandleFunc("/me/exports/{id}/download", dataExportHandler.DownloadExport).Methods("GET")
+	authProtected.HandleFunc("/me/identities", oidcHandler.LinkIdentity).Methods("POST")
+	authProtected.HandleFunc("/password/change", passwordHandler.ChangePassword).Methods("POST")
 	authProtected.HandleFunc("/2fa/setup", authHandler.SetupTwoFactor).Methods("POST")
 	authProtected.HandleFunc("/2fa/verify", authHandler.VerifyTwoFactorSetup).Methods("POST")
 	authProtected.HandleFunc("/2fa/disable", authHandler.DisableTwoFactor).Methods("POST")
-
-	// Admin only routes
+	authProtected.HandleFunc("/2fa/recovery-codes", authHandler.GetRecoveryCodesStatus).Methods("GET")
+	authProtected.HandleFunc("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes).Methods("POST")
+	authProtected.HandleFunc("/webauthn/register/begin", webAuthnHandler.BeginRegistration).Methods("POST")
+	authProtected.HandleFunc("/webauthn/register/finish", webAuthnHandler.FinishRegistration).Methods("POST")
+	authProtected.HandleFunc("/webauthn/credentials", webAuthnHandler.GetCredentials).Methods("GET")
+	authProtected.HandleFunc("/webauthn/credentials/{id}", webAuthnHandler.DeleteCredential).Methods("DELETE")
+	authProtected.HandleFunc("/logout", authHandler.Logout).Methods("POST")
+	authProtected.HandleFunc("/logout-all", authHandler.LogoutAll).Methods("POST")
+	authProtected.HandleFunc("/sessions", authHandler.GetSessions).Methods("GET")
+	authProtected.HandleFunc("/sessions/{id}", authHandler.RevokeSession).Methods("DELETE")
+	authProtected.HandleFunc("/api-keys", apiKeyHandler.GetKeys).Methods("GET")
+	authProtected.HandleFunc("/api-keys", apiKeyHandler.CreateKey).Methods("POST")
+	authProtected.HandleFunc("/api-keys/{id}", apiKeyHandler.RevokeKey).Methods("DELETE")
+
+	// User management routes
 	authAdmin := auth.PathPrefix("").Subrouter()
 	authAdmin.Use(authMiddleware.Authenticate)
-	authAdmin.Use(authMiddleware.RequireAdmin)
-	authAdmin.HandleFunc("/users/{id}/make-admin", authHandler.MakeAdmin).Methods("POST")
+	authAdmin.Use(authMiddleware.RequireSession)
+	authAdmin.Use(authMiddleware.RequirePermission(domain.PermissionUsersManage))
+	authAdmin.HandleFunc("/roles", roleHandler.GetRoles).Methods("GET")
+	authAdmin.HandleFunc("/users/{id}/roles", roleHandler.AssignRole).Methods("POST")
+	authAdmin.HandleFunc("/users/{id}/roles/{role}", roleHandler.RevokeRole).Methods("DELETE")
+	authAdmin.HandleFunc("/users/{id}/2fa/reset", authHandler.ResetTwoFactor).Methods("POST")
+	authAdmin.HandleFunc("/users/{id}/unlock", authHandler.UnlockAccount).Methods("POST")
+
+	// Admin user management
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(authMiddleware.Authenticate)
+	admin.Use(authMiddleware.RequireSession)
+	admin.Use(authMiddleware.RequirePermission(domain.PermissionUsersManage))
+	admin.HandleFunc("/users", userAdminHandler.ListUsers).Methods("GET")
+	admin.HandleFunc("/users/{id}", userAdminHandler.GetUser).Methods("GET")
+	admin.HandleFunc("/users/{id}", userAdminHandler.DeleteUser).Methods("DELETE")
+	admin.HandleFunc("/users/{id}/demote", userAdminHandler.DemoteUser).Methods("POST")
+	admin.HandleFunc("/users/{id}/suspend", userAdminHandler.SuspendUser).Methods("POST")
+	admin.HandleFunc("/users/{id}/unsuspend", userAdminHandler.UnsuspendUser).Methods("POST")
+	admin.HandleFunc("/users/{id}/password-reset", userAdminHandler.ForcePasswordReset).Methods("POST")
+	admin.HandleFunc("/audit", auditHandler.ListEvents).Methods("GET")
+	admin.HandleFunc("/audit/verify", auditHandler.VerifyChain).Methods("GET")
 
 	// Book routes (public read, admin write)
 	books := api.PathPrefix("/books").Subrouter()
 	books.HandleFunc("", bookHandler.GetAllBooks).Methods("GET")
 	books.HandleFunc("/search", bookHandler.SearchBooks).Methods("GET")
+	books.HandleFunc("/isbn/{isbn}", bookHandler.GetBookByISBN).Methods("GET")
 	books.HandleFunc("/{id}", bookHandler.GetBook).Methods("GET")
+	books.HandleFunc("/{id}/editions", editionHandler.GetBookEditions).Methods("GET")
 
-	// Protected book routes (admin only)
+	// Protected book routes (catalog editors only)
 	booksAdmin := books.PathPrefix("").Subrouter()
 	booksAdmin.Use(authMiddleware.Authenticate)
-	booksAdmin.Use(authMiddleware.RequireAdmin)
+	booksAdmin.Use(authMiddleware.RequireScope(domain.ScopeCatalogWrite))
+	booksAdmin.Use(authMiddleware.RequirePermission(domain.PermissionBooksWrite))
 	booksAdmin.HandleFunc("", bookHandler.CreateBook).Methods("POST")
 	booksAdmin.HandleFunc("/{id}", bookHandler.UpdateBook).Methods("PUT")
 	booksAdmin.HandleFunc("/{id}", bookHandler.DeleteBook).Methods("DELETE")
 	booksAdmin.HandleFunc("/{id}/cover", bookHandler.UploadCover).Methods("POST")
+	booksAdmin.HandleFunc("/{id}/editions", editionHandler.CreateEdition).Methods("POST")
+
+	// Editions whose ISBN has to be corrected, before the /{id} routes
+	legacyISBNs := api.PathPrefix("/editions/legacy-isbns").Subrouter()
+	legacyISBNs.Use(authMiddleware.Authenticate)
+	legacyISBNs.Use(authMiddleware.RequireScope(domain.ScopeCatalogRead))
+	legacyISBNs.Use(authMiddleware.RequirePermission(domain.PermissionBooksWrite))
+	legacyISBNs.HandleFunc("", editionHandler.GetLegacyISBNEditions).Methods("GET")
+
+	// Edition routes (public read, catalog editors write)
+	editions := api.PathPrefix("/editions").Subrouter()
+	editions.HandleFunc("/{id}", editionHandler.GetEdition).Methods("GET")
+
+	editionsAdmin := editions.PathPrefix("").Subrouter()
+	editionsAdmin.Use(authMiddleware.Authenticate)
+	editionsAdmin.Use(authMiddleware.RequireScope(domain.ScopeCatalogWrite))
+	editionsAdmin.Use(authMiddleware.RequirePermission(domain.PermissionBooksWrite))
+	editionsAdmin.HandleFunc("/{id}", editionHandler.UpdateEdition).Methods("PUT")
+	editionsAdmin.HandleFunc("/{id}", editionHandler.DeleteEdition).Methods("DELETE")
+	editionsAdmin.HandleFunc("/{id}/cover", editionHandler.UploadCover).Methods("POST")
+
+	// Bulk catalog imports
+	imports := api.PathPrefix("/imports").Subrouter()
+	imports.Use(authMiddleware.Authenticate)
+	imports.Use(authMiddleware.RequirePermission(domain.PermissionCatalogImport))
+
+	importsRead := imports.PathPrefix("").Subrouter()
+	importsRead.Use(authMiddleware.RequireScope(domain.ScopeCatalogRead))
+	importsWrite := imports.PathPrefix("").Subrouter()
+	importsWrite.Use(authMiddleware.RequireScope(domain.ScopeCatalogWrite))
+
+	importsRead.HandleFunc("", catalogImportHandler.GetImports).Methods("GET")
+	importsWrite.HandleFunc("", catalogImportHandler.StartImport).Methods("POST")
+	importsRead.HandleFunc("/{id}", catalogImportHandler.GetImport).Methods("GET")
+	importsRead.HandleFunc("/{id}/errors", catalogImportHandler.GetImportErrors).Methods("GET")
+
+	// Genre and tag routes (public read, admin write)
+	genres := api.PathPrefix("/genres").Subrouter()
+	genres.HandleFunc("", taxonomyHandler.GetGenres).Methods("GET")
+	genres.HandleFunc("/{id}", taxonomyHandler.GetGenre).Methods("GET")
+	genres.HandleFunc("/{id}/books", taxonomyHandler.GetGenreBooks).Methods("GET")
+
+	tags := api.PathPrefix("/tags").Subrouter()
+	tags.HandleFunc("", taxonomyHandler.GetTags).Methods("GET")
+	tags.HandleFunc("/{id}", taxonomyHandler.GetTag).Methods("GET")
+	tags.HandleFunc("/{id}/books", taxonomyHandler.GetTagBooks).Methods("GET")
+
+	genresAdmin := genres.PathPrefix("").Subrouter()
+	genresAdmin.Use(authMiddleware.Authenticate)
+	genresAdmin.Use(authMiddleware.RequireScope(domain.ScopeCatalogWrite))
+	genresAdmin.Use(authMiddleware.RequirePermission(domain.PermissionTaxonomyManage))
+	genresAdmin.HandleFunc("", taxonomyHandler.CreateGenre).Methods("POST")
+	genresAdmin.HandleFunc("/{id}", taxonomyHandler.UpdateGenre).Methods("PUT")
+	genresAdmin.HandleFunc("/{id}", taxonomyHandler.DeleteGenre).Methods("DELETE")
+
+	tagsAdmin := tags.PathPrefix("").Subrouter()
+	tagsAdmin.Use(authMiddleware.Authenticate)
+	tagsAdmin.Use(authMiddleware.RequireScope(domain.ScopeCatalogWrite))
+	tagsAdmin.Use(authMiddleware.RequirePermission(domain.PermissionTaxonomyManage))
+	tagsAdmin.HandleFunc("", taxonomyHandler.CreateTag).Methods("POST")
+	tagsAdmin.HandleFunc("/{id}", taxonomyHandler.UpdateTag).Methods("PUT")
+	tagsAdmin.HandleFunc("/{id}", taxonomyHandler.DeleteTag).Methods("DELETE")
+
+	// Series routes (public read, catalog editors write)
+	series := api.PathPrefix("/series").Subrouter()
+	series.HandleFunc("", seriesHandler.GetAllSeries).Methods("GET")
+	series.HandleFunc("/{id}", seriesHandler.GetSeries).Methods("GET")
+	series.HandleFunc("/{id}/books", seriesHandler.GetSeriesBooks).Methods("GET")
+
+	seriesAdmin := series.PathPrefix("").Subrouter()
+	seriesAdmin.Use(authMiddleware.Authenticate)
+	seriesAdmin.Use(authMiddleware.RequireScope(domain.ScopeCatalogWrite))
+	seriesAdmin.Use(authMiddleware.RequirePermission(domain.PermissionBooksWrite))
+	seriesAdmin.HandleFunc("", seriesHandler.CreateSeries).Methods("POST")
+	seriesAdmin.HandleFunc("/{id}", seriesHandler.UpdateSeries).Methods("PUT")
+	seriesAdmin.HandleFunc("/{id}", seriesHandler.DeleteSeries).Methods("DELETE")
+	seriesAdmin.HandleFunc("/{id}/books/{book_id}", seriesHandler.SetVolume).Methods("PUT")
+	seriesAdmin.HandleFunc("/{id}/books/{book_id}", seriesHandler.RemoveVolume).Methods("DELETE")
 
 	// Author routes
 	authors := api.PathPrefix("/authors").Subrouter()
 	authors.HandleFunc("", bookHandler.GetAllAuthors).Methods("GET")
 	authors.HandleFunc("/{id}", bookHandler.GetAuthor).Methods("GET")
 
-	// Protected author routes (admin only)
+	// Protected author routes (catalog editors only)
 	authorsAdmin := authors.PathPrefix("").Subrouter()
 	authorsAdmin.Use(authMiddleware.Authenticate)
-	authorsAdmin.Use(authMiddleware.RequireAdmin)
+	authorsAdmin.Use(authMiddleware.RequireScope(domain.ScopeCatalogWrite))
+	authorsAdmin.Use(authMiddleware.RequirePermission(domain.PermissionAuthorsWrite))
 	authorsAdmin.HandleFunc("", bookHandler.CreateAuthor).Methods("POST")
 	authorsAdmin.HandleFunc("/{id}", bookHandler.UpdateAuthor).Methods("PUT")
 	authorsAdmin.HandleFunc("/{id}", bookHandler.DeleteAuthor).Methods("DELETE")
@@ -128,15 +358,26 @@ func main() {
 	userBooks := api.PathPrefix("/user").Subrouter()
 	userBooks.Use(authMiddleware.Authenticate)
 
+	userBooksRead := userBooks.PathPrefix("").Subrouter()
+	userBooksRead.Use(authMiddleware.RequireScope(domain.ScopeLibraryRead))
+	userBooksWrite := userBooks.PathPrefix("").Subrouter()
+	userBooksWrite.Use(authMiddleware.RequireScope(domain.ScopeLibraryWrite))
+
 	// Reading lists
-	userBooks.HandleFunc("/reading-list", userBookHandler.GetReadingList).Methods("GET")
-	userBooks.HandleFunc("/books/{id}/reading-list", userBookHandler.AddToReadingList).Methods("POST")
-	userBooks.HandleFunc("/books/{id}/reading-list", userBookHandler.RemoveFromReadingList).Methods("DELETE")
+	userBooksRead.HandleFunc("/reading-list", userBookHandler.GetReadingList).Methods("GET")
+	userBooksRead.HandleFunc("/series", userBookHandler.GetSeriesProgress).Methods("GET")
+	userBooksWrite.HandleFunc("/books/{id}/reading-list", userBookHandler.AddToReadingList).Methods("POST")
+	userBooksWrite.HandleFunc("/books/{id}/reading-list", userBookHandler.RemoveFromReadingList).Methods("DELETE")
 
 	// Favorites
-	userBooks.HandleFunc("/favorites", userBookHandler.GetFavorites).Methods("GET")
-	userBooks.HandleFunc("/books/{id}/favorites", userBookHandler.AddToFavorites).Methods("POST")
-	userBooks.HandleFunc("/books/{id}/favorites", userBookHandler.RemoveFromFavorites).Methods("DELETE")
+	userBooksRead.HandleFunc("/favorites", userBookHandler.GetFavorites).Methods("GET")
+	userBooksWrite.HandleFunc("/books/{id}/favorites", userBookHandler.AddToFavorites).Methods("POST")
+	userBooksWrite.HandleFunc("/books/{id}/favorites", userBookHandler.RemoveFromFavorites).Methods("DELETE")
+
+	// Ratings
+	userBooksRead.HandleFunc("/books/{id}/rating", userBookHandler.GetRating).Methods("GET")
+	userBooksWrite.HandleFunc("/books/{id}/rating", userBookHandler.RateBook).Methods("PUT")
+	userBooksWrite.HandleFunc("/books/{id}/rating", userBookHandler.RemoveRating).Methods("DELETE")
 
 	// Comments
 	comments := api.PathPrefix("/books/{id}/comments").Subrouter()
@@ -144,20 +385,37 @@ func main() {
 
 	commentsProtected := comments.PathPrefix("").Subrouter()
 	commentsProtected.Use(authMiddleware.Authenticate)
+	commentsProtected.Use(authMiddleware.RequireScope(domain.ScopeLibraryWrite))
+	commentsProtected.Use(emailVerificationMiddleware.RequireVerified)
 	commentsProtected.HandleFunc("", userBookHandler.CreateComment).Methods("POST")
 
+	commentWrite := authMiddleware.RequireScope(domain.ScopeLibraryWrite)
 	api.HandleFunc("/comments/{id}", userBookHandler.UpdateComment).
 		Methods("PUT").
-		Handler(authMiddleware.Authenticate(http.HandlerFunc(userBookHandler.UpdateComment)))
+		Handler(authMiddleware.Authenticate(commentWrite(emailVerificationMiddleware.RequireVerified(http.HandlerFunc(userBookHandler.UpdateComment)))))
 	api.HandleFunc("/comments/{id}", userBookHandler.DeleteComment).
 		Methods("DELETE").
-		Handler(authMiddleware.Authenticate(http.HandlerFunc(userBookHandler.DeleteComment)))
+		Handler(authMiddleware.Authenticate(commentWrite(http.HandlerFunc(userBookHandler.DeleteComment))))
 
 	// Serve uploaded files
 	r.PathPrefix("/uploads/").Handler(
 		http.StripPrefix("/uploads/", http.FileServer(http.Dir(uploadDir))),
 	)
 
+	// Delete accounts whose grace period is over
+	go purgeDeletedAccounts(profileService, uploadDir, time.Hour)
+
+	// Finish exports interrupted by a restart, and delete expired ones
+	if err := dataExportService.ResumeUnfinished(); err != nil {
+		log.Printf("Failed to resume data exports: %v", err)
+	}
+	go cleanUpDataExports(dataExportService, time.Hour)
+
+	// Finish catalog imports interrupted by a restart
+	if err := catalogImportService.ResumeUnfinished(); err != nil {
+		log.Printf("Failed to resume catalog imports: %v", err)
+	}
+
 	// Start server
 	port := getEnv("PORT", "8080")
 	srv := &http.Server{
@@ -180,6 +438,154 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"ok","message":"Library API is running"}`))
 }
 
+// purgeDeletedAccounts deletes accounts whose deletion is due, and their
+// avatars, every interval.
+func purgeDeletedAccounts(profileService *service.ProfileService, uploadDir string, interval time.Duration) {
+	for ; ; time.Sleep(interval) {
+		avatars, err := profileService.PurgeDeletedAccounts()
+		if err != nil {
+			log.Printf("Failed to purge deleted accounts: %v", err)
+			continue
+		}
+
+		for _, avatarURL := range avatars {
+			utils.DeleteUploadedFile(uploadDir, avatarURL)
+		}
+		if len(avatars) > 0 {
+			log.Printf("Purged %d deleted accounts", len(avatars))
+		}
+	}
+}
+
+// cleanUpDataExports deletes expired data exports every interval.
+func cleanUpDataExports(dataExportService *service.DataExportService, interval time.Duration) {
+	for ; ; time.Sleep(interval) {
+		removed, err := dataExportService.CleanUp()
+		if err != nil {
+			log.Printf("Failed to clean up data exports: %v", err)
+			continue
+		}
+
+		if removed > 0 {
+			log.Printf("Removed %d data export files", removed)
+		}
+	}
+}
+
+// defaultJWTSecrets are the placeholder secrets shipped in this repository.
+var defaultJWTSecrets = []string{
+	"your-secret-key-change-in-production",
+	"your-super-secret-jwt-key-change-in-production",
+}
+
+// loadJWTSecret returns the HMAC secret for tokens only this server reads. It
+// refuses a missing, default or short secret unless devMode is set.
+func loadJWTSecret(devMode bool) string {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" && devMode {
+		log.Println("WARNING: JWT_SECRET is not set, using the default secret (DEV_MODE)")
+		return defaultJWTSecrets[0]
+	}
+
+	weak := len(secret) < 32
+	for _, def := range defaultJWTSecrets {
+		if secret == def {
+			weak = true
+		}
+	}
+
+	if weak {
+		if !devMode {
+			log.Fatal("JWT_SECRET must be set to a random value of at least 32 characters (set DEV_MODE=true to allow the default)")
+		}
+		log.Println("WARNING: JWT_SECRET is a default or short value (DEV_MODE)")
+	}
+
+	return secret
+}
+
+// loadSigningKeys loads the access and refresh token keys from JWT_KEYS_DIR,
+// signing with JWT_ACTIVE_KEY_ID. In dev mode a missing directory falls back
+// to a key that only lives until the server restarts.
+func loadSigningKeys(devMode bool) *auth.KeySet {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		if !devMode {
+			log.Fatal("JWT_KEYS_DIR must point to the token signing keys (set DEV_MODE=true to generate a temporary key)")
+		}
+
+		keys, err := auth.GenerateKeySet()
+		if err != nil {
+			log.Fatalf("Failed to generate signing key: %v", err)
+		}
+		log.Printf("WARNING: JWT_KEYS_DIR is not set, signing tokens with temporary key %s (DEV_MODE)", keys.ActiveKeyID())
+		return keys
+	}
+
+	keys, err := auth.LoadKeySet(dir, os.Getenv("JWT_ACTIVE_KEY_ID"))
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	log.Printf("Signing tokens with key %s", keys.ActiveKeyID())
+
+	return keys
+}
+
+// loadOIDCProviders reads the providers listed in OIDC_PROVIDERS. Each one is
+// configured with OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET and
+// optionally _REDIRECT_URL and _SCOPES.
+func loadOIDCProviders(frontendURL string) []service.OIDCProviderConfig {
+	var providers []service.OIDCProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := service.OIDCProviderConfig{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", frontendURL+"/auth/oidc/"+name+"/callback"),
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			provider.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+
+		if provider.IssuerURL == "" || provider.ClientID == "" {
+			log.Fatalf("OIDC provider %s needs %sISSUER and %sCLIENT_ID", name, prefix, prefix)
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
+
+// loadPasswordPolicy reads the password rules. PASSWORD_BREACHED_LIST names
+// an optional file of extra breached passwords, one per line.
+func loadPasswordPolicy() *service.PasswordPolicy {
+	policy := &service.PasswordPolicy{
+		MinLength:          getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:          getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		RejectPersonalInfo: getEnvBool("PASSWORD_REJECT_PERSONAL_INFO", true),
+	}
+
+	if getEnvBool("PASSWORD_CHECK_BREACHED", true) {
+		policy.Breached = auth.BundledBreachedPasswords()
+		if path := os.Getenv("PASSWORD_BREACHED_LIST"); path != "" {
+			if err := policy.Breached.AddFile(path); err != nil {
+				log.Fatalf("Failed to load PASSWORD_BREACHED_LIST: %v", err)
+			}
+		}
+		log.Printf("Loaded %d breached passwords", policy.Breached.Len())
+	}
+
+	return policy
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -187,3 +593,27 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
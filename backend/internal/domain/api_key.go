@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Scopes limit what an API key can do. They never grant more than the
+// owner's own permissions.
+const (
+	ScopeCatalogRead  = "catalog:read"
+	ScopeCatalogWrite = "catalog:write"
+	ScopeLibraryRead  = "library:read"
+	ScopeLibraryWrite = "library:write"
+)
+
+// APIKeyScopes lists every scope a key can be given.
+var APIKeyScopes = []string{ScopeCatalogRead, ScopeCatalogWrite, ScopeLibraryRead, ScopeLibraryWrite}
+
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type APIKeyCreate struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=catalog:read catalog:write library:read library:write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey is returned once, when the key is created. The plain key is
+// not stored and cannot be shown again.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -4,16 +4,25 @@ import (
 	"time"
 )
 
+// Book is a work, such as Dune, with its authors and subjects. Its ISBN,
+// cover, language and publication date are those of its primary edition.
 type Book struct {
-	ID          int64     `json:"id" db:"id"`
-	Title       string    `json:"title" db:"title"`
-	Description string    `json:"description" db:"description"`
-	CoverURL    string    `json:"cover_url" db:"cover_url"`
-	ISBN        string    `json:"isbn" db:"isbn"`
-	PublishedAt time.Time `json:"published_at" db:"published_at"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
-	Authors     []Author  `json:"authors,omitempty"`
+	ID            int64       `json:"id" db:"id"`
+	Title         string      `json:"title" db:"title"`
+	Description   string      `json:"description" db:"description"`
+	CoverURL      string      `json:"cover_url" db:"cover_url"`
+	ISBN          string      `json:"isbn" db:"isbn"`
+	PublishedAt   time.Time   `json:"published_at" db:"published_at"`
+	Language      string      `json:"language,omitempty" db:"language"`
+	AverageRating float64     `json:"average_rating"`
+	RatingCount   int         `json:"rating_count"`
+	CreatedAt     time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at" db:"updated_at"`
+	Authors       []Author    `json:"authors,omitempty"`
+	Genres        []Genre     `json:"genres,omitempty"`
+	Tags          []Tag       `json:"tags,omitempty"`
+	Series        *BookSeries `json:"series,omitempty"`
+	Editions      []Edition   `json:"editions,omitempty"`
 }
 
 type Author struct {
@@ -24,23 +33,106 @@ type Author struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// BookCreate adds a book with its first edition. The ISBN may be an
+// ISBN-10 or ISBN-13, with or without hyphens, and is stored as a bare
+// ISBN-13.
 type BookCreate struct {
-	Title       string   `json:"title" validate:"required,min=1,max=255"`
-	Description string   `json:"description" validate:"required"`
-	ISBN        string   `json:"isbn" validate:"omitempty,len=13"`
-	PublishedAt string   `json:"published_at" validate:"required"`
-	AuthorIDs   []int64  `json:"author_ids" validate:"required,min=1"`
+	Title       string  `json:"title" validate:"required,min=1,max=255"`
+	Description string  `json:"description" validate:"required"`
+	ISBN        string  `json:"isbn" validate:"omitempty,isbn"`
+	PublishedAt string  `json:"published_at" validate:"required"`
+	Language    string  `json:"language" validate:"omitempty,bcp47_language_tag"`
+	AuthorIDs   []int64 `json:"author_ids" validate:"required,min=1"`
+	GenreIDs    []int64 `json:"genre_ids"`
+	TagIDs      []int64 `json:"tag_ids"`
 }
 
+// BookUpdate changes the fields that are set. GenreIDs and TagIDs replace
+// the book's genres and tags when present, and [] removes them all.
 type BookUpdate struct {
-	Title       string   `json:"title" validate:"omitempty,min=1,max=255"`
-	Description string   `json:"description" validate:"omitempty"`
-	ISBN        string   `json:"isbn" validate:"omitempty,len=13"`
-	PublishedAt string   `json:"published_at" validate:"omitempty"`
-	AuthorIDs   []int64  `json:"author_ids" validate:"omitempty,min=1"`
+	Title       string  `json:"title" validate:"omitempty,min=1,max=255"`
+	Description string  `json:"description" validate:"omitempty"`
+	ISBN        string  `json:"isbn" validate:"omitempty,isbn"`
+	PublishedAt string  `json:"published_at" validate:"omitempty"`
+	Language    string  `json:"language" validate:"omitempty,bcp47_language_tag"`
+	AuthorIDs   []int64 `json:"author_ids" validate:"omitempty,min=1"`
+	GenreIDs    []int64 `json:"genre_ids"`
+	TagIDs      []int64 `json:"tag_ids"`
 }
 
 type AuthorCreate struct {
 	Name string `json:"name" validate:"required,min=1,max=255"`
 	Bio  string `json:"bio" validate:"omitempty"`
 }
+
+// BookSearchResult is a book matching a search query. TitleHighlight and
+// Snippet are HTML-escaped, with the matched words wrapped in <mark> tags.
+// Fuzzy results matched a misspelled title or author name, and have no
+// highlights.
+type BookSearchResult struct {
+	Book
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight,omitempty"`
+	Snippet        string  `json:"snippet,omitempty"`
+	Fuzzy          bool    `json:"fuzzy"`
+}
+
+// Orders of the books listing
+const (
+	BookSortNewest     = "newest"
+	BookSortTitle      = "title"
+	BookSortPublished  = "published_at"
+	BookSortPopularity = "popularity"
+	BookSortRating     = "rating"
+	BookSortSeries     = "series"
+)
+
+// BookFilter narrows and orders the books listing. Zero values match every
+// book. YearFrom and YearTo are inclusive. Language matches a book with any
+// edition in it. With IncludeDescendants, GenreID also matches the books of
+// every genre below it. BookSortSeries orders the books of SeriesID by their
+// position.
+type BookFilter struct {
+	AuthorID           int64
+	GenreID            int64
+	IncludeDescendants bool
+	TagID              int64
+	SeriesID           int64
+	Language           string
+	YearFrom           int
+	YearTo             int
+	HasCover           *bool
+	Sort               string
+	Descending         bool
+}
+
+// FacetCount is how many books match a filter value. Label is set when the
+// value is an ID.
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// BookFacets count the books for each value of each filter. Every facet
+// applies all the other filters but not its own, so the counts show what
+// choosing another value would return.
+type BookFacets struct {
+	Authors   []FacetCount `json:"authors"`
+	Genres    []FacetCount `json:"genres"`
+	Tags      []FacetCount `json:"tags"`
+	Languages []FacetCount `json:"languages"`
+	Years     []FacetCount `json:"years"`
+	HasCover  []FacetCount `json:"has_cover"`
+}
+
+// BookPage is a page of the books listing with the facet counts of the
+// whole listing.
+type BookPage struct {
+	Page[Book]
+	Facets BookFacets `json:"facets"`
+}
+
+type BookRating struct {
+	Rating int `json:"rating" validate:"required,min=1,max=5"`
+}
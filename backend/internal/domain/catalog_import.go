@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+type CatalogImportStatus string
+
+const (
+	ImportPending   CatalogImportStatus = "pending"
+	ImportRunning   CatalogImportStatus = "running"
+	ImportCompleted CatalogImportStatus = "completed"
+	ImportFailed    CatalogImportStatus = "failed"
+)
+
+// CatalogImport is a job that adds the books of a CSV, MARC21 or ONIX file
+// to the catalog. Records are matched to editions by ISBN, so running the
+// same file again updates the books it created instead of adding them
+// twice. A dry run only validates the file and counts what would change.
+type CatalogImport struct {
+	ID         int64               `json:"id"`
+	UserID     *int64              `json:"user_id"`
+	Format     string              `json:"format"`
+	DryRun     bool                `json:"dry_run"`
+	Mapping    map[string]string   `json:"mapping,omitempty"`
+	SourceName string              `json:"source_name"`
+	FileName   string              `json:"-"`
+	Status     CatalogImportStatus `json:"status"`
+	// Total is the number of records in the file, known once the file has
+	// been read through.
+	Total       int        `json:"total"`
+	Processed   int        `json:"processed"`
+	Created     int        `json:"created"`
+	Updated     int        `json:"updated"`
+	Unchanged   int        `json:"unchanged"`
+	Failed      int        `json:"failed"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// CatalogImportError is a record of an import that was skipped, with the
+// reason. Record is its position in the file, or the row for CSV.
+type CatalogImportError struct {
+	ID       int64  `json:"id"`
+	ImportID int64  `json:"import_id"`
+	Record   int    `json:"record"`
+	ISBN     string `json:"isbn,omitempty"`
+	Message  string `json:"message"`
+}
+
+// CatalogImportRequest starts an import of an uploaded file. Mapping maps
+// record fields to CSV columns and is ignored for other formats.
+type CatalogImportRequest struct {
+	Format  string            `validate:"required,oneof=csv marc21 marcxml onix"`
+	DryRun  bool              `validate:"-"`
+	Mapping map[string]string `validate:"omitempty,dive,keys,required,endkeys,required,max=255"`
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+type DataExportStatus string
+
+const (
+	ExportPending DataExportStatus = "pending"
+	ExportRunning DataExportStatus = "running"
+	ExportReady   DataExportStatus = "ready"
+	ExportFailed  DataExportStatus = "failed"
+)
+
+// DataExport is a job that collects everything stored about a user into a
+// ZIP archive. The archive can be downloaded until ExpiresAt.
+type DataExport struct {
+	ID          int64            `json:"id"`
+	UserID      int64            `json:"user_id"`
+	Status      DataExportStatus `json:"status"`
+	FileName    string           `json:"-"`
+	FileSize    int64            `json:"file_size,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`
+}
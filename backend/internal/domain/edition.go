@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// Edition formats
+const (
+	EditionFormatHardcover = "hardcover"
+	EditionFormatPaperback = "paperback"
+	EditionFormatEbook     = "ebook"
+	EditionFormatAudiobook = "audiobook"
+)
+
+// Edition is one printing, translation or format of a book. The book's
+// first edition is its primary one, which the book's ISBN, cover, language
+// and publication date come from.
+type Edition struct {
+	ID          int64      `json:"id" db:"id"`
+	BookID      int64      `json:"book_id" db:"book_id"`
+	ISBN        string     `json:"isbn" db:"isbn"`
+	Publisher   string     `json:"publisher" db:"publisher"`
+	Format      string     `json:"format" db:"format"`
+	Language    string     `json:"language,omitempty" db:"language"`
+	PageCount   int        `json:"page_count,omitempty" db:"page_count"`
+	PublishedAt *time.Time `json:"published_at,omitempty" db:"published_at"`
+	CoverURL    string     `json:"cover_url" db:"cover_url"`
+	// LegacyISBN is an invalid or duplicate ISBN the edition had before
+	// ISBNs were validated, kept until the edition is given a valid one.
+	LegacyISBN string    `json:"legacy_isbn,omitempty" db:"legacy_isbn"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EditionCreate adds an edition to a book, or replaces every field of one
+// when updating it. The ISBN may be an ISBN-10 or ISBN-13, with or without
+// hyphens, and is stored as a bare ISBN-13.
+type EditionCreate struct {
+	ISBN        string `json:"isbn" validate:"omitempty,isbn"`
+	Publisher   string `json:"publisher" validate:"omitempty,max=255"`
+	Format      string `json:"format" validate:"omitempty,oneof=hardcover paperback ebook audiobook"`
+	Language    string `json:"language" validate:"omitempty,bcp47_language_tag"`
+	PageCount   int    `json:"page_count" validate:"omitempty,min=1,max=100000"`
+	PublishedAt string `json:"published_at" validate:"omitempty"`
+}
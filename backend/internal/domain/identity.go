@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// UserIdentity links an account at an external OpenID Connect provider to a
+// local user.
+type UserIdentity struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"-"`
+	Email       string     `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OIDCState is kept between the redirect to the provider and the callback.
+type OIDCState struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+// OIDCLinkRequest is an identity whose email matches an existing account.
+// It is only linked once the account owner confirms.
+type OIDCLinkRequest struct {
+	ID        string
+	UserID    int64
+	Provider  string
+	Subject   string
+	Email     string
+	ExpiresAt time.Time
+}
+
+type OIDCLinkConfirm struct {
+	LinkToken string `json:"link_token" validate:"required"`
+	Password  string `json:"password" validate:"required"`
+}
+
+// OIDCLinkIdentity confirms a link request from a session of the account,
+// for accounts without a password.
+type OIDCLinkIdentity struct {
+	LinkToken string `json:"link_token" validate:"required"`
+}
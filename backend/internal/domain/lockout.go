@@ -0,0 +1,16 @@
+package domain
+
+// Login events recorded by the lockout service
+const (
+	LoginEventPasswordFailed  = "password_failed"
+	LoginEventTwoFactorFailed = "2fa_failed"
+	LoginEventAccountLocked   = "account_locked"
+	LoginEventIPLocked        = "ip_locked"
+	LoginEventAccountUnlocked = "account_unlocked"
+)
+
+type LoginEvent struct {
+	UserID    int64
+	IPAddress string
+	Event     string
+}
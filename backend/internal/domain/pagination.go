@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Page sizes of the list endpoints
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidCursor is returned for cursors that cannot be decoded or that
+// belong to a list sorted another way.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points at an item of a list. The next page starts after it, or the
+// previous page ends before it when Before is set. Keys are the item's sort
+// key values as text and Sort names the order they belong to.
+type Cursor struct {
+	Sort   string   `json:"s"`
+	Keys   []string `json:"k"`
+	Before bool     `json:"b,omitempty"`
+}
+
+// Encode returns the cursor as an opaque, URL-safe string.
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &Cursor{}
+	if err := json.Unmarshal(data, cursor); err != nil || len(cursor.Keys) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// PageRequest asks for Size items next to Cursor, or the first Size items
+// when Cursor is nil.
+type PageRequest struct {
+	Cursor *Cursor
+	Size   int
+}
+
+// Page is one page of a list. Total counts the items across all pages, and
+// a cursor is empty when there is nothing more in its direction.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// SinglePage returns a short list that is never split across pages as a
+// Page, so that every list endpoint responds the same way.
+func SinglePage[T any](items []T) *Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return &Page[T]{Items: items, Total: len(items)}
+}
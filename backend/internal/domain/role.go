@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Permissions granted by roles
+const (
+	PermissionBooksWrite       = "books:write"
+	PermissionAuthorsWrite     = "authors:write"
+	PermissionCommentsModerate = "comments:moderate"
+	PermissionUsersManage      = "users:manage"
+	PermissionTaxonomyManage   = "taxonomy:manage"
+	PermissionCatalogImport    = "catalog:import"
+)
+
+// Built-in roles
+const (
+	RoleAdmin     = "admin"
+	RoleLibrarian = "librarian"
+	RoleModerator = "moderator"
+)
+
+// ErrLastAdmin is returned for changes that would leave no active admin.
+var ErrLastAdmin = errors.New("the last admin cannot lose their access")
+
+type Role struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RoleAssignment struct {
+	Role string `json:"role" validate:"required,max=50"`
+}
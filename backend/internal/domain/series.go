@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// Series is a sequence of books meant to be read in order, such as The
+// Expanse.
+type Series struct {
+	ID          int64     `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	VolumeCount int       `json:"volume_count"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type SeriesCreate struct {
+	Name        string `json:"name" validate:"required,min=1,max=255"`
+	Description string `json:"description" validate:"omitempty"`
+}
+
+// SeriesVolumeRequest places a book in a series. Positions have up to two
+// decimals, so that a novella fits between volumes, such as 2.5 between
+// books 2 and 3.
+type SeriesVolumeRequest struct {
+	Position float64 `json:"position" validate:"required,gt=0,lt=10000"`
+}
+
+// SeriesVolume is a book at its position in a series.
+type SeriesVolume struct {
+	BookID   int64   `json:"book_id"`
+	Title    string  `json:"title"`
+	Position float64 `json:"position"`
+}
+
+// BookSeries is the series of a book and its position there. Book details
+// add the volumes before and after it in reading order.
+type BookSeries struct {
+	ID       int64         `json:"id"`
+	Name     string        `json:"name"`
+	Position float64       `json:"position"`
+	Previous *SeriesVolume `json:"previous,omitempty"`
+	Next     *SeriesVolume `json:"next,omitempty"`
+}
+
+// SeriesProgress is how far a user is through a series, counting the
+// volumes on their reading list, and the first volume they have not read.
+type SeriesProgress struct {
+	SeriesID   int64         `json:"series_id"`
+	SeriesName string        `json:"series_name"`
+	Volumes    int           `json:"volumes"`
+	Read       int           `json:"read"`
+	Reading    int           `json:"reading"`
+	NextVolume *SeriesVolume `json:"next_volume,omitempty"`
+}
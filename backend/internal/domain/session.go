@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"time"
+)
+
+// Session is a single login of a user on one device. All refresh tokens
+// rotated from that login belong to the session.
+type Session struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IPAddress  string     `json:"ip_address" db:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	Current    bool       `json:"current"`
+}
+
+// ClientInfo describes the client a request came from.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// Genre is a node of the genre and subject tree, such as Science Fiction
+// under Fiction. Top-level genres have no parent.
+type Genre struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	ParentID  *int64    `json:"parent_id" db:"parent_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// GenreCreate creates a genre, or replaces its name and parent on update. A
+// nil ParentID makes it a top-level genre.
+type GenreCreate struct {
+	Name     string `json:"name" validate:"required,min=1,max=100"`
+	ParentID *int64 `json:"parent_id" validate:"omitempty,min=1"`
+}
+
+// Tag is a free-form label for books, such as "book club" or "award winner".
+type Tag struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type TagCreate struct {
+	Name string `json:"name" validate:"required,min=1,max=50"`
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"time"
+)
+
+// RefreshToken is the server-side record of an issued refresh token.
+// Tokens rotated from the same login share a FamilyID.
+type RefreshToken struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    int64      `json:"user_id" db:"user_id"`
+	FamilyID  string     `json:"family_id" db:"family_id"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TwoFactorChallenge is issued after a successful password check when the
+// user has 2FA enabled. It can be redeemed once for a token pair.
+type TwoFactorChallenge struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	Attempts   int        `json:"attempts" db:"attempts"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty" db:"consumed_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
@@ -5,23 +5,79 @@ import (
 )
 
 type User struct {
-	ID              int64     `json:"id" db:"id"`
-	Email           string    `json:"email" db:"email"`
-	Username        string    `json:"username" db:"username"`
-	PasswordHash    string    `json:"-" db:"password_hash"`
-	IsAdmin         bool      `json:"is_admin" db:"is_admin"`
-	GoogleID        string    `json:"-" db:"google_id"`
-	TwoFactorSecret string    `json:"-" db:"two_factor_secret"`
-	TwoFactorEnabled bool     `json:"two_factor_enabled" db:"two_factor_enabled"`
-	EmailVerified   bool      `json:"email_verified" db:"email_verified"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	ID               int64      `json:"id" db:"id"`
+	Email            string     `json:"email" db:"email"`
+	Username         string     `json:"username" db:"username"`
+	AvatarURL        string     `json:"avatar_url,omitempty" db:"avatar_url"`
+	Bio              string     `json:"bio,omitempty" db:"bio"`
+	PendingEmail     string     `json:"pending_email,omitempty" db:"pending_email"`
+	PasswordHash     string     `json:"-" db:"password_hash"`
+	TwoFactorSecret  string     `json:"-" db:"two_factor_secret"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled" db:"two_factor_enabled"`
+	TOTPLastStep     int64      `json:"-" db:"totp_last_step"`
+	EmailVerified    bool       `json:"email_verified" db:"email_verified"`
+	Roles            []string   `json:"roles"`
+	Permissions      []string   `json:"permissions"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty" db:"suspended_at"`
+	SuspensionReason string     `json:"suspension_reason,omitempty" db:"suspension_reason"`
+	DeleteAfter      *time.Time `json:"delete_after,omitempty" db:"delete_after"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *User) HasPermission(permission string) bool {
+	for _, p := range u.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// UserFilter narrows the user list for admins. Nil flags match everyone.
+type UserFilter struct {
+	Email     string
+	Username  string
+	Role      string
+	TwoFactor *bool
+	Verified  *bool
+	Suspended *bool
+}
+
+type UserSuspension struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+// ProfileUpdate changes only the fields that are set. A new email address
+// takes effect once it is verified, and needs the current password if the
+// account has one.
+type ProfileUpdate struct {
+	Username        *string `json:"username" validate:"omitempty,min=3,max=50"`
+	Email           *string `json:"email" validate:"omitempty,email"`
+	Bio             *string `json:"bio" validate:"omitempty,max=1000"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+// AccountDeletion confirms the deletion of the user's own account with
+// either their password or a 2FA code.
+type AccountDeletion struct {
+	Password string `json:"password" validate:"required_without=Code"`
+	Code     string `json:"code" validate:"required_without=Password,omitempty,len=6"`
 }
 
 type UserRegistration struct {
 	Email    string `json:"email" validate:"required,email"`
 	Username string `json:"username" validate:"required,min=3,max=50"`
-	Password string `json:"password" validate:"required,min=8"`
+	Password string `json:"password" validate:"required"`
 }
 
 type UserLogin struct {
@@ -37,3 +93,43 @@ type TwoFactorSetup struct {
 type TwoFactorVerify struct {
 	Code string `json:"code" validate:"required,len=6"`
 }
+
+// TwoFactorLoginVerify redeems a login challenge with either a TOTP code or
+// one of the user's recovery codes.
+type TwoFactorLoginVerify struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6"`
+	RecoveryCode   string `json:"recovery_code" validate:"required_without=Code,omitempty,max=32"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
+
+type TwoFactorReset struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+type EmailVerification struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerification struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ForgotPassword struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPassword struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// ChangePassword requires a TOTP code when the account has 2FA enabled.
+type ChangePassword struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+	Code            string `json:"code" validate:"omitempty,len=6"`
+}
@@ -13,13 +13,15 @@ const (
 )
 
 type UserBook struct {
-	ID        int64         `json:"id" db:"id"`
-	UserID    int64         `json:"user_id" db:"user_id"`
-	BookID    int64         `json:"book_id" db:"book_id"`
-	Status    ReadingStatus `json:"status" db:"status"`
-	CreatedAt time.Time     `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time     `json:"updated_at" db:"updated_at"`
-	Book      *Book         `json:"book,omitempty"`
+	ID             int64           `json:"id" db:"id"`
+	UserID         int64           `json:"user_id" db:"user_id"`
+	BookID         int64           `json:"book_id" db:"book_id"`
+	EditionID      *int64          `json:"edition_id" db:"edition_id"`
+	Status         ReadingStatus   `json:"status" db:"status"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+	Book           *Book           `json:"book,omitempty"`
+	SeriesProgress *SeriesProgress `json:"series_progress,omitempty"`
 }
 
 type Favorite struct {
@@ -30,6 +32,16 @@ type Favorite struct {
 	Book      *Book     `json:"book,omitempty"`
 }
 
+// Rating is a user's 1 to 5 star rating of a book.
+type Rating struct {
+	UserID    int64     `json:"user_id" db:"user_id"`
+	BookID    int64     `json:"book_id" db:"book_id"`
+	Rating    int       `json:"rating" db:"rating"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	BookTitle string    `json:"book_title,omitempty"`
+}
+
 type Comment struct {
 	ID        int64     `json:"id" db:"id"`
 	UserID    int64     `json:"user_id" db:"user_id"`
@@ -38,10 +50,14 @@ type Comment struct {
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	Username  string    `json:"username,omitempty" db:"username"`
+	BookTitle string    `json:"book_title,omitempty" db:"book_title"`
 }
 
+// UserBookRequest sets the reading status of a book, and optionally which
+// of its editions the user owns or reads.
 type UserBookRequest struct {
-	Status ReadingStatus `json:"status" validate:"required,oneof=want_to_read reading read"`
+	Status    ReadingStatus `json:"status" validate:"required,oneof=want_to_read reading read"`
+	EditionID *int64        `json:"edition_id" validate:"omitempty,min=1"`
 }
 
 type CommentCreate struct {
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebAuthnPurpose string
+
+const (
+	WebAuthnPurposeRegister     WebAuthnPurpose = "register"
+	WebAuthnPurposeSecondFactor WebAuthnPurpose = "second_factor"
+	WebAuthnPurposeLogin        WebAuthnPurpose = "login"
+)
+
+// WebAuthnCredential is a security key or passkey registered by a user.
+// Data holds the serialized credential record used for verification.
+type WebAuthnCredential struct {
+	ID           int64      `json:"id" db:"id"`
+	UserID       int64      `json:"user_id" db:"user_id"`
+	CredentialID []byte     `json:"-" db:"credential_id"`
+	Name         string     `json:"name" db:"name"`
+	Data         []byte     `json:"-" db:"credential"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+}
+
+// WebAuthnCeremony stores the server side state between the begin and finish
+// steps of a registration or assertion.
+type WebAuthnCeremony struct {
+	ID          string          `db:"id"`
+	UserID      int64           `db:"user_id"`
+	Purpose     WebAuthnPurpose `db:"purpose"`
+	SessionData []byte          `db:"session_data"`
+	ExpiresAt   time.Time       `db:"expires_at"`
+}
+
+type WebAuthnRegisterBegin struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+type WebAuthnSecondFactorBegin struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+// WebAuthnFinish carries the browser's PublicKeyCredential response for the
+// ceremony started with the matching begin call.
+type WebAuthnFinish struct {
+	CeremonyID     string          `json:"ceremony_id" validate:"required"`
+	ChallengeToken string          `json:"challenge_token,omitempty"`
+	Credential     json.RawMessage `json:"credential" validate:"required"`
+}
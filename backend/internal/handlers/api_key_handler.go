@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+func (h *APIKeyHandler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	keys, err := h.apiKeyService.GetKeys(userID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, domain.SinglePage(keys))
+}
+
+func (h *APIKeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	var req domain.APIKeyCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key, err := h.apiKeyService.CreateKey(userID, &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	keyID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid api key ID")
+		return
+	}
+
+	if err := h.apiKeyService.RevokeKey(userID, keyID, clientInfo(r)); err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "API key revoked")
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+func (h *AuditHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	actorUserID := middleware.GetUserID(r.Context())
+	query := r.URL.Query()
+
+	page, err := pageRequest(r)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := domain.AuditFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+	}
+
+	for param, id := range map[string]*int64{
+		"actor_user_id": &filter.ActorUserID,
+		"target_id":     &filter.TargetID,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "invalid value for "+param)
+			return
+		}
+		*id = parsed
+	}
+
+	for param, bound := range map[string]**time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseAuditTime(value, param == "to")
+		if err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "invalid value for "+param)
+			return
+		}
+		*bound = &parsed
+	}
+
+	events, err := h.auditService.ListEvents(actorUserID, filter, page)
+	if err != nil {
+		listErrorResponse(w, err)
+		return
+	}
+
+	setPageLinks(w, r, events)
+	utils.SuccessResponseWithData(w, events)
+}
+
+func (h *AuditHandler) VerifyChain(w http.ResponseWriter, r *http.Request) {
+	result, err := h.auditService.VerifyChain(middleware.GetUserID(r.Context()))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, result)
+}
+
+// parseAuditTime accepts an RFC 3339 timestamp or a plain date. A plain
+// date used as the upper bound includes that whole day.
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
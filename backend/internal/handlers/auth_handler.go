@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -14,11 +15,17 @@ import (
 )
 
 type AuthHandler struct {
-	authService *service.AuthService
+	authService    *service.AuthService
+	sessionService *service.SessionService
+	emailService   *service.EmailVerificationService
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *service.AuthService, sessionService *service.SessionService, emailService *service.EmailVerificationService) *AuthHandler {
+	return &AuthHandler{
+		authService:    authService,
+		sessionService: sessionService,
+		emailService:   emailService,
+	}
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -45,6 +52,46 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req domain.EmailVerification
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.emailService.VerifyEmail(req.Token); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Email verified successfully")
+}
+
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req domain.ResendVerification
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.emailService.ResendVerification(req.Email); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "failed to send verification email")
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "If the account exists and is not verified yet, a verification email has been sent")
+}
+
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req domain.UserLogin
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -57,26 +104,32 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, user, err := h.authService.Login(&req)
+	result, err := h.authService.Login(&req, clientInfo(r))
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusUnauthorized, err.Error())
+		authErrorResponse(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	// If 2FA is enabled, don't return tokens yet
-	if user.TwoFactorEnabled {
-		utils.SuccessResponseWithData(w, map[string]interface{}{
-			"requires_2fa": true,
-			"user_id":      user.ID,
-			"message":      "Please provide 2FA code",
-		})
-		return
+	utils.SuccessResponseWithData(w, loginResponse(result))
+}
+
+// loginResponse renders the outcome of a primary login. If 2FA is enabled,
+// no tokens are returned yet.
+func loginResponse(result *service.LoginResult) map[string]interface{} {
+	if result.ChallengeToken != "" {
+		return map[string]interface{}{
+			"requires_2fa":    true,
+			"challenge_token": result.ChallengeToken,
+			"expires_at":      result.ChallengeExpiresAt,
+			"methods":         result.SecondFactors,
+			"message":         "Please provide 2FA code",
+		}
 	}
 
-	utils.SuccessResponseWithData(w, map[string]interface{}{
-		"tokens": tokens,
-		"user":   user,
-	})
+	return map[string]interface{}{
+		"tokens": result.Tokens,
+		"user":   result.User,
+	}
 }
 
 func (h *AuthHandler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
@@ -105,21 +158,81 @@ func (h *AuthHandler) VerifyTwoFactorSetup(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err := h.authService.VerifyAndEnableTwoFactor(userID, req.Code)
+	codes, err := h.authService.VerifyAndEnableTwoFactor(userID, req.Code, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	utils.SuccessResponseWithMessage(w, "2FA enabled successfully")
+	utils.SuccessResponseWithData(w, map[string]interface{}{
+		"recovery_codes": codes,
+		"message":        "2FA enabled successfully. Store these recovery codes somewhere safe, they will not be shown again.",
+	})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	var req domain.TwoFactorVerify
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(userID, req.Code, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, domain.RecoveryCodes{Codes: codes})
+}
+
+func (h *AuthHandler) GetRecoveryCodesStatus(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	remaining, err := h.authService.CountRecoveryCodes(userID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, map[string]int{
+		"remaining": remaining,
+	})
 }
 
 func (h *AuthHandler) VerifyTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		UserID int64  `json:"user_id" validate:"required"`
-		Code   string `json:"code" validate:"required,len=6"`
+	var req domain.TwoFactorLoginVerify
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.authService.VerifyTwoFactorLogin(&req, clientInfo(r))
+	if err != nil {
+		authErrorResponse(w, http.StatusUnauthorized, err)
+		return
 	}
 
+	utils.SuccessResponseWithData(w, map[string]interface{}{
+		"tokens": result.Tokens,
+		"user":   result.User,
+	})
+}
+
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req domain.RefreshTokenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
 		return
@@ -130,7 +243,7 @@ func (h *AuthHandler) VerifyTwoFactorLogin(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	tokens, err := h.authService.VerifyTwoFactorLogin(req.UserID, req.Code)
+	tokens, err := h.authService.RefreshTokens(req.RefreshToken)
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusUnauthorized, err.Error())
 		return
@@ -155,7 +268,7 @@ func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.authService.DisableTwoFactor(userID, req.Code)
+	err := h.authService.DisableTwoFactor(userID, req.Code, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -164,7 +277,36 @@ func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
 	utils.SuccessResponseWithMessage(w, "2FA disabled successfully")
 }
 
-func (h *AuthHandler) MakeAdmin(w http.ResponseWriter, r *http.Request) {
+func (h *AuthHandler) ResetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	adminUserID := middleware.GetUserID(r.Context())
+	vars := mux.Vars(r)
+	targetUserID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	var req domain.TwoFactorReset
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.authService.ResetTwoFactor(adminUserID, targetUserID, req.Reason, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "2FA has been reset for the user")
+}
+
+func (h *AuthHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
 	adminUserID := middleware.GetUserID(r.Context())
 	vars := mux.Vars(r)
 	targetUserID, err := strconv.ParseInt(vars["id"], 10, 64)
@@ -173,23 +315,81 @@ func (h *AuthHandler) MakeAdmin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.authService.MakeAdmin(adminUserID, targetUserID)
+	err = h.authService.UnlockAccount(adminUserID, targetUserID, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	utils.SuccessResponseWithMessage(w, "User is now an admin")
+	utils.SuccessResponseWithMessage(w, "Account unlocked")
 }
 
-func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID := middleware.GetSessionID(r.Context())
+
+	err := h.sessionService.RevokeSession(sessionID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Logged out successfully")
+}
+
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
-	email := middleware.GetEmail(r.Context())
-	isAdmin := middleware.IsAdmin(r.Context())
 
-	utils.SuccessResponseWithData(w, map[string]interface{}{
-		"id":       userID,
-		"email":    email,
-		"is_admin": isAdmin,
-	})
+	err := h.sessionService.RevokeAllSessions(userID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Logged out from all devices")
+}
+
+func (h *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	sessionID := middleware.GetSessionID(r.Context())
+
+	sessions, err := h.sessionService.GetUserSessions(userID, sessionID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, domain.SinglePage(sessions))
+}
+
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	vars := mux.Vars(r)
+
+	err := h.sessionService.RevokeUserSession(userID, vars["id"])
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Session revoked")
+}
+
+func clientInfo(r *http.Request) domain.ClientInfo {
+	return domain.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: utils.ClientIP(r),
+	}
+}
+
+// authErrorResponse writes a failed login step. Lockouts are answered with
+// 429 and a Retry-After header in seconds.
+func authErrorResponse(w http.ResponseWriter, statusCode int, err error) {
+	var locked *service.LockedError
+	if errors.As(err, &locked) {
+		w.Header().Set("Retry-After", strconv.Itoa(locked.RetryAfterSeconds()))
+		utils.ErrorResponse(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+
+	utils.ErrorResponse(w, statusCode, err.Error())
 }
@@ -2,16 +2,22 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
 	"github.com/razvan/library-app/internal/service"
 	"github.com/razvan/library-app/internal/utils"
 	"github.com/razvan/library-app/pkg/validator"
 )
 
+const maxSearchQueryLength = 200
+
 type BookHandler struct {
 	bookService *service.BookService
 	uploadDir   string
@@ -36,7 +42,7 @@ func (h *BookHandler) CreateBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	book, err := h.bookService.CreateBook(&req)
+	book, err := h.bookService.CreateBook(middleware.GetUserID(r.Context()), &req, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -62,49 +68,172 @@ func (h *BookHandler) GetBook(w http.ResponseWriter, r *http.Request) {
 	utils.SuccessResponseWithData(w, book)
 }
 
+// GetBookByISBN looks a book up by the ISBN-10 or ISBN-13 of any of its
+// editions.
+func (h *BookHandler) GetBookByISBN(w http.ResponseWriter, r *http.Request) {
+	book, err := h.bookService.GetBookByISBN(mux.Vars(r)["isbn"])
+	if errors.Is(err, validator.ErrInvalidISBN) {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, book)
+}
+
 func (h *BookHandler) GetAllBooks(w http.ResponseWriter, r *http.Request) {
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
+	listBooks(w, r, h.bookService, nil)
+}
+
+// listBooks serves the books listing. node, when set, narrows it to one
+// genre, tag or series over the request's own filters.
+func listBooks(w http.ResponseWriter, r *http.Request, bookService *service.BookService, node func(*domain.BookFilter)) {
+	page, err := pageRequest(r)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	filter, err := bookFilter(r, node)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	books, err := h.bookService.GetAllBooks(page, pageSize)
+	books, err := bookService.ListBooks(filter, page)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		listErrorResponse(w, err)
 		return
 	}
 
+	setPageLinks(w, r, &books.Page)
 	utils.SuccessResponseWithData(w, books)
 }
 
+// bookFilter reads the filter and sort query parameters of the books
+// listing, then applies node when set.
+func bookFilter(r *http.Request, node func(*domain.BookFilter)) (domain.BookFilter, error) {
+	query := r.URL.Query()
+
+	filter := domain.BookFilter{
+		Language: query.Get("language"),
+		Sort:     query.Get("sort"),
+	}
+
+	for param, id := range map[string]*int64{
+		"author_id": &filter.AuthorID,
+		"genre_id":  &filter.GenreID,
+		"tag_id":    &filter.TagID,
+		"series_id": &filter.SeriesID,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid value for %s", param)
+		}
+		*id = parsed
+	}
+
+	for param, year := range map[string]*int{
+		"year_from": &filter.YearFrom,
+		"year_to":   &filter.YearTo,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 9999 {
+			return filter, fmt.Errorf("invalid value for %s", param)
+		}
+		*year = parsed
+	}
+
+	if value := query.Get("has_cover"); value != "" {
+		hasCover, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid value for has_cover")
+		}
+		filter.HasCover = &hasCover
+	}
+
+	// Whether genre_id also matches the books of its subgenres
+	if value := query.Get("include_descendants"); value != "" {
+		includeDescendants, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid value for include_descendants")
+		}
+		filter.IncludeDescendants = includeDescendants
+	}
+
+	if node != nil {
+		node(&filter)
+	}
+
+	// Series read in order and titles A to Z by default, everything else
+	// best or newest first
+	switch filter.Sort {
+	case "":
+		if filter.SeriesID != 0 {
+			filter.Sort = domain.BookSortSeries
+		} else {
+			filter.Sort = domain.BookSortNewest
+			filter.Descending = true
+		}
+	case domain.BookSortTitle:
+	case domain.BookSortSeries:
+		if filter.SeriesID == 0 {
+			return filter, fmt.Errorf("sort by series needs a series_id")
+		}
+	case domain.BookSortNewest, domain.BookSortPublished, domain.BookSortPopularity, domain.BookSortRating:
+		filter.Descending = true
+	default:
+		return filter, fmt.Errorf("sort must be one of newest, title, published_at, popularity, rating or series")
+	}
+
+	switch query.Get("order") {
+	case "":
+	case "asc":
+		filter.Descending = false
+	case "desc":
+		filter.Descending = true
+	default:
+		return filter, fmt.Errorf("order must be asc or desc")
+	}
+
+	return filter, nil
+}
+
 func (h *BookHandler) SearchBooks(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
 	if query == "" {
 		utils.ErrorResponse(w, http.StatusBadRequest, "search query is required")
 		return
 	}
-
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
+	if len(query) > maxSearchQueryLength {
+		utils.ErrorResponse(w, http.StatusBadRequest, "search query is too long")
+		return
 	}
 
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	page, err := pageRequest(r)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	books, err := h.bookService.SearchBooks(query, page, pageSize)
+	books, err := h.bookService.SearchBooks(query, page)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		listErrorResponse(w, err)
 		return
 	}
 
+	setPageLinks(w, r, books)
 	utils.SuccessResponseWithData(w, books)
 }
 
@@ -127,7 +256,7 @@ func (h *BookHandler) UpdateBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	book, err := h.bookService.UpdateBook(id, &req)
+	book, err := h.bookService.UpdateBook(middleware.GetUserID(r.Context()), id, &req, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -144,7 +273,7 @@ func (h *BookHandler) DeleteBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.bookService.DeleteBook(id)
+	err = h.bookService.DeleteBook(middleware.GetUserID(r.Context()), id, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -184,7 +313,7 @@ func (h *BookHandler) UploadCover(w http.ResponseWriter, r *http.Request) {
 
 	// Update book cover URL
 	coverURL := "/uploads/" + filename
-	err = h.bookService.UpdateBookCover(bookID, coverURL)
+	err = h.bookService.UpdateBookCover(middleware.GetUserID(r.Context()), bookID, coverURL, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
@@ -208,7 +337,7 @@ func (h *BookHandler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	author, err := h.bookService.CreateAuthor(&req)
+	author, err := h.bookService.CreateAuthor(middleware.GetUserID(r.Context()), &req, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -218,22 +347,19 @@ func (h *BookHandler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *BookHandler) GetAllAuthors(w http.ResponseWriter, r *http.Request) {
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
-
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 50
+	page, err := pageRequest(r)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	authors, err := h.bookService.GetAllAuthors(page, pageSize)
+	authors, err := h.bookService.GetAllAuthors(page)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		listErrorResponse(w, err)
 		return
 	}
 
+	setPageLinks(w, r, authors)
 	utils.SuccessResponseWithData(w, authors)
 }
 
@@ -273,7 +399,7 @@ func (h *BookHandler) UpdateAuthor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	author, err := h.bookService.UpdateAuthor(id, &req)
+	author, err := h.bookService.UpdateAuthor(middleware.GetUserID(r.Context()), id, &req, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -290,7 +416,7 @@ func (h *BookHandler) DeleteAuthor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.bookService.DeleteAuthor(id)
+	err = h.bookService.DeleteAuthor(middleware.GetUserID(r.Context()), id, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
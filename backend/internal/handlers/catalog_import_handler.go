@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+// maxImportSize limits uploaded catalog files, which hold tens of thousands
+// of records.
+const maxImportSize = 512 << 20
+
+// importUploadTimeout replaces the server's read and write timeouts for
+// uploads of catalog files, which take longer than other requests.
+const importUploadTimeout = 10 * time.Minute
+
+type CatalogImportHandler struct {
+	catalogImportService *service.CatalogImportService
+}
+
+func NewCatalogImportHandler(catalogImportService *service.CatalogImportService) *CatalogImportHandler {
+	return &CatalogImportHandler{catalogImportService: catalogImportService}
+}
+
+// StartImport uploads a catalog file as the multipart field "file", with
+// its "format", an optional "dry_run" flag and, for CSV, an optional
+// "mapping" of fields to columns as JSON.
+func (h *CatalogImportHandler) StartImport(w http.ResponseWriter, r *http.Request) {
+	controller := http.NewResponseController(w)
+	controller.SetReadDeadline(time.Now().Add(importUploadTimeout))
+	controller.SetWriteDeadline(time.Now().Add(importUploadTimeout))
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "file too large")
+		return
+	}
+
+	req := domain.CatalogImportRequest{Format: r.FormValue("format")}
+	if value := r.FormValue("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "invalid value for dry_run")
+			return
+		}
+		req.DryRun = dryRun
+	}
+	if value := r.FormValue("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &req.Mapping); err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "invalid mapping, use a JSON object of fields to column names")
+			return
+		}
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	imp, err := h.catalogImportService.StartImport(middleware.GetUserID(r.Context()), &req, header.Filename, file)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	message := "The import has started"
+	if imp.DryRun {
+		message = "The dry run has started"
+	}
+	utils.JSONResponse(w, http.StatusAccepted, utils.SuccessResponse{
+		Data:    imp,
+		Message: message,
+	})
+}
+
+func (h *CatalogImportHandler) GetImports(w http.ResponseWriter, r *http.Request) {
+	page, err := pageRequest(r)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	imports, err := h.catalogImportService.GetImports(page)
+	if err != nil {
+		listErrorResponse(w, err)
+		return
+	}
+
+	setPageLinks(w, r, imports)
+	utils.SuccessResponseWithData(w, imports)
+}
+
+// GetImport returns an import with its progress, for polling while it
+// runs.
+func (h *CatalogImportHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid import ID")
+		return
+	}
+
+	imp, err := h.catalogImportService.GetImport(id)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, imp)
+}
+
+func (h *CatalogImportHandler) GetImportErrors(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid import ID")
+		return
+	}
+
+	if _, err := h.catalogImportService.GetImport(id); err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	page, err := pageRequest(r)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	importErrors, err := h.catalogImportService.GetImportErrors(id, page)
+	if err != nil {
+		listErrorResponse(w, err)
+		return
+	}
+
+	setPageLinks(w, r, importErrors)
+	utils.SuccessResponseWithData(w, importErrors)
+}
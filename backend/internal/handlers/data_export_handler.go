@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+)
+
+type DataExportHandler struct {
+	dataExportService *service.DataExportService
+}
+
+func NewDataExportHandler(dataExportService *service.DataExportService) *DataExportHandler {
+	return &DataExportHandler{dataExportService: dataExportService}
+}
+
+func (h *DataExportHandler) GetExports(w http.ResponseWriter, r *http.Request) {
+	exports, err := h.dataExportService.GetExports(middleware.GetUserID(r.Context()))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, domain.SinglePage(exports))
+}
+
+func (h *DataExportHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	export, err := h.dataExportService.RequestExport(middleware.GetUserID(r.Context()))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusAccepted, utils.SuccessResponse{
+		Data:    export,
+		Message: "Your export is being prepared",
+	})
+}
+
+func (h *DataExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	exportID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid export ID")
+		return
+	}
+
+	file, export, err := h.dataExportService.OpenExport(middleware.GetUserID(r.Context()), exportID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+	defer file.Close()
+
+	name := "library-export-" + export.CreatedAt.Format("2006-01-02") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeContent(w, r, name, *export.CompletedAt, file)
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+type EditionHandler struct {
+	editionService *service.EditionService
+	uploadDir      string
+}
+
+func NewEditionHandler(editionService *service.EditionService, uploadDir string) *EditionHandler {
+	return &EditionHandler{
+		editionService: editionService,
+		uploadDir:      uploadDir,
+	}
+}
+
+func (h *EditionHandler) CreateEdition(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid book ID")
+		return
+	}
+
+	var req domain.EditionCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	edition, err := h.editionService.CreateEdition(middleware.GetUserID(r.Context()), bookID, &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusCreated, edition)
+}
+
+// GetBookEditions lists the editions of a book, the primary one first.
+func (h *EditionHandler) GetBookEditions(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid book ID")
+		return
+	}
+
+	editions, err := h.editionService.GetBookEditions(bookID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, domain.SinglePage(editions))
+}
+
+// GetLegacyISBNEditions lists the editions whose invalid or duplicate ISBN
+// was cleared, for catalog editors to correct.
+func (h *EditionHandler) GetLegacyISBNEditions(w http.ResponseWriter, r *http.Request) {
+	page, err := pageRequest(r)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	editions, err := h.editionService.GetLegacyISBNEditions(page)
+	if err != nil {
+		listErrorResponse(w, err)
+		return
+	}
+
+	setPageLinks(w, r, editions)
+	utils.SuccessResponseWithData(w, editions)
+}
+
+func (h *EditionHandler) GetEdition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid edition ID")
+		return
+	}
+
+	edition, err := h.editionService.GetEdition(id)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, edition)
+}
+
+func (h *EditionHandler) UpdateEdition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid edition ID")
+		return
+	}
+
+	var req domain.EditionCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	edition, err := h.editionService.UpdateEdition(middleware.GetUserID(r.Context()), id, &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, edition)
+}
+
+func (h *EditionHandler) DeleteEdition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid edition ID")
+		return
+	}
+
+	err = h.editionService.DeleteEdition(middleware.GetUserID(r.Context()), id, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Edition deleted successfully")
+}
+
+func (h *EditionHandler) UploadCover(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid edition ID")
+		return
+	}
+
+	// Parse multipart form (10 MB max)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "file too large")
+		return
+	}
+
+	file, header, err := r.FormFile("cover")
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "cover file is required")
+		return
+	}
+	defer file.Close()
+
+	filename, err := utils.SaveUploadedFile(file, header, h.uploadDir)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	coverURL := "/uploads/" + filename
+	err = h.editionService.UpdateEditionCover(middleware.GetUserID(r.Context()), id, coverURL, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, map[string]string{
+		"cover_url": coverURL,
+	})
+}
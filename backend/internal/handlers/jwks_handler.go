@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/auth"
+)
+
+type JWKSHandler struct {
+	keys *auth.KeySet
+}
+
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS publishes the public keys access tokens are signed with. The body
+// is a bare JWK Set, as verifiers expect, not the usual response envelope.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.JSONResponse(w, http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService}
+}
+
+func (h *OIDCHandler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	utils.SuccessResponseWithData(w, domain.SinglePage(h.oidcService.Providers()))
+}
+
+func (h *OIDCHandler) Start(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+
+	authorization, err := h.oidcService.Start(r.Context(), provider)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, authorization)
+}
+
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+	query := r.URL.Query()
+
+	if errCode := query.Get("error"); errCode != "" {
+		utils.ErrorResponse(w, http.StatusUnauthorized, "provider returned an error: "+errCode)
+		return
+	}
+
+	code, state := query.Get("code"), query.Get("state")
+	if code == "" || state == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "code and state are required")
+		return
+	}
+
+	result, err := h.oidcService.Callback(r.Context(), provider, code, state, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// The email belongs to an existing account, which has to confirm the link
+	if result.LinkToken != "" {
+		utils.SuccessResponseWithData(w, map[string]interface{}{
+			"requires_link_confirmation": true,
+			"link_token":                 result.LinkToken,
+			"email":                      result.Email,
+			"expires_at":                 result.LinkExpiresAt,
+			"message":                    "An account with this email already exists, enter its password to link it",
+		})
+		return
+	}
+
+	utils.SuccessResponseWithData(w, loginResponse(result.LoginResult))
+}
+
+func (h *OIDCHandler) ConfirmLink(w http.ResponseWriter, r *http.Request) {
+	var req domain.OIDCLinkConfirm
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.oidcService.ConfirmLink(&req, clientInfo(r))
+	if err != nil {
+		authErrorResponse(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	utils.SuccessResponseWithData(w, loginResponse(result))
+}
+
+// LinkIdentity confirms a link request from a session of the account, for
+// accounts that have no password to confirm it with.
+func (h *OIDCHandler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	var req domain.OIDCLinkIdentity
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	identity, err := h.oidcService.LinkIdentity(middleware.GetUserID(r.Context()), &req)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusCreated, identity)
+}
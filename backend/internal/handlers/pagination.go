@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/utils"
+)
+
+// pageRequest reads the page_size and cursor query parameters. Page sizes
+// out of range fall back to the default.
+func pageRequest(r *http.Request) (domain.PageRequest, error) {
+	query := r.URL.Query()
+
+	page := domain.PageRequest{Size: domain.DefaultPageSize}
+	if size, err := strconv.Atoi(query.Get("page_size")); err == nil && size >= 1 && size <= domain.MaxPageSize {
+		page.Size = size
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := domain.DecodeCursor(value)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = cursor
+	}
+
+	return page, nil
+}
+
+// setPageLinks points the Link header (RFC 8288) at the first, previous and
+// next pages, keeping every other query parameter of the request.
+func setPageLinks[T any](w http.ResponseWriter, r *http.Request, page *domain.Page[T]) {
+	link := func(cursor, rel string) string {
+		query := r.URL.Query()
+		query.Del("cursor")
+		query.Del("page")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+
+		target := r.URL.Path
+		if encoded := query.Encode(); encoded != "" {
+			target += "?" + encoded
+		}
+		return fmt.Sprintf(`<%s>; rel="%s"`, target, rel)
+	}
+
+	var links []string
+	if page.PrevCursor != "" {
+		links = append(links, link("", "first"), link(page.PrevCursor, "prev"))
+	}
+	if page.NextCursor != "" {
+		links = append(links, link(page.NextCursor, "next"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// listErrorResponse reports a failed list request. Cursors are client
+// input, so a bad one is the client's mistake.
+func listErrorResponse(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		utils.ErrorResponse(w, http.StatusBadRequest, domain.ErrInvalidCursor.Error())
+		return
+	}
+	utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+type PasswordHandler struct {
+	passwordService *service.PasswordService
+}
+
+func NewPasswordHandler(passwordService *service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{passwordService: passwordService}
+}
+
+func (h *PasswordHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req domain.ForgotPassword
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.passwordService.ForgotPassword(req.Email); err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, "failed to process request")
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "If an account exists for this email, a password reset link has been sent")
+}
+
+func (h *PasswordHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req domain.ResetPassword
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.passwordService.ResetPassword(&req, clientInfo(r)); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Password reset successfully, please log in again")
+}
+
+func (h *PasswordHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	var req domain.ChangePassword
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, err := h.passwordService.ChangePassword(userID, &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, map[string]interface{}{
+		"tokens":  tokens,
+		"message": "Password changed successfully, other sessions have been logged out",
+	})
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+type ProfileHandler struct {
+	profileService *service.ProfileService
+	uploadDir      string
+}
+
+func NewProfileHandler(profileService *service.ProfileService, uploadDir string) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+		uploadDir:      uploadDir,
+	}
+}
+
+func (h *ProfileHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	user, err := h.profileService.GetProfile(middleware.GetUserID(r.Context()))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, user)
+}
+
+func (h *ProfileHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	var req domain.ProfileUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.profileService.UpdateProfile(middleware.GetUserID(r.Context()), &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, user)
+}
+
+func (h *ProfileHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	// Parse multipart form (5 MB max)
+	if err := r.ParseMultipartForm(5 << 20); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "file too large")
+		return
+	}
+
+	file, header, err := r.FormFile("avatar")
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "avatar file is required")
+		return
+	}
+	defer file.Close()
+
+	filename, err := utils.SaveUploadedFile(file, header, h.uploadDir)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	avatarURL := "/uploads/" + filename
+	previous, err := h.profileService.UpdateAvatar(middleware.GetUserID(r.Context()), avatarURL)
+	if err != nil {
+		utils.DeleteUploadedFile(h.uploadDir, avatarURL)
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.DeleteUploadedFile(h.uploadDir, previous)
+
+	utils.SuccessResponseWithData(w, map[string]string{
+		"avatar_url": avatarURL,
+	})
+}
+
+func (h *ProfileHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	var req domain.AccountDeletion
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deleteAfter, err := h.profileService.RequestDeletion(middleware.GetUserID(r.Context()), &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, map[string]interface{}{
+		"message":      "Your account will be deleted. Log in again before then to keep it.",
+		"delete_after": deleteAfter,
+	})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+type RoleHandler struct {
+	roleService *service.RoleService
+}
+
+func NewRoleHandler(roleService *service.RoleService) *RoleHandler {
+	return &RoleHandler{roleService: roleService}
+}
+
+func (h *RoleHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleService.GetRoles()
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, domain.SinglePage(roles))
+}
+
+func (h *RoleHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	actorUserID := middleware.GetUserID(r.Context())
+	targetUserID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	var req domain.RoleAssignment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.roleService.AssignRole(actorUserID, targetUserID, req.Role, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, user)
+}
+
+func (h *RoleHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	actorUserID := middleware.GetUserID(r.Context())
+	vars := mux.Vars(r)
+	targetUserID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	user, err := h.roleService.RevokeRole(actorUserID, targetUserID, vars["role"], clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, user)
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+type SeriesHandler struct {
+	seriesService *service.SeriesService
+	bookService   *service.BookService
+}
+
+func NewSeriesHandler(seriesService *service.SeriesService, bookService *service.BookService) *SeriesHandler {
+	return &SeriesHandler{
+		seriesService: seriesService,
+		bookService:   bookService,
+	}
+}
+
+func (h *SeriesHandler) CreateSeries(w http.ResponseWriter, r *http.Request) {
+	var req domain.SeriesCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	series, err := h.seriesService.CreateSeries(middleware.GetUserID(r.Context()), &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusCreated, series)
+}
+
+func (h *SeriesHandler) GetAllSeries(w http.ResponseWriter, r *http.Request) {
+	page, err := pageRequest(r)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	series, err := h.seriesService.ListSeries(page)
+	if err != nil {
+		listErrorResponse(w, err)
+		return
+	}
+
+	setPageLinks(w, r, series)
+	utils.SuccessResponseWithData(w, series)
+}
+
+func (h *SeriesHandler) GetSeries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid series ID")
+		return
+	}
+
+	series, err := h.seriesService.GetSeries(id)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, series)
+}
+
+// GetSeriesBooks lists the volumes of a series in reading order. It takes
+// the filters and sorting of the books listing.
+func (h *SeriesHandler) GetSeriesBooks(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid series ID")
+		return
+	}
+
+	if _, err := h.seriesService.GetSeries(id); err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	listBooks(w, r, h.bookService, func(filter *domain.BookFilter) { filter.SeriesID = id })
+}
+
+func (h *SeriesHandler) UpdateSeries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid series ID")
+		return
+	}
+
+	var req domain.SeriesCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	series, err := h.seriesService.UpdateSeries(middleware.GetUserID(r.Context()), id, &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, series)
+}
+
+func (h *SeriesHandler) DeleteSeries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid series ID")
+		return
+	}
+
+	err = h.seriesService.DeleteSeries(middleware.GetUserID(r.Context()), id, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Series deleted successfully")
+}
+
+func (h *SeriesHandler) SetVolume(w http.ResponseWriter, r *http.Request) {
+	seriesID, bookID, ok := volumeIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.SeriesVolumeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	book, err := h.seriesService.SetVolume(middleware.GetUserID(r.Context()), seriesID, bookID, req.Position, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, book)
+}
+
+func (h *SeriesHandler) RemoveVolume(w http.ResponseWriter, r *http.Request) {
+	seriesID, bookID, ok := volumeIDs(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.seriesService.RemoveVolume(middleware.GetUserID(r.Context()), seriesID, bookID, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Book removed from series")
+}
+
+// volumeIDs reads the series and book IDs of a volume route.
+func volumeIDs(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	vars := mux.Vars(r)
+	seriesID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid series ID")
+		return 0, 0, false
+	}
+	bookID, err := strconv.ParseInt(vars["book_id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid book ID")
+		return 0, 0, false
+	}
+	return seriesID, bookID, true
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+type TaxonomyHandler struct {
+	taxonomyService *service.TaxonomyService
+	bookService     *service.BookService
+}
+
+func NewTaxonomyHandler(taxonomyService *service.TaxonomyService, bookService *service.BookService) *TaxonomyHandler {
+	return &TaxonomyHandler{
+		taxonomyService: taxonomyService,
+		bookService:     bookService,
+	}
+}
+
+// Genre handlers
+func (h *TaxonomyHandler) CreateGenre(w http.ResponseWriter, r *http.Request) {
+	var req domain.GenreCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	genre, err := h.taxonomyService.CreateGenre(middleware.GetUserID(r.Context()), &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusCreated, genre)
+}
+
+func (h *TaxonomyHandler) GetGenres(w http.ResponseWriter, r *http.Request) {
+	genres, err := h.taxonomyService.GetAllGenres()
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, domain.SinglePage(genres))
+}
+
+func (h *TaxonomyHandler) GetGenre(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid genre ID")
+		return
+	}
+
+	genre, err := h.taxonomyService.GetGenre(id)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, genre)
+}
+
+// GetGenreBooks lists the books of a genre, and with include_descendants
+// those of its subgenres too. It takes the filters of the books listing.
+func (h *TaxonomyHandler) GetGenreBooks(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid genre ID")
+		return
+	}
+
+	if _, err := h.taxonomyService.GetGenre(id); err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	listBooks(w, r, h.bookService, func(filter *domain.BookFilter) { filter.GenreID = id })
+}
+
+func (h *TaxonomyHandler) UpdateGenre(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid genre ID")
+		return
+	}
+
+	var req domain.GenreCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	genre, err := h.taxonomyService.UpdateGenre(middleware.GetUserID(r.Context()), id, &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, genre)
+}
+
+func (h *TaxonomyHandler) DeleteGenre(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid genre ID")
+		return
+	}
+
+	err = h.taxonomyService.DeleteGenre(middleware.GetUserID(r.Context()), id, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Genre deleted successfully")
+}
+
+// Tag handlers
+func (h *TaxonomyHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var req domain.TagCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tag, err := h.taxonomyService.CreateTag(middleware.GetUserID(r.Context()), &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusCreated, tag)
+}
+
+// GetTags lists the tags by name, only those starting with q when given.
+func (h *TaxonomyHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	page, err := pageRequest(r)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tags, err := h.taxonomyService.ListTags(strings.TrimSpace(r.URL.Query().Get("q")), page)
+	if err != nil {
+		listErrorResponse(w, err)
+		return
+	}
+
+	setPageLinks(w, r, tags)
+	utils.SuccessResponseWithData(w, tags)
+}
+
+func (h *TaxonomyHandler) GetTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid tag ID")
+		return
+	}
+
+	tag, err := h.taxonomyService.GetTag(id)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, tag)
+}
+
+// GetTagBooks lists the books with a tag. It takes the filters of the books
+// listing.
+func (h *TaxonomyHandler) GetTagBooks(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid tag ID")
+		return
+	}
+
+	if _, err := h.taxonomyService.GetTag(id); err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	listBooks(w, r, h.bookService, func(filter *domain.BookFilter) { filter.TagID = id })
+}
+
+func (h *TaxonomyHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid tag ID")
+		return
+	}
+
+	var req domain.TagCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tag, err := h.taxonomyService.UpdateTag(middleware.GetUserID(r.Context()), id, &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, tag)
+}
+
+func (h *TaxonomyHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid tag ID")
+		return
+	}
+
+	err = h.taxonomyService.DeleteTag(middleware.GetUserID(r.Context()), id, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Tag deleted successfully")
+}
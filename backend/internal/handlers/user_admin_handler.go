@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+type UserAdminHandler struct {
+	userAdminService *service.UserAdminService
+}
+
+func NewUserAdminHandler(userAdminService *service.UserAdminService) *UserAdminHandler {
+	return &UserAdminHandler{userAdminService: userAdminService}
+}
+
+func (h *UserAdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	actorUserID := middleware.GetUserID(r.Context())
+	query := r.URL.Query()
+
+	page, err := pageRequest(r)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := domain.UserFilter{
+		Email:    query.Get("email"),
+		Username: query.Get("username"),
+		Role:     query.Get("role"),
+	}
+
+	for param, flag := range map[string]**bool{
+		"two_factor": &filter.TwoFactor,
+		"verified":   &filter.Verified,
+		"suspended":  &filter.Suspended,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.ErrorResponse(w, http.StatusBadRequest, "invalid value for "+param)
+			return
+		}
+		*flag = &parsed
+	}
+
+	users, err := h.userAdminService.ListUsers(actorUserID, filter, page)
+	if err != nil {
+		listErrorResponse(w, err)
+		return
+	}
+
+	setPageLinks(w, r, users)
+	utils.SuccessResponseWithData(w, users)
+}
+
+func (h *UserAdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	targetUserID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userAdminService.GetUser(middleware.GetUserID(r.Context()), targetUserID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, user)
+}
+
+func (h *UserAdminHandler) DemoteUser(w http.ResponseWriter, r *http.Request) {
+	targetUserID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userAdminService.DemoteUser(middleware.GetUserID(r.Context()), targetUserID, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, user)
+}
+
+func (h *UserAdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	targetUserID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.UserSuspension
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.userAdminService.SuspendUser(middleware.GetUserID(r.Context()), targetUserID, req.Reason, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, user)
+}
+
+func (h *UserAdminHandler) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	targetUserID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userAdminService.UnsuspendUser(middleware.GetUserID(r.Context()), targetUserID, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, user)
+}
+
+func (h *UserAdminHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	targetUserID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.userAdminService.ForcePasswordReset(middleware.GetUserID(r.Context()), targetUserID, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Password cleared and reset link sent")
+}
+
+func (h *UserAdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	targetUserID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.userAdminService.DeleteUser(middleware.GetUserID(r.Context()), targetUserID, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "User deleted")
+}
+
+// userIDParam parses the {id} route variable, writing a 400 response if it
+// is not a number.
+func userIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid user ID")
+		return 0, false
+	}
+	return userID, true
+}
@@ -42,7 +42,7 @@ func (h *UserBookHandler) AddToReadingList(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = h.userBookService.AddToReadingList(userID, bookID, req.Status)
+	err = h.userBookService.AddToReadingList(userID, bookID, req.Status, req.EditionID, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -60,7 +60,7 @@ func (h *UserBookHandler) RemoveFromReadingList(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = h.userBookService.RemoveFromReadingList(userID, bookID)
+	err = h.userBookService.RemoveFromReadingList(userID, bookID, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -73,15 +73,32 @@ func (h *UserBookHandler) GetReadingList(w http.ResponseWriter, r *http.Request)
 	userID := middleware.GetUserID(r.Context())
 	status := r.URL.Query().Get("status")
 
-	books, err := h.userBookService.GetUserReadingList(userID, status)
+	page, err := pageRequest(r)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	books, err := h.userBookService.GetUserReadingList(userID, status, page)
+	if err != nil {
+		listErrorResponse(w, err)
 		return
 	}
 
+	setPageLinks(w, r, books)
 	utils.SuccessResponseWithData(w, books)
 }
 
+func (h *UserBookHandler) GetSeriesProgress(w http.ResponseWriter, r *http.Request) {
+	progress, err := h.userBookService.GetSeriesProgress(middleware.GetUserID(r.Context()))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, domain.SinglePage(progress))
+}
+
 // Favorite handlers
 func (h *UserBookHandler) AddToFavorites(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
@@ -92,7 +109,7 @@ func (h *UserBookHandler) AddToFavorites(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.userBookService.AddToFavorites(userID, bookID)
+	err = h.userBookService.AddToFavorites(userID, bookID, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -110,7 +127,7 @@ func (h *UserBookHandler) RemoveFromFavorites(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = h.userBookService.RemoveFromFavorites(userID, bookID)
+	err = h.userBookService.RemoveFromFavorites(userID, bookID, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -122,15 +139,88 @@ func (h *UserBookHandler) RemoveFromFavorites(w http.ResponseWriter, r *http.Req
 func (h *UserBookHandler) GetFavorites(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 
-	favorites, err := h.userBookService.GetUserFavorites(userID)
+	page, err := pageRequest(r)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	favorites, err := h.userBookService.GetUserFavorites(userID, page)
+	if err != nil {
+		listErrorResponse(w, err)
+		return
+	}
+
+	setPageLinks(w, r, favorites)
 	utils.SuccessResponseWithData(w, favorites)
 }
 
+// Rating handlers
+func (h *UserBookHandler) RateBook(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	vars := mux.Vars(r)
+	bookID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid book ID")
+		return
+	}
+
+	var req domain.BookRating
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rating, err := h.userBookService.RateBook(userID, bookID, req.Rating, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, rating)
+}
+
+func (h *UserBookHandler) GetRating(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	vars := mux.Vars(r)
+	bookID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid book ID")
+		return
+	}
+
+	rating, err := h.userBookService.GetRating(userID, bookID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, rating)
+}
+
+func (h *UserBookHandler) RemoveRating(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	vars := mux.Vars(r)
+	bookID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid book ID")
+		return
+	}
+
+	err = h.userBookService.RemoveRating(userID, bookID, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Rating removed")
+}
+
 // Comment handlers
 func (h *UserBookHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
@@ -152,7 +242,7 @@ func (h *UserBookHandler) CreateComment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	comment, err := h.userBookService.CreateComment(userID, bookID, req.Content)
+	comment, err := h.userBookService.CreateComment(userID, bookID, req.Content, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -169,12 +259,19 @@ func (h *UserBookHandler) GetBookComments(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	comments, err := h.userBookService.GetBookComments(bookID)
+	page, err := pageRequest(r)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comments, err := h.userBookService.GetBookComments(bookID, page)
+	if err != nil {
+		listErrorResponse(w, err)
 		return
 	}
 
+	setPageLinks(w, r, comments)
 	utils.SuccessResponseWithData(w, comments)
 }
 
@@ -198,7 +295,7 @@ func (h *UserBookHandler) UpdateComment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.userBookService.UpdateComment(commentID, userID, req.Content)
+	err = h.userBookService.UpdateComment(commentID, userID, req.Content, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -216,7 +313,8 @@ func (h *UserBookHandler) DeleteComment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.userBookService.DeleteComment(commentID, userID)
+	moderate := middleware.HasPermission(r.Context(), domain.PermissionCommentsModerate)
+	err = h.userBookService.DeleteComment(commentID, userID, moderate, clientInfo(r))
 	if err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
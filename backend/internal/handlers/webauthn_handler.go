@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/middleware"
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+type WebAuthnHandler struct {
+	webAuthnService *service.WebAuthnService
+}
+
+func NewWebAuthnHandler(webAuthnService *service.WebAuthnService) *WebAuthnHandler {
+	return &WebAuthnHandler{webAuthnService: webAuthnService}
+}
+
+// Registration handlers
+func (h *WebAuthnHandler) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	var req domain.WebAuthnRegisterBegin
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	options, err := h.webAuthnService.BeginRegistration(userID, req.Name)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, options)
+}
+
+func (h *WebAuthnHandler) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	var req domain.WebAuthnFinish
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	credential, err := h.webAuthnService.FinishRegistration(userID, &req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusCreated, credential)
+}
+
+func (h *WebAuthnHandler) GetCredentials(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+
+	credentials, err := h.webAuthnService.GetCredentials(userID)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, domain.SinglePage(credentials))
+}
+
+func (h *WebAuthnHandler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	vars := mux.Vars(r)
+	credentialID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid credential ID")
+		return
+	}
+
+	err = h.webAuthnService.DeleteCredential(userID, credentialID, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithMessage(w, "Security key removed")
+}
+
+// Second factor handlers
+func (h *WebAuthnHandler) BeginSecondFactor(w http.ResponseWriter, r *http.Request) {
+	var req domain.WebAuthnSecondFactorBegin
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	options, err := h.webAuthnService.BeginSecondFactor(req.ChallengeToken, clientInfo(r))
+	if err != nil {
+		authErrorResponse(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	utils.SuccessResponseWithData(w, options)
+}
+
+func (h *WebAuthnHandler) FinishSecondFactor(w http.ResponseWriter, r *http.Request) {
+	var req domain.WebAuthnFinish
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.webAuthnService.FinishSecondFactor(&req, clientInfo(r))
+	if err != nil {
+		authErrorResponse(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	utils.SuccessResponseWithData(w, map[string]interface{}{
+		"tokens": result.Tokens,
+		"user":   result.User,
+	})
+}
+
+// Passwordless login handlers
+func (h *WebAuthnHandler) BeginLogin(w http.ResponseWriter, r *http.Request) {
+	options, err := h.webAuthnService.BeginLogin()
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, options)
+}
+
+func (h *WebAuthnHandler) FinishLogin(w http.ResponseWriter, r *http.Request) {
+	var req domain.WebAuthnFinish
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := validator.Validate(&req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.webAuthnService.FinishLogin(&req, clientInfo(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	utils.SuccessResponseWithData(w, map[string]interface{}{
+		"tokens": result.Tokens,
+		"user":   result.User,
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/service"
 	"github.com/razvan/library-app/internal/utils"
 	"github.com/razvan/library-app/pkg/auth"
 )
@@ -12,21 +14,37 @@ import (
 type contextKey string
 
 const (
-	UserIDKey  contextKey = "user_id"
-	EmailKey   contextKey = "email"
-	IsAdminKey contextKey = "is_admin"
+	UserIDKey      contextKey = "user_id"
+	EmailKey       contextKey = "email"
+	RolesKey       contextKey = "roles"
+	PermissionsKey contextKey = "permissions"
+	SessionIDKey   contextKey = "session_id"
+	APIKeyKey      contextKey = "api_key"
 )
 
 type AuthMiddleware struct {
-	jwtSecret string
+	keys           *auth.KeySet
+	sessionService *service.SessionService
+	apiKeyService  *service.APIKeyService
 }
 
-func NewAuthMiddleware(jwtSecret string) *AuthMiddleware {
-	return &AuthMiddleware{jwtSecret: jwtSecret}
+func NewAuthMiddleware(keys *auth.KeySet, sessionService *service.SessionService, apiKeyService *service.APIKeyService) *AuthMiddleware {
+	return &AuthMiddleware{
+		keys:           keys,
+		sessionService: sessionService,
+		apiKeyService:  apiKeyService,
+	}
 }
 
+// Authenticate accepts either an access token from a login session or an API
+// key, sent as a bearer token or in the X-API-Key header.
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+			m.authenticateAPIKey(w, r, next, apiKey)
+			return
+		}
+
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			utils.ErrorResponse(w, http.StatusUnauthorized, "missing authorization header")
@@ -40,26 +58,73 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 		}
 
 		token := parts[1]
-		claims, err := auth.ValidateToken(token, m.jwtSecret)
+		if service.IsAPIKey(token) {
+			m.authenticateAPIKey(w, r, next, token)
+			return
+		}
+
+		claims, err := auth.ValidateAccessToken(token, m.keys)
 		if err != nil {
 			utils.ErrorResponse(w, http.StatusUnauthorized, "invalid or expired token")
 			return
 		}
 
+		// Reject tokens whose session was logged out or revoked
+		if err := m.sessionService.ValidateSession(claims.SessionID); err != nil {
+			utils.ErrorResponse(w, http.StatusUnauthorized, "session expired or revoked")
+			return
+		}
+
 		// Add claims to context
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, EmailKey, claims.Email)
-		ctx = context.WithValue(ctx, IsAdminKey, claims.IsAdmin)
+		ctx = context.WithValue(ctx, RolesKey, claims.Roles)
+		ctx = context.WithValue(ctx, PermissionsKey, claims.Permissions)
+		ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
+func (m *AuthMiddleware) authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, plain string) {
+	key, user, err := m.apiKeyService.Authenticate(plain, utils.ClientIP(r))
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// Permissions come from the owner's current roles, not from a token
+	ctx := context.WithValue(r.Context(), UserIDKey, user.ID)
+	ctx = context.WithValue(ctx, EmailKey, user.Email)
+	ctx = context.WithValue(ctx, RolesKey, user.Roles)
+	ctx = context.WithValue(ctx, PermissionsKey, user.Permissions)
+	ctx = context.WithValue(ctx, APIKeyKey, key)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope rejects API keys that were not given the scope. Requests from
+// login sessions are not limited by scopes. It must run after Authenticate.
+func (m *AuthMiddleware) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := GetAPIKey(r.Context()); key != nil && !key.HasScope(scope) {
+				utils.ErrorResponse(w, http.StatusForbidden, "api key is missing scope: "+scope)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects API keys, for account and security settings that
+// should only be changed from a login session. It must run after
+// Authenticate.
+func (m *AuthMiddleware) RequireSession(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		isAdmin, ok := r.Context().Value(IsAdminKey).(bool)
-		if !ok || !isAdmin {
-			utils.ErrorResponse(w, http.StatusForbidden, "admin access required")
+		if GetAPIKey(r.Context()) != nil {
+			utils.ErrorResponse(w, http.StatusForbidden, "this endpoint cannot be used with an api key")
 			return
 		}
 
@@ -67,6 +132,21 @@ func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
 	})
 }
 
+// RequirePermission only lets through users whose token grants the
+// permission. It must run after Authenticate.
+func (m *AuthMiddleware) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasPermission(r.Context(), permission) {
+				utils.ErrorResponse(w, http.StatusForbidden, "missing permission: "+permission)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func GetUserID(ctx context.Context) int64 {
 	userID, ok := ctx.Value(UserIDKey).(int64)
 	if !ok {
@@ -83,10 +163,36 @@ func GetEmail(ctx context.Context) string {
 	return email
 }
 
-func IsAdmin(ctx context.Context) bool {
-	isAdmin, ok := ctx.Value(IsAdminKey).(bool)
+func GetRoles(ctx context.Context) []string {
+	roles, _ := ctx.Value(RolesKey).([]string)
+	return roles
+}
+
+func GetPermissions(ctx context.Context) []string {
+	permissions, _ := ctx.Value(PermissionsKey).([]string)
+	return permissions
+}
+
+func HasPermission(ctx context.Context, permission string) bool {
+	for _, p := range GetPermissions(ctx) {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func GetSessionID(ctx context.Context) string {
+	sessionID, ok := ctx.Value(SessionIDKey).(string)
 	if !ok {
-		return false
+		return ""
 	}
-	return isAdmin
+	return sessionID
+}
+
+// GetAPIKey returns the API key the request was authenticated with, or nil
+// for requests from a login session.
+func GetAPIKey(ctx context.Context) *domain.APIKey {
+	key, _ := ctx.Value(APIKeyKey).(*domain.APIKey)
+	return key
 }
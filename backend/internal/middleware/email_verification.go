@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/razvan/library-app/internal/service"
+	"github.com/razvan/library-app/internal/utils"
+)
+
+type EmailVerificationMiddleware struct {
+	emailService *service.EmailVerificationService
+}
+
+func NewEmailVerificationMiddleware(emailService *service.EmailVerificationService) *EmailVerificationMiddleware {
+	return &EmailVerificationMiddleware{emailService: emailService}
+}
+
+// RequireVerified blocks unverified accounts when the email verification
+// policy covers writes. It must run after Authenticate.
+func (m *EmailVerificationMiddleware) RequireVerified(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := m.emailService.CheckWrite(GetUserID(r.Context())); err != nil {
+			utils.ErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
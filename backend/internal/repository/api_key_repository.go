@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `
+		id, user_id, name, prefix, key_hash, scopes,
+		expires_at, last_used_at, COALESCE(last_used_ip, ''), created_at`
+
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	key := &domain.APIKey{}
+	var expiresAt, lastUsedAt sql.NullTime
+
+	err := row.Scan(
+		&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, pq.Array(&key.Scopes),
+		&expiresAt, &lastUsedAt, &key.LastUsedIP, &key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return key, nil
+}
+
+func (r *APIKeyRepository) Create(key *domain.APIKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(
+		query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (r *APIKeyRepository) GetByHash(keyHash string) (*domain.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, keyHash))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *APIKeyRepository) GetByUser(userID int64) ([]domain.APIKey, error) {
+	rows, err := r.db.Query(`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Touch records that a key was used. To avoid a write on every request the
+// timestamp is only moved forward once a minute.
+func (r *APIKeyRepository) Touch(id int64, ipAddress string) error {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = CURRENT_TIMESTAMP, last_used_ip = $2
+		WHERE id = $1
+		  AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')`
+
+	_, err := r.db.Exec(query, id, ipAddress)
+	return err
+}
+
+func (r *APIKeyRepository) Delete(userID, id int64) error {
+	result, err := r.db.Exec("DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("api key not found")
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepository) CountByUser(userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM api_keys WHERE user_id = $1", userID).Scan(&count)
+	return count, err
+}
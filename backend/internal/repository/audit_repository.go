@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+// auditChainLock is the advisory lock that serializes appends, so that two
+// events never claim the same predecessor.
+const auditChainLock = 0x61756469
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+const auditColumns = `
+		id, actor_user_id, action, COALESCE(target_type, ''), target_id,
+		COALESCE(ip_address, ''), COALESCE(user_agent, ''), COALESCE(before, ''), COALESCE(after, ''),
+		COALESCE(reason, ''), created_at, prev_hash, hash`
+
+func scanAuditEvent(row rowScanner) (*domain.AuditEvent, error) {
+	event := &domain.AuditEvent{}
+	var actorUserID, targetID sql.NullInt64
+	var before, after string
+
+	err := row.Scan(
+		&event.ID, &actorUserID, &event.Action, &event.TargetType, &targetID,
+		&event.IPAddress, &event.UserAgent, &before, &after,
+		&event.Reason, &event.CreatedAt, &event.PrevHash, &event.Hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if actorUserID.Valid {
+		event.ActorUserID = &actorUserID.Int64
+	}
+	if targetID.Valid {
+		event.TargetID = &targetID.Int64
+	}
+	if before != "" {
+		event.Before = json.RawMessage(before)
+	}
+	if after != "" {
+		event.After = json.RawMessage(after)
+	}
+
+	return event, nil
+}
+
+// Append links the event to the latest one and stores it. It sets the
+// event's ID, CreatedAt, PrevHash and Hash.
+func (r *AuditRepository) Append(event *domain.AuditEvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1`).Scan(&event.PrevHash)
+	if err == sql.ErrNoRows {
+		event.PrevHash = strings.Repeat("0", 64)
+	} else if err != nil {
+		return err
+	}
+
+	// Postgres keeps microseconds, so hash exactly what will be stored
+	event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	event.Hash = event.ComputeHash()
+
+	query := `
+		INSERT INTO audit_events (
+			actor_user_id, action, target_type, target_id, ip_address, user_agent,
+			before, after, reason, created_at, prev_hash, hash
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`
+
+	err = tx.QueryRow(
+		query,
+		event.ActorUserID,
+		event.Action,
+		sql.NullString{String: event.TargetType, Valid: event.TargetType != ""},
+		event.TargetID,
+		sql.NullString{String: event.IPAddress, Valid: event.IPAddress != ""},
+		sql.NullString{String: event.UserAgent, Valid: event.UserAgent != ""},
+		sql.NullString{String: string(event.Before), Valid: len(event.Before) > 0},
+		sql.NullString{String: string(event.After), Valid: len(event.After) > 0},
+		sql.NullString{String: event.Reason, Valid: event.Reason != ""},
+		event.CreatedAt,
+		event.PrevHash,
+		event.Hash,
+	).Scan(&event.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// auditKeyset orders events newest first. IDs follow the chain, so they
+// are in the order the events happened.
+var auditKeyset = keyset{sort: "id", columns: []string{"id"}, descending: true}
+
+// List returns a page of events, newest first. An action filter without a
+// dot, such as "book", matches every action in that group.
+func (r *AuditRepository) List(filter domain.AuditFilter, page domain.PageRequest) (*domain.Page[domain.AuditEvent], error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorUserID != 0 {
+		addCondition("actor_user_id = $%d", filter.ActorUserID)
+	}
+	if filter.Action != "" {
+		if strings.Contains(filter.Action, ".") {
+			addCondition("action = $%d", filter.Action)
+		} else {
+			addCondition("action LIKE $%d ESCAPE '\\'", escapeLike(filter.Action)+".%")
+		}
+	}
+	if filter.TargetType != "" {
+		addCondition("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		addCondition("target_id = $%d", filter.TargetID)
+	}
+	if filter.From != nil {
+		addCondition("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("created_at < $%d", *filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM audit_events`+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	seek, args, err := auditKeyset.seek(page, args)
+	if err != nil {
+		return nil, err
+	}
+	order, args := auditKeyset.orderBy(page, args)
+
+	rows, err := r.db.Query(`SELECT `+auditColumns+auditKeyset.keys()+` FROM audit_events`+andWhere(where, seek)+order, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
+	defer rows.Close()
+
+	events := []domain.AuditEvent{}
+	var keys [][]string
+	for rows.Next() {
+		row := auditKeyset.row(rows)
+		event, err := scanAuditEvent(row)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newPage(auditKeyset, page, events, keys, total), nil
+}
+
+// GetByActor returns the events of actions the user took, oldest first.
+func (r *AuditRepository) GetByActor(userID int64) ([]domain.AuditEvent, error) {
+	rows, err := r.db.Query(`SELECT `+auditColumns+` FROM audit_events WHERE actor_user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []domain.AuditEvent{}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+
+	return events, rows.Err()
+}
+
+// Walk calls fn for every event in chain order, stopping at the first error.
+func (r *AuditRepository) Walk(fn func(*domain.AuditEvent) error) error {
+	rows, err := r.db.Query(`SELECT ` + auditColumns + ` FROM audit_events ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -3,8 +3,9 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"html"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/razvan/library-app/internal/domain"
 )
@@ -17,7 +18,53 @@ func NewBookRepository(db *sql.DB) *BookRepository {
 	return &BookRepository{db: db}
 }
 
-func (r *BookRepository) Create(book *domain.Book, authorIDs []int64) error {
+// bookColumns are read by scanBook. They need the primary edition and
+// rating_stats lateral joins of bookFrom.
+const bookColumns = `
+		b.id, b.title, b.description, pe.cover_url, pe.isbn, pe.published_at,
+		COALESCE(pe.language, ''), COALESCE(rs.average, 0), COALESCE(rs.count, 0),
+		b.created_at, b.updated_at`
+
+// primaryEditionJoin adds the book's first edition as pe, which the book's
+// ISBN, cover, language and publication date come from.
+const primaryEditionJoin = `
+		LEFT JOIN LATERAL (
+			SELECT id, isbn, language, published_at, cover_url
+			FROM editions WHERE book_id = b.id
+			ORDER BY id LIMIT 1
+		) pe ON true`
+
+const bookFrom = `
+		FROM books b` + primaryEditionJoin + `
+		LEFT JOIN LATERAL (
+			SELECT AVG(rating)::float8 AS average, COUNT(*) AS count
+			FROM book_ratings WHERE book_id = b.id
+		) rs ON true`
+
+// scanBook reads bookColumns, followed by any extra columns into extra.
+func scanBook(row rowScanner, extra ...interface{}) (*domain.Book, error) {
+	book := &domain.Book{}
+	var coverURL, isbn sql.NullString
+	var publishedAt sql.NullTime
+
+	dest := []interface{}{
+		&book.ID, &book.Title, &book.Description, &coverURL, &isbn, &publishedAt,
+		&book.Language, &book.AverageRating, &book.RatingCount,
+		&book.CreatedAt, &book.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	book.CoverURL = coverURL.String
+	book.ISBN = isbn.String
+	book.PublishedAt = publishedAt.Time
+	return book, nil
+}
+
+// Create saves the book as a work with its first edition, which holds the
+// book's ISBN, cover, language and publication date.
+func (r *BookRepository) Create(book *domain.Book, authorIDs, genreIDs, tagIDs []int64) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -25,19 +72,27 @@ func (r *BookRepository) Create(book *domain.Book, authorIDs []int64) error {
 	defer tx.Rollback()
 
 	query := `
-		INSERT INTO books (title, description, cover_url, isbn, published_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO books (title, description)
+		VALUES ($1, $2)
 		RETURNING id, created_at, updated_at`
 
-	err = tx.QueryRow(
-		query,
-		book.Title,
-		book.Description,
-		book.CoverURL,
-		book.ISBN,
-		book.PublishedAt,
-	).Scan(&book.ID, &book.CreatedAt, &book.UpdatedAt)
+	err = tx.QueryRow(query, book.Title, book.Description).Scan(&book.ID, &book.CreatedAt, &book.UpdatedAt)
+	if err != nil {
+		return err
+	}
 
+	_, err = tx.Exec(`
+		INSERT INTO editions (book_id, isbn, language, published_at, cover_url)
+		VALUES ($1, $2, $3, $4, $5)`,
+		book.ID,
+		sql.NullString{String: book.ISBN, Valid: book.ISBN != ""},
+		sql.NullString{String: book.Language, Valid: book.Language != ""},
+		sql.NullTime{Time: book.PublishedAt, Valid: !book.PublishedAt.IsZero()},
+		sql.NullString{String: book.CoverURL, Valid: book.CoverURL != ""},
+	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("an edition with ISBN %s already exists", book.ISBN)
+	}
 	if err != nil {
 		return err
 	}
@@ -53,22 +108,81 @@ func (r *BookRepository) Create(book *domain.Book, authorIDs []int64) error {
 		}
 	}
 
+	if err := setBookGenres(tx, book.ID, genreIDs); err != nil {
+		return err
+	}
+	if err := setBookTags(tx, book.ID, tagIDs); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
-func (r *BookRepository) GetByID(id int64) (*domain.Book, error) {
-	book := &domain.Book{}
-	query := `
-		SELECT id, title, description, cover_url, isbn, published_at,
-		       created_at, updated_at
-		FROM books WHERE id = $1`
+// SaveImported saves a book and one of its editions in one transaction, as
+// read from a catalog file. Either is inserted when its ID is 0 and updated
+// otherwise. The book's authors are replaced when authorIDs is not empty.
+func (r *BookRepository) SaveImported(book *domain.Book, edition *domain.Edition, authorIDs []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	var coverURL, isbn sql.NullString
-	err := r.db.QueryRow(query, id).Scan(
-		&book.ID, &book.Title, &book.Description, &coverURL, &isbn,
-		&book.PublishedAt, &book.CreatedAt, &book.UpdatedAt,
-	)
+	if book.ID == 0 {
+		err = tx.QueryRow(`INSERT INTO books (title, description) VALUES ($1, $2) RETURNING id`,
+			book.Title, book.Description).Scan(&book.ID)
+	} else {
+		_, err = tx.Exec(`UPDATE books SET title = $1, description = $2 WHERE id = $3`,
+			book.Title, book.Description, book.ID)
+	}
+	if err != nil {
+		return err
+	}
 
+	edition.BookID = book.ID
+	if edition.ID == 0 {
+		query := `
+			INSERT INTO editions (isbn, publisher, format, language, page_count, published_at, cover_url, book_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id`
+		err = tx.QueryRow(query, append(editionArgs(edition), edition.BookID)...).Scan(&edition.ID)
+	} else {
+		query := `
+			UPDATE editions
+			SET isbn = $1, publisher = $2, format = $3, language = $4, page_count = $5,
+			    published_at = $6, cover_url = $7
+			WHERE id = $8`
+		_, err = tx.Exec(query, append(editionArgs(edition), edition.ID)...)
+	}
+	if isUniqueViolation(err) {
+		return fmt.Errorf("an edition with ISBN %s already exists", edition.ISBN)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(authorIDs) > 0 {
+		if _, err := tx.Exec("DELETE FROM book_authors WHERE book_id = $1", book.ID); err != nil {
+			return err
+		}
+		for _, authorID := range authorIDs {
+			_, err := tx.Exec(
+				"INSERT INTO book_authors (book_id, author_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+				book.ID, authorID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByISBN returns the book with an edition of the ISBN-13.
+func (r *BookRepository) GetByISBN(isbn string) (*domain.Book, error) {
+	var bookID int64
+	err := r.db.QueryRow(`SELECT book_id FROM editions WHERE isbn = $1`, isbn).Scan(&bookID)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("book not found")
 	}
@@ -76,11 +190,18 @@ func (r *BookRepository) GetByID(id int64) (*domain.Book, error) {
 		return nil, err
 	}
 
-	if coverURL.Valid {
-		book.CoverURL = coverURL.String
+	return r.GetByID(bookID)
+}
+
+func (r *BookRepository) GetByID(id int64) (*domain.Book, error) {
+	query := `SELECT ` + bookColumns + bookFrom + ` WHERE b.id = $1`
+
+	book, err := scanBook(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("book not found")
 	}
-	if isbn.Valid {
-		book.ISBN = isbn.String
+	if err != nil {
+		return nil, err
 	}
 
 	// Get authors
@@ -89,117 +210,486 @@ func (r *BookRepository) GetByID(id int64) (*domain.Book, error) {
 		return nil, err
 	}
 
+	book.Genres, err = r.GetBookGenres(id)
+	if err != nil {
+		return nil, err
+	}
+
+	book.Tags, err = r.GetBookTags(id)
+	if err != nil {
+		return nil, err
+	}
+
+	book.Series, err = r.GetBookSeries(id)
+	if err != nil {
+		return nil, err
+	}
+
+	book.Editions, err = r.GetBookEditions(id)
+	if err != nil {
+		return nil, err
+	}
+
 	return book, nil
 }
 
-func (r *BookRepository) GetAll(limit, offset int) ([]domain.Book, error) {
-	query := `
-		SELECT id, title, description, cover_url, isbn, published_at,
-		       created_at, updated_at
-		FROM books
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2`
+// bookKeyset returns the expressions the filter's sort orders by. The book
+// ID comes last so that the order is stable.
+func bookKeyset(filter domain.BookFilter) keyset {
+	var columns []string
+	switch filter.Sort {
+	case domain.BookSortTitle:
+		columns = []string{"lower(b.title)", "b.id"}
+	case domain.BookSortPublished:
+		// Books without a publication date come last either way
+		missing := "'infinity'::date"
+		if filter.Descending {
+			missing = "'-infinity'::date"
+		}
+		columns = []string{"COALESCE(pe.published_at, " + missing + ")", "b.id"}
+	case domain.BookSortRating:
+		columns = []string{"COALESCE(rs.average, 0)", "COALESCE(rs.count, 0)", "b.id"}
+	case domain.BookSortPopularity:
+		columns = []string{"(SELECT COUNT(*) FROM favorites f WHERE f.book_id = b.id) + (SELECT COUNT(*) FROM user_books ub WHERE ub.book_id = b.id)", "b.id"}
+	case domain.BookSortSeries:
+		// Only valid with a series filter, which leaves no book without a position
+		columns = []string{"(SELECT position FROM series_books WHERE book_id = b.id)", "b.id"}
+	default:
+		columns = []string{"b.created_at", "b.id"}
+	}
+
+	return keyset{sort: filter.Sort, columns: columns, descending: filter.Descending}
+}
+
+// Facets of the books listing. Each facet skips its own filter.
+const (
+	facetAuthor   = "author"
+	facetGenre    = "genre"
+	facetTag      = "tag"
+	facetLanguage = "language"
+	facetYear     = "year"
+	facetCover    = "cover"
+)
+
+// maxFacetValues caps the author, genre and tag facets to the most common
+// values.
+const maxFacetValues = 20
+
+// bookConditions turns a filter into a WHERE clause, leaving out the filter
+// of the skipped facet. The clause needs primaryEditionJoin.
+func bookConditions(filter domain.BookFilter, skip string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.AuthorID != 0 && skip != facetAuthor {
+		addCondition("EXISTS (SELECT 1 FROM book_authors WHERE book_id = b.id AND author_id = $%d)", filter.AuthorID)
+	}
+	if filter.GenreID != 0 && skip != facetGenre {
+		if filter.IncludeDescendants {
+			addCondition("EXISTS (SELECT 1 FROM book_genres WHERE book_id = b.id AND genre_id IN ("+genreSubtree+"))", filter.GenreID)
+		} else {
+			addCondition("EXISTS (SELECT 1 FROM book_genres WHERE book_id = b.id AND genre_id = $%d)", filter.GenreID)
+		}
+	}
+	if filter.TagID != 0 && skip != facetTag {
+		addCondition("EXISTS (SELECT 1 FROM book_tags WHERE book_id = b.id AND tag_id = $%d)", filter.TagID)
+	}
+	if filter.SeriesID != 0 {
+		addCondition("EXISTS (SELECT 1 FROM series_books WHERE book_id = b.id AND series_id = $%d)", filter.SeriesID)
+	}
+	// A book is in every language it has an edition in, so that a
+	// translation finds the original work
+	if filter.Language != "" && skip != facetLanguage {
+		addCondition("EXISTS (SELECT 1 FROM editions WHERE book_id = b.id AND lower(language) = lower($%d))", filter.Language)
+	}
+	if skip != facetYear {
+		if filter.YearFrom != 0 {
+			addCondition("pe.published_at >= make_date($%d, 1, 1)", filter.YearFrom)
+		}
+		if filter.YearTo != 0 {
+			addCondition("pe.published_at < make_date($%d, 1, 1)", filter.YearTo+1)
+		}
+	}
+	if filter.HasCover != nil && skip != facetCover {
+		addCondition("(COALESCE(pe.cover_url, '') <> '') = $%d", *filter.HasCover)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// List returns a filtered and sorted page of books.
+func (r *BookRepository) List(filter domain.BookFilter, page domain.PageRequest) (*domain.Page[domain.Book], error) {
+	where, args := bookConditions(filter, "")
 
-	rows, err := r.db.Query(query, limit, offset)
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM books b`+primaryEditionJoin+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	keyset := bookKeyset(filter)
+	seek, args, err := keyset.seek(page, args)
 	if err != nil {
 		return nil, err
 	}
+	order, args := keyset.orderBy(page, args)
+
+	rows, err := r.db.Query(`SELECT `+bookColumns+keyset.keys()+bookFrom+andWhere(where, seek)+order, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
 	defer rows.Close()
 
-	var books []domain.Book
+	books := []domain.Book{}
+	var keys [][]string
 	for rows.Next() {
-		var book domain.Book
-		var coverURL, isbn sql.NullString
-
-		err := rows.Scan(
-			&book.ID, &book.Title, &book.Description, &coverURL, &isbn,
-			&book.PublishedAt, &book.CreatedAt, &book.UpdatedAt,
-		)
+		row := keyset.row(rows)
+		book, err := scanBook(row)
 		if err != nil {
 			return nil, err
 		}
+		books = append(books, *book)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		if coverURL.Valid {
-			book.CoverURL = coverURL.String
+	result := newPage(keyset, page, books, keys, total)
+	for i := range result.Items {
+		if result.Items[i].Authors, err = r.GetBookAuthors(result.Items[i].ID); err != nil {
+			return nil, err
+		}
+		if result.Items[i].Genres, err = r.GetBookGenres(result.Items[i].ID); err != nil {
+			return nil, err
+		}
+		if result.Items[i].Tags, err = r.GetBookTags(result.Items[i].ID); err != nil {
+			return nil, err
 		}
-		if isbn.Valid {
-			book.ISBN = isbn.String
+		if result.Items[i].Series, err = r.GetBookSeries(result.Items[i].ID); err != nil {
+			return nil, err
 		}
+	}
+
+	return result, nil
+}
+
+// Facets counts the books matching the filter for each facet value.
+func (r *BookRepository) Facets(filter domain.BookFilter) (*domain.BookFacets, error) {
+	facets := &domain.BookFacets{}
+	var err error
+
+	facets.Authors, err = r.countFacet(filter, facetAuthor, "", fmt.Sprintf(`
+		SELECT a.id::text, a.name, COUNT(*)
+		FROM books b`+primaryEditionJoin+`
+		JOIN book_authors ba ON ba.book_id = b.id
+		JOIN authors a ON a.id = ba.author_id
+		%%s
+		GROUP BY a.id
+		ORDER BY COUNT(*) DESC, a.name
+		LIMIT %d`, maxFacetValues))
+	if err != nil {
+		return nil, err
+	}
+
+	facets.Genres, err = r.countFacet(filter, facetGenre, "", fmt.Sprintf(`
+		SELECT g.id::text, g.name, COUNT(*)
+		FROM books b`+primaryEditionJoin+`
+		JOIN book_genres bg ON bg.book_id = b.id
+		JOIN genres g ON g.id = bg.genre_id
+		%%s
+		GROUP BY g.id
+		ORDER BY COUNT(*) DESC, g.name
+		LIMIT %d`, maxFacetValues))
+	if err != nil {
+		return nil, err
+	}
+
+	facets.Tags, err = r.countFacet(filter, facetTag, "", fmt.Sprintf(`
+		SELECT t.id::text, t.name, COUNT(*)
+		FROM books b`+primaryEditionJoin+`
+		JOIN book_tags bt ON bt.book_id = b.id
+		JOIN tags t ON t.id = bt.tag_id
+		%%s
+		GROUP BY t.id
+		ORDER BY COUNT(*) DESC, t.name
+		LIMIT %d`, maxFacetValues))
+	if err != nil {
+		return nil, err
+	}
+
+	facets.Languages, err = r.countFacet(filter, facetLanguage, "e.language IS NOT NULL", `
+		SELECT lower(e.language), '', COUNT(DISTINCT b.id)
+		FROM books b`+primaryEditionJoin+`
+		JOIN editions e ON e.book_id = b.id
+		%s
+		GROUP BY 1
+		ORDER BY COUNT(DISTINCT b.id) DESC, 1`)
+	if err != nil {
+		return nil, err
+	}
 
-		// Get authors for each book
-		book.Authors, _ = r.GetBookAuthors(book.ID)
-		books = append(books, book)
+	facets.Years, err = r.countFacet(filter, facetYear, "pe.published_at IS NOT NULL", `
+		SELECT EXTRACT(YEAR FROM pe.published_at)::int::text, '', COUNT(*)
+		FROM books b`+primaryEditionJoin+`
+		%s
+		GROUP BY 1
+		ORDER BY 1 DESC`)
+	if err != nil {
+		return nil, err
 	}
 
-	return books, nil
+	facets.HasCover, err = r.countFacet(filter, facetCover, "", `
+		SELECT (COALESCE(pe.cover_url, '') <> '')::text, '', COUNT(*)
+		FROM books b`+primaryEditionJoin+`
+		%s
+		GROUP BY 1
+		ORDER BY 1 DESC`)
+	if err != nil {
+		return nil, err
+	}
+
+	return facets, nil
 }
 
-func (r *BookRepository) Search(query string, limit, offset int) ([]domain.Book, error) {
-	sqlQuery := `
-		SELECT DISTINCT b.id, b.title, b.description, b.cover_url, b.isbn,
-		       b.published_at, b.created_at, b.updated_at
-		FROM books b
-		LEFT JOIN book_authors ba ON b.id = ba.book_id
-		LEFT JOIN authors a ON ba.author_id = a.id
-		WHERE b.title ILIKE $1 OR b.description ILIKE $1 OR a.name ILIKE $1
-		ORDER BY b.created_at DESC
-		LIMIT $2 OFFSET $3`
+// countFacet runs a facet query, whose %s is replaced with the filter's
+// WHERE clause without the facet's own filter, plus the extra condition.
+func (r *BookRepository) countFacet(filter domain.BookFilter, facet, extra, query string) ([]domain.FacetCount, error) {
+	where, args := bookConditions(filter, facet)
+	where = andWhere(where, extra)
 
-	searchPattern := "%" + query + "%"
-	rows, err := r.db.Query(sqlQuery, searchPattern, limit, offset)
+	rows, err := r.db.Query(fmt.Sprintf(query, where), args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var books []domain.Book
+	counts := []domain.FacetCount{}
 	for rows.Next() {
-		var book domain.Book
-		var coverURL, isbn sql.NullString
+		var count domain.FacetCount
+		if err := rows.Scan(&count.Value, &count.Label, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
 
-		err := rows.Scan(
-			&book.ID, &book.Title, &book.Description, &coverURL, &isbn,
-			&book.PublishedAt, &book.CreatedAt, &book.UpdatedAt,
-		)
+	return counts, rows.Err()
+}
+
+// searchConfig is the text search configuration of books.search_vector.
+const searchConfig = "english"
+
+// headlineOptions mark matched words with sentinel characters that cannot
+// appear in normal text, so that the rest can be HTML-escaped safely.
+const (
+	highlightStart  = "\x02"
+	highlightStop   = "\x03"
+	headlineOptions = "StartSel=\x02, StopSel=\x03"
+)
+
+// minFuzzySimilarity is how close, from 0 to 1, a misspelled query has to
+// be to a word sequence of a title or an author name.
+const minFuzzySimilarity = 0.4
+
+// Search ranks books by how well their title, author names and description
+// match a web-style query, such as `"lord of the rings" -hobbit`.
+func (r *BookRepository) Search(query string, page domain.PageRequest) (*domain.Page[domain.BookSearchResult], error) {
+	var total int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM books WHERE search_vector @@ websearch_to_tsquery($1::regconfig, $2)`,
+		searchConfig, query,
+	).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return &domain.Page[domain.BookSearchResult]{Items: []domain.BookSearchResult{}}, nil
+	}
+
+	keyset := keyset{sort: "relevance", columns: []string{"ts_rank_cd(b.search_vector, q)", "b.id"}, descending: true}
+	seek, args, err := keyset.seek(page, []interface{}{searchConfig, headlineOptions, query})
+	if err != nil {
+		return nil, err
+	}
+	order, args := keyset.orderBy(page, args)
+
+	sqlQuery := `
+		SELECT ` + bookColumns + `,
+		       ts_rank_cd(b.search_vector, q),
+		       ts_headline($1::regconfig, b.title, q, $2::text || ', HighlightAll=true'),
+		       ts_headline($1::regconfig, b.description, q, $2::text || ', MaxWords=35, MinWords=15, MaxFragments=2')
+		       ` + keyset.keys() + bookFrom + `
+		CROSS JOIN websearch_to_tsquery($1::regconfig, $3) q` +
+		andWhere(" WHERE b.search_vector @@ q", seek) + order
+
+	rows, err := r.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
+	defer rows.Close()
+
+	results := []domain.BookSearchResult{}
+	var keys [][]string
+	for rows.Next() {
+		var result domain.BookSearchResult
+		row := keyset.row(rows)
+		book, err := scanBook(row, &result.Rank, &result.TitleHighlight, &result.Snippet)
 		if err != nil {
 			return nil, err
 		}
 
-		if coverURL.Valid {
-			book.CoverURL = coverURL.String
-		}
-		if isbn.Valid {
-			book.ISBN = isbn.String
+		result.Book = *book
+		result.TitleHighlight = highlightHTML(result.TitleHighlight)
+		result.Snippet = highlightHTML(result.Snippet)
+		results = append(results, result)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return r.withAuthors(newPage(keyset, page, results, keys, total))
+}
+
+// fuzzyRankJoin scores how closely the query in $1 resembles a word
+// sequence of the title or of any author name. The <% operator picks the
+// candidates through the trigram indexes, so only those are ranked.
+const fuzzyRankJoin = `
+		JOIN (
+			SELECT book_id, MAX(rank) AS rank
+			FROM (
+				SELECT id AS book_id, word_similarity($1::text, title) AS rank
+				FROM books
+				WHERE $1::text <% title
+				UNION ALL
+				SELECT ba.book_id, word_similarity($1::text, a.name)
+				FROM authors a
+				JOIN book_authors ba ON ba.author_id = a.id
+				WHERE $1::text <% a.name
+			) matches
+			GROUP BY book_id
+		) sim ON sim.book_id = b.id`
+
+// SearchFuzzy finds books whose title or author names resemble the query,
+// which tolerates typos the full-text search cannot match.
+func (r *BookRepository) SearchFuzzy(query string, page domain.PageRequest) (*domain.Page[domain.BookSearchResult], error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// <% matches word similarities of at least this threshold
+	_, err = tx.Exec(`SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`,
+		strconv.FormatFloat(minFuzzySimilarity, 'f', -1, 64))
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM books b`+fuzzyRankJoin, query).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	keyset := keyset{sort: "similarity", columns: []string{"sim.rank", "b.id"}, descending: true}
+	seek, args, err := keyset.seek(page, []interface{}{query})
+	if err != nil {
+		return nil, err
+	}
+	order, args := keyset.orderBy(page, args)
+
+	sqlQuery := `SELECT ` + bookColumns + `, sim.rank` + keyset.keys() + bookFrom + fuzzyRankJoin +
+		andWhere("", seek) + order
+
+	rows, err := tx.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
+	defer rows.Close()
+
+	results := []domain.BookSearchResult{}
+	var keys [][]string
+	for rows.Next() {
+		result := domain.BookSearchResult{Fuzzy: true}
+		row := keyset.row(rows)
+		book, err := scanBook(row, &result.Rank)
+		if err != nil {
+			return nil, err
 		}
 
-		book.Authors, _ = r.GetBookAuthors(book.ID)
-		books = append(books, book)
+		result.Book = *book
+		results = append(results, result)
+		keys = append(keys, row.keys)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
-	return books, nil
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.withAuthors(newPage(keyset, page, results, keys, total))
 }
 
-func (r *BookRepository) Update(book *domain.Book, authorIDs []int64) error {
+func (r *BookRepository) withAuthors(results *domain.Page[domain.BookSearchResult]) (*domain.Page[domain.BookSearchResult], error) {
+	for i := range results.Items {
+		authors, err := r.GetBookAuthors(results.Items[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		results.Items[i].Authors = authors
+	}
+	return results, nil
+}
+
+// highlightHTML escapes a ts_headline result and turns its sentinel
+// characters into <mark> tags.
+func highlightHTML(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
+
+// Update saves the book, and its ISBN, cover, language and publication
+// date to its primary edition. Its authors are replaced when authorIDs is
+// not empty, and its genres and tags when genreIDs and tagIDs are not nil.
+func (r *BookRepository) Update(book *domain.Book, authorIDs, genreIDs, tagIDs []int64) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	_, err = tx.Exec(`UPDATE books SET title = $1, description = $2 WHERE id = $3`,
+		book.Title, book.Description, book.ID)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		UPDATE books
-		SET title = $1, description = $2, cover_url = $3, isbn = $4, published_at = $5
-		WHERE id = $6`
+		UPDATE editions
+		SET isbn = $1, language = $2, published_at = $3, cover_url = $4
+		WHERE id = (SELECT id FROM editions WHERE book_id = $5 ORDER BY id LIMIT 1)`
 
 	_, err = tx.Exec(
 		query,
-		book.Title,
-		book.Description,
-		book.CoverURL,
-		book.ISBN,
-		book.PublishedAt,
+		sql.NullString{String: book.ISBN, Valid: book.ISBN != ""},
+		sql.NullString{String: book.Language, Valid: book.Language != ""},
+		sql.NullTime{Time: book.PublishedAt, Valid: !book.PublishedAt.IsZero()},
+		sql.NullString{String: book.CoverURL, Valid: book.CoverURL != ""},
 		book.ID,
 	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("an edition with ISBN %s already exists", book.ISBN)
+	}
 	if err != nil {
 		return err
 	}
@@ -224,9 +714,59 @@ func (r *BookRepository) Update(book *domain.Book, authorIDs []int64) error {
 		}
 	}
 
+	if genreIDs != nil {
+		if _, err := tx.Exec("DELETE FROM book_genres WHERE book_id = $1", book.ID); err != nil {
+			return err
+		}
+		if err := setBookGenres(tx, book.ID, genreIDs); err != nil {
+			return err
+		}
+	}
+
+	if tagIDs != nil {
+		if _, err := tx.Exec("DELETE FROM book_tags WHERE book_id = $1", book.ID); err != nil {
+			return err
+		}
+		if err := setBookTags(tx, book.ID, tagIDs); err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit()
 }
 
+func setBookGenres(tx *sql.Tx, bookID int64, genreIDs []int64) error {
+	for _, genreID := range genreIDs {
+		_, err := tx.Exec(
+			"INSERT INTO book_genres (book_id, genre_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			bookID, genreID,
+		)
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("genre %d not found", genreID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setBookTags(tx *sql.Tx, bookID int64, tagIDs []int64) error {
+	for _, tagID := range tagIDs {
+		_, err := tx.Exec(
+			"INSERT INTO book_tags (book_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			bookID, tagID,
+		)
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("tag %d not found", tagID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *BookRepository) Delete(id int64) error {
 	query := "DELETE FROM books WHERE id = $1"
 	_, err := r.db.Exec(query, id)
@@ -269,6 +809,113 @@ func (r *BookRepository) GetBookAuthors(bookID int64) ([]domain.Author, error) {
 	return authors, nil
 }
 
+func (r *BookRepository) GetBookGenres(bookID int64) ([]domain.Genre, error) {
+	query := `
+		SELECT ` + genreColumns + `
+		FROM genres g
+		JOIN book_genres bg ON g.id = bg.genre_id
+		WHERE bg.book_id = $1
+		ORDER BY g.name`
+
+	return queryGenres(r.db, query, bookID)
+}
+
+func (r *BookRepository) GetBookTags(bookID int64) ([]domain.Tag, error) {
+	query := `
+		SELECT ` + tagColumns + `
+		FROM tags t
+		JOIN book_tags bt ON t.id = bt.tag_id
+		WHERE bt.book_id = $1
+		ORDER BY lower(t.name)`
+
+	rows, err := r.db.Query(query, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []domain.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, *tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// GetBookEditions returns the editions of a book, the primary one first.
+func (r *BookRepository) GetBookEditions(bookID int64) ([]domain.Edition, error) {
+	query := `SELECT ` + editionColumns + ` FROM editions e WHERE e.book_id = $1 ORDER BY e.id`
+
+	rows, err := r.db.Query(query, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	editions := []domain.Edition{}
+	for rows.Next() {
+		edition, err := scanEdition(rows)
+		if err != nil {
+			return nil, err
+		}
+		editions = append(editions, *edition)
+	}
+
+	return editions, rows.Err()
+}
+
+// GetBookSeries returns the series of the book, with the volumes before
+// and after it, or nil when the book is not part of a series.
+func (r *BookRepository) GetBookSeries(bookID int64) (*domain.BookSeries, error) {
+	query := `
+		SELECT s.id, s.name, v.position,
+		       v.prev_id, v.prev_title, v.prev_position,
+		       v.next_id, v.next_title, v.next_position
+		FROM (
+			SELECT sb.series_id, sb.book_id, sb.position,
+			       LAG(sb.book_id) OVER w AS prev_id, LAG(b.title) OVER w AS prev_title,
+			       LAG(sb.position) OVER w AS prev_position,
+			       LEAD(sb.book_id) OVER w AS next_id, LEAD(b.title) OVER w AS next_title,
+			       LEAD(sb.position) OVER w AS next_position
+			FROM series_books sb
+			JOIN books b ON b.id = sb.book_id
+			WHERE sb.series_id = (SELECT series_id FROM series_books WHERE book_id = $1)
+			WINDOW w AS (ORDER BY sb.position)
+		) v
+		JOIN series s ON s.id = v.series_id
+		WHERE v.book_id = $1`
+
+	series := &domain.BookSeries{}
+	var prevID, nextID sql.NullInt64
+	var prevTitle, nextTitle sql.NullString
+	var prevPosition, nextPosition sql.NullFloat64
+
+	err := r.db.QueryRow(query, bookID).Scan(
+		&series.ID, &series.Name, &series.Position,
+		&prevID, &prevTitle, &prevPosition,
+		&nextID, &nextTitle, &nextPosition,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if prevID.Valid {
+		series.Previous = &domain.SeriesVolume{BookID: prevID.Int64, Title: prevTitle.String, Position: prevPosition.Float64}
+	}
+	if nextID.Valid {
+		series.Next = &domain.SeriesVolume{BookID: nextID.Int64, Title: nextTitle.String, Position: nextPosition.Float64}
+	}
+
+	return series, nil
+}
+
 // Author methods
 func (r *BookRepository) CreateAuthor(author *domain.Author) error {
 	query := `
@@ -305,25 +952,38 @@ func (r *BookRepository) GetAuthorByID(id int64) (*domain.Author, error) {
 	return author, nil
 }
 
-func (r *BookRepository) GetAllAuthors(limit, offset int) ([]domain.Author, error) {
-	query := `
-		SELECT id, name, bio, created_at, updated_at
-		FROM authors
-		ORDER BY name ASC
-		LIMIT $1 OFFSET $2`
+// authorKeyset orders authors by name.
+var authorKeyset = keyset{sort: "name", columns: []string{"name", "id"}}
+
+func (r *BookRepository) GetAllAuthors(page domain.PageRequest) (*domain.Page[domain.Author], error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM authors`).Scan(&total); err != nil {
+		return nil, err
+	}
 
-	rows, err := r.db.Query(query, limit, offset)
+	seek, args, err := authorKeyset.seek(page, nil)
 	if err != nil {
 		return nil, err
 	}
+	order, args := authorKeyset.orderBy(page, args)
+
+	query := `SELECT id, name, bio, created_at, updated_at` + authorKeyset.keys() +
+		` FROM authors` + andWhere("", seek) + order
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
 	defer rows.Close()
 
-	var authors []domain.Author
+	authors := []domain.Author{}
+	var keys [][]string
 	for rows.Next() {
 		var author domain.Author
 		var bio sql.NullString
 
-		err := rows.Scan(
+		row := authorKeyset.row(rows)
+		err := row.Scan(
 			&author.ID, &author.Name, &bio,
 			&author.CreatedAt, &author.UpdatedAt,
 		)
@@ -336,9 +996,33 @@ func (r *BookRepository) GetAllAuthors(limit, offset int) ([]domain.Author, erro
 		}
 
 		authors = append(authors, author)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return authors, nil
+	return newPage(authorKeyset, page, authors, keys, total), nil
+}
+
+// FindAuthorByName returns the oldest author with the name, ignoring case,
+// or nil when there is none.
+func (r *BookRepository) FindAuthorByName(name string) (*domain.Author, error) {
+	query := `
+		SELECT id, name, COALESCE(bio, ''), created_at, updated_at
+		FROM authors WHERE lower(name) = lower($1)
+		ORDER BY id LIMIT 1`
+
+	author := &domain.Author{}
+	err := r.db.QueryRow(query, name).Scan(&author.ID, &author.Name, &author.Bio, &author.CreatedAt, &author.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return author, nil
 }
 
 func (r *BookRepository) UpdateAuthor(author *domain.Author) error {
@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type CatalogImportRepository struct {
+	db *sql.DB
+}
+
+func NewCatalogImportRepository(db *sql.DB) *CatalogImportRepository {
+	return &CatalogImportRepository{db: db}
+}
+
+const catalogImportColumns = `
+		id, user_id, format, dry_run, mapping, source_name, file_name, status,
+		total, processed, created, updated, unchanged, failed, COALESCE(error, ''),
+		created_at, started_at, completed_at`
+
+func scanCatalogImport(row rowScanner) (*domain.CatalogImport, error) {
+	imp := &domain.CatalogImport{}
+	var userID sql.NullInt64
+	var mapping []byte
+	var startedAt, completedAt sql.NullTime
+
+	err := row.Scan(
+		&imp.ID, &userID, &imp.Format, &imp.DryRun, &mapping, &imp.SourceName, &imp.FileName, &imp.Status,
+		&imp.Total, &imp.Processed, &imp.Created, &imp.Updated, &imp.Unchanged, &imp.Failed, &imp.Error,
+		&imp.CreatedAt, &startedAt, &completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID.Valid {
+		imp.UserID = &userID.Int64
+	}
+	if len(mapping) > 0 {
+		if err := json.Unmarshal(mapping, &imp.Mapping); err != nil {
+			return nil, err
+		}
+	}
+	if startedAt.Valid {
+		imp.StartedAt = &startedAt.Time
+	}
+	if completedAt.Valid {
+		imp.CompletedAt = &completedAt.Time
+	}
+
+	return imp, nil
+}
+
+func (r *CatalogImportRepository) queryImports(query string, args ...interface{}) ([]domain.CatalogImport, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var imports []domain.CatalogImport
+	for rows.Next() {
+		imp, err := scanCatalogImport(rows)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, *imp)
+	}
+
+	return imports, rows.Err()
+}
+
+func (r *CatalogImportRepository) Create(imp *domain.CatalogImport) error {
+	var mapping sql.NullString
+	if len(imp.Mapping) > 0 {
+		encoded, err := json.Marshal(imp.Mapping)
+		if err != nil {
+			return err
+		}
+		mapping = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	query := `
+		INSERT INTO catalog_imports (user_id, format, dry_run, mapping, source_name, file_name, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(
+		query, imp.UserID, imp.Format, imp.DryRun, mapping, imp.SourceName, imp.FileName, imp.Status,
+	).Scan(&imp.ID, &imp.CreatedAt)
+}
+
+func (r *CatalogImportRepository) GetByID(id int64) (*domain.CatalogImport, error) {
+	query := `SELECT ` + catalogImportColumns + ` FROM catalog_imports WHERE id = $1`
+
+	imp, err := scanCatalogImport(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("import not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return imp, nil
+}
+
+// catalogImportKeyset orders imports newest first.
+var catalogImportKeyset = keyset{sort: "id", columns: []string{"id"}, descending: true}
+
+func (r *CatalogImportRepository) List(page domain.PageRequest) (*domain.Page[domain.CatalogImport], error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM catalog_imports`).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	seek, args, err := catalogImportKeyset.seek(page, nil)
+	if err != nil {
+		return nil, err
+	}
+	order, args := catalogImportKeyset.orderBy(page, args)
+
+	query := `SELECT ` + catalogImportColumns + catalogImportKeyset.keys() +
+		` FROM catalog_imports` + andWhere("", seek) + order
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
+	defer rows.Close()
+
+	imports := []domain.CatalogImport{}
+	var keys [][]string
+	for rows.Next() {
+		row := catalogImportKeyset.row(rows)
+		imp, err := scanCatalogImport(row)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, *imp)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newPage(catalogImportKeyset, page, imports, keys, total), nil
+}
+
+// GetUnfinished returns the jobs that were pending or running, for example
+// when the server stopped while running them.
+func (r *CatalogImportRepository) GetUnfinished() ([]domain.CatalogImport, error) {
+	return r.queryImports(`SELECT ` + catalogImportColumns + ` FROM catalog_imports WHERE status IN ('pending', 'running') ORDER BY id`)
+}
+
+// MarkRunning starts, or restarts, a job with the number of records in its
+// file. Counts and errors of an interrupted run are cleared, since the
+// records are processed again from the start.
+func (r *CatalogImportRepository) MarkRunning(id int64, total int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM catalog_import_errors WHERE import_id = $1`, id); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE catalog_imports
+		SET status = 'running', total = $2, processed = 0, created = 0, updated = 0,
+		    unchanged = 0, failed = 0, error = NULL, started_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	if _, err := tx.Exec(query, id, total); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateProgress saves the counts of a running job and the errors of the
+// records processed since the last update.
+func (r *CatalogImportRepository) UpdateProgress(imp *domain.CatalogImport, importErrors []domain.CatalogImportError) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(importErrors) > 0 {
+		values := make([]string, len(importErrors))
+		args := []interface{}{imp.ID}
+		for i, e := range importErrors {
+			values[i] = fmt.Sprintf("($1, $%d, $%d, $%d)", len(args)+1, len(args)+2, len(args)+3)
+			args = append(args, e.Record, sql.NullString{String: e.ISBN, Valid: e.ISBN != ""}, e.Message)
+		}
+
+		query := `INSERT INTO catalog_import_errors (import_id, record, isbn, message) VALUES ` + strings.Join(values, ", ")
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE catalog_imports
+		SET processed = $2, created = $3, updated = $4, unchanged = $5, failed = $6
+		WHERE id = $1`
+
+	_, err = tx.Exec(query, imp.ID, imp.Processed, imp.Created, imp.Updated, imp.Unchanged, imp.Failed)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *CatalogImportRepository) MarkCompleted(id int64) error {
+	_, err := r.db.Exec(`UPDATE catalog_imports SET status = 'completed', completed_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	return err
+}
+
+func (r *CatalogImportRepository) MarkFailed(id int64, reason string) error {
+	query := `
+		UPDATE catalog_imports
+		SET status = 'failed', error = $2, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	_, err := r.db.Exec(query, id, reason)
+	return err
+}
+
+// catalogImportErrorKeyset orders the errors of an import as in the file.
+var catalogImportErrorKeyset = keyset{sort: "id", columns: []string{"id"}}
+
+func (r *CatalogImportRepository) ListErrors(importID int64, page domain.PageRequest) (*domain.Page[domain.CatalogImportError], error) {
+	var total int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM catalog_import_errors WHERE import_id = $1`, importID).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	seek, args, err := catalogImportErrorKeyset.seek(page, []interface{}{importID})
+	if err != nil {
+		return nil, err
+	}
+	order, args := catalogImportErrorKeyset.orderBy(page, args)
+
+	query := `SELECT id, import_id, record, COALESCE(isbn, ''), message` + catalogImportErrorKeyset.keys() +
+		` FROM catalog_import_errors` + andWhere(" WHERE import_id = $1", seek) + order
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
+	defer rows.Close()
+
+	importErrors := []domain.CatalogImportError{}
+	var keys [][]string
+	for rows.Next() {
+		var e domain.CatalogImportError
+		row := catalogImportErrorKeyset.row(rows)
+		if err := row.Scan(&e.ID, &e.ImportID, &e.Record, &e.ISBN, &e.Message); err != nil {
+			return nil, err
+		}
+		importErrors = append(importErrors, e)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newPage(catalogImportErrorKeyset, page, importErrors, keys, total), nil
+}
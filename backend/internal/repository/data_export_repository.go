@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type DataExportRepository struct {
+	db *sql.DB
+}
+
+func NewDataExportRepository(db *sql.DB) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+const dataExportColumns = `
+		id, user_id, status, file_name, file_size, COALESCE(error, ''),
+		created_at, completed_at, expires_at`
+
+func scanDataExport(row rowScanner) (*domain.DataExport, error) {
+	export := &domain.DataExport{}
+	var completedAt, expiresAt sql.NullTime
+
+	err := row.Scan(
+		&export.ID, &export.UserID, &export.Status, &export.FileName, &export.FileSize, &export.Error,
+		&export.CreatedAt, &completedAt, &expiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+
+	return export, nil
+}
+
+func (r *DataExportRepository) queryExports(query string, args ...interface{}) ([]domain.DataExport, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []domain.DataExport
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, *export)
+	}
+
+	return exports, rows.Err()
+}
+
+func (r *DataExportRepository) Create(export *domain.DataExport) error {
+	query := `
+		INSERT INTO data_exports (user_id, status, file_name)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(query, export.UserID, export.Status, export.FileName).
+		Scan(&export.ID, &export.CreatedAt)
+}
+
+func (r *DataExportRepository) GetByID(userID, id int64) (*domain.DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE id = $1 AND user_id = $2`
+
+	export, err := scanDataExport(r.db.QueryRow(query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("export not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (r *DataExportRepository) GetByUser(userID int64) ([]domain.DataExport, error) {
+	return r.queryExports(`SELECT `+dataExportColumns+` FROM data_exports WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+}
+
+// GetUnfinished returns the jobs that were pending or running, for example
+// when the server stopped while building them.
+func (r *DataExportRepository) GetUnfinished() ([]domain.DataExport, error) {
+	return r.queryExports(`SELECT ` + dataExportColumns + ` FROM data_exports WHERE status IN ('pending', 'running') ORDER BY id`)
+}
+
+func (r *DataExportRepository) HasUnfinished(userID int64) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM data_exports WHERE user_id = $1 AND status IN ('pending', 'running'))`
+	err := r.db.QueryRow(query, userID).Scan(&exists)
+	return exists, err
+}
+
+func (r *DataExportRepository) MarkRunning(id int64) error {
+	_, err := r.db.Exec("UPDATE data_exports SET status = 'running' WHERE id = $1", id)
+	return err
+}
+
+func (r *DataExportRepository) MarkReady(id, fileSize int64, expiresAt time.Time) error {
+	query := `
+		UPDATE data_exports
+		SET status = 'ready', file_size = $2, completed_at = CURRENT_TIMESTAMP, expires_at = $3
+		WHERE id = $1`
+
+	_, err := r.db.Exec(query, id, fileSize, expiresAt)
+	return err
+}
+
+func (r *DataExportRepository) MarkFailed(id int64, reason string, expiresAt time.Time) error {
+	query := `
+		UPDATE data_exports
+		SET status = 'failed', error = $2, completed_at = CURRENT_TIMESTAMP, expires_at = $3
+		WHERE id = $1`
+
+	_, err := r.db.Exec(query, id, reason, expiresAt)
+	return err
+}
+
+// DeleteExpired removes the expired jobs and returns their file names.
+func (r *DataExportRepository) DeleteExpired() ([]string, error) {
+	rows, err := r.db.Query(`DELETE FROM data_exports WHERE expires_at < CURRENT_TIMESTAMP RETURNING file_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fileNames []string
+	for rows.Next() {
+		var fileName string
+		if err := rows.Scan(&fileName); err != nil {
+			return nil, err
+		}
+		fileNames = append(fileNames, fileName)
+	}
+
+	return fileNames, rows.Err()
+}
+
+// FilterKnown returns the file names that still belong to a job. Archives of
+// deleted users lose their row with the user.
+func (r *DataExportRepository) FilterKnown(fileNames []string) (map[string]bool, error) {
+	rows, err := r.db.Query(`SELECT file_name FROM data_exports WHERE file_name = ANY($1)`, pq.Array(fileNames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	known := make(map[string]bool)
+	for rows.Next() {
+		var fileName string
+		if err := rows.Scan(&fileName); err != nil {
+			return nil, err
+		}
+		known[fileName] = true
+	}
+
+	return known, rows.Err()
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type EditionRepository struct {
+	db *sql.DB
+}
+
+func NewEditionRepository(db *sql.DB) *EditionRepository {
+	return &EditionRepository{db: db}
+}
+
+// editionColumns are read by scanEdition.
+const editionColumns = `
+		e.id, e.book_id, e.isbn, e.publisher, e.format, e.language,
+		e.page_count, e.published_at, e.cover_url, e.legacy_isbn, e.created_at, e.updated_at`
+
+func scanEdition(row rowScanner) (*domain.Edition, error) {
+	edition := &domain.Edition{}
+	var isbn, language, coverURL, legacyISBN sql.NullString
+	var pageCount sql.NullInt64
+	var publishedAt sql.NullTime
+
+	err := row.Scan(
+		&edition.ID, &edition.BookID, &isbn, &edition.Publisher, &edition.Format, &language,
+		&pageCount, &publishedAt, &coverURL, &legacyISBN, &edition.CreatedAt, &edition.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	edition.ISBN = isbn.String
+	edition.Language = language.String
+	edition.PageCount = int(pageCount.Int64)
+	if publishedAt.Valid {
+		edition.PublishedAt = &publishedAt.Time
+	}
+	edition.CoverURL = coverURL.String
+	edition.LegacyISBN = legacyISBN.String
+	return edition, nil
+}
+
+// editionArgs are the editable columns of an edition, in the order of
+// Create and Update.
+func editionArgs(edition *domain.Edition) []interface{} {
+	var publishedAt sql.NullTime
+	if edition.PublishedAt != nil {
+		publishedAt = sql.NullTime{Time: *edition.PublishedAt, Valid: true}
+	}
+
+	return []interface{}{
+		sql.NullString{String: edition.ISBN, Valid: edition.ISBN != ""},
+		edition.Publisher,
+		edition.Format,
+		sql.NullString{String: edition.Language, Valid: edition.Language != ""},
+		sql.NullInt64{Int64: int64(edition.PageCount), Valid: edition.PageCount > 0},
+		publishedAt,
+		sql.NullString{String: edition.CoverURL, Valid: edition.CoverURL != ""},
+	}
+}
+
+func (r *EditionRepository) Create(edition *domain.Edition) error {
+	query := `
+		INSERT INTO editions (isbn, publisher, format, language, page_count, published_at, cover_url, book_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(query, append(editionArgs(edition), edition.BookID)...).Scan(
+		&edition.ID, &edition.CreatedAt, &edition.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("an edition with ISBN %s already exists", edition.ISBN)
+	}
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("book not found")
+	}
+	return err
+}
+
+func (r *EditionRepository) GetByID(id int64) (*domain.Edition, error) {
+	query := `SELECT ` + editionColumns + ` FROM editions e WHERE e.id = $1`
+
+	edition, err := scanEdition(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("edition not found")
+	}
+	return edition, err
+}
+
+// FindByISBN returns the edition with the ISBN-13, or nil when there is
+// none.
+func (r *EditionRepository) FindByISBN(isbn string) (*domain.Edition, error) {
+	query := `SELECT ` + editionColumns + ` FROM editions e WHERE e.isbn = $1`
+
+	edition, err := scanEdition(r.db.QueryRow(query, isbn))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return edition, err
+}
+
+// Update saves the edition's fields. Giving it an ISBN clears its legacy
+// ISBN.
+func (r *EditionRepository) Update(edition *domain.Edition) error {
+	query := `
+		UPDATE editions
+		SET isbn = $1, publisher = $2, format = $3, language = $4, page_count = $5,
+		    published_at = $6, cover_url = $7,
+		    legacy_isbn = CASE WHEN $1::text IS NULL THEN legacy_isbn END
+		WHERE id = $8
+		RETURNING COALESCE(legacy_isbn, ''), updated_at`
+
+	err := r.db.QueryRow(query, append(editionArgs(edition), edition.ID)...).Scan(&edition.LegacyISBN, &edition.UpdatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("an edition with ISBN %s already exists", edition.ISBN)
+	}
+	return err
+}
+
+// legacyISBNKeyset orders editions by ID.
+var legacyISBNKeyset = keyset{sort: "id", columns: []string{"e.id"}}
+
+// ListLegacyISBN lists the editions whose ISBN was cleared because it was
+// invalid or shared with an older edition.
+func (r *EditionRepository) ListLegacyISBN(page domain.PageRequest) (*domain.Page[domain.Edition], error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM editions WHERE legacy_isbn IS NOT NULL`).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	seek, args, err := legacyISBNKeyset.seek(page, nil)
+	if err != nil {
+		return nil, err
+	}
+	order, args := legacyISBNKeyset.orderBy(page, args)
+
+	query := `SELECT ` + editionColumns + legacyISBNKeyset.keys() +
+		` FROM editions e` + andWhere(" WHERE e.legacy_isbn IS NOT NULL", seek) + order
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
+	defer rows.Close()
+
+	editions := []domain.Edition{}
+	var keys [][]string
+	for rows.Next() {
+		row := legacyISBNKeyset.row(rows)
+		edition, err := scanEdition(row)
+		if err != nil {
+			return nil, err
+		}
+		editions = append(editions, *edition)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newPage(legacyISBNKeyset, page, editions, keys, total), nil
+}
+
+func (r *EditionRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM editions WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// Identity methods
+func (r *IdentityRepository) Create(identity *domain.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(
+		query,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		sql.NullString{String: identity.Email, Valid: identity.Email != ""},
+	).Scan(&identity.ID, &identity.CreatedAt)
+}
+
+func (r *IdentityRepository) GetBySubject(provider, subject string) (*domain.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, COALESCE(email, ''), created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2`
+
+	identity := &domain.UserIdentity{}
+	var lastLoginAt sql.NullTime
+	err := r.db.QueryRow(query, provider, subject).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+		&identity.Email, &identity.CreatedAt, &lastLoginAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("identity not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if lastLoginAt.Valid {
+		identity.LastLoginAt = &lastLoginAt.Time
+	}
+
+	return identity, nil
+}
+
+func (r *IdentityRepository) GetByUser(userID int64) ([]domain.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, COALESCE(email, ''), created_at, last_login_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []domain.UserIdentity
+	for rows.Next() {
+		var identity domain.UserIdentity
+		var lastLoginAt sql.NullTime
+		err := rows.Scan(
+			&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+			&identity.Email, &identity.CreatedAt, &lastLoginAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if lastLoginAt.Valid {
+			identity.LastLoginAt = &lastLoginAt.Time
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+func (r *IdentityRepository) Touch(id int64) error {
+	_, err := r.db.Exec("UPDATE user_identities SET last_login_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+	return err
+}
+
+// State methods
+func (r *IdentityRepository) CreateState(state *domain.OIDCState) error {
+	query := `
+		INSERT INTO oidc_states (state, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.Exec(query, state.State, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	return err
+}
+
+// TakeState deletes and returns the state, so that each authorization
+// response can only be redeemed once.
+func (r *IdentityRepository) TakeState(state, provider string) (*domain.OIDCState, error) {
+	query := `
+		DELETE FROM oidc_states
+		WHERE state = $1 AND provider = $2
+		RETURNING state, provider, nonce, code_verifier, expires_at`
+
+	s := &domain.OIDCState{}
+	err := r.db.QueryRow(query, state, provider).Scan(
+		&s.State, &s.Provider, &s.Nonce, &s.CodeVerifier, &s.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("state not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Link request methods
+func (r *IdentityRepository) CreateLinkRequest(link *domain.OIDCLinkRequest) error {
+	query := `
+		INSERT INTO oidc_link_requests (id, user_id, provider, subject, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.Exec(
+		query,
+		link.ID,
+		link.UserID,
+		link.Provider,
+		link.Subject,
+		sql.NullString{String: link.Email, Valid: link.Email != ""},
+		link.ExpiresAt,
+	)
+	return err
+}
+
+func (r *IdentityRepository) GetLinkRequest(id string) (*domain.OIDCLinkRequest, error) {
+	query := `
+		SELECT id, user_id, provider, subject, COALESCE(email, ''), expires_at
+		FROM oidc_link_requests
+		WHERE id = $1`
+
+	link := &domain.OIDCLinkRequest{}
+	err := r.db.QueryRow(query, id).Scan(
+		&link.ID, &link.UserID, &link.Provider, &link.Subject, &link.Email, &link.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("link request not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// DeleteLinkRequest returns false if the request was already used.
+func (r *IdentityRepository) DeleteLinkRequest(id string) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM oidc_link_requests WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
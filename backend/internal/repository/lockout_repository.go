@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type LockoutRepository struct {
+	db *sql.DB
+}
+
+func NewLockoutRepository(db *sql.DB) *LockoutRepository {
+	return &LockoutRepository{db: db}
+}
+
+// GetLockedUntil returns when the lockout of the key ends, or nil if the key
+// is not locked.
+func (r *LockoutRepository) GetLockedUntil(key string) (*time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRow(
+		"SELECT locked_until FROM login_lockouts WHERE key = $1 AND locked_until > CURRENT_TIMESTAMP",
+		key,
+	).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &lockedUntil.Time, nil
+}
+
+// RecordFailure increments the failure counter of the key and returns the
+// new count. Counters whose last failure is older than window start over.
+func (r *LockoutRepository) RecordFailure(key string, window time.Duration) (int, error) {
+	query := `
+		INSERT INTO login_lockouts (key, failures, last_failure_at)
+		VALUES ($1, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_lockouts.last_failure_at < CURRENT_TIMESTAMP - make_interval(secs => $2) THEN 1
+				ELSE login_lockouts.failures + 1
+			END,
+			last_failure_at = CURRENT_TIMESTAMP
+		RETURNING failures`
+
+	var failures int
+	err := r.db.QueryRow(query, key, window.Seconds()).Scan(&failures)
+	return failures, err
+}
+
+func (r *LockoutRepository) Lock(key string, until time.Time) error {
+	_, err := r.db.Exec("UPDATE login_lockouts SET locked_until = $1 WHERE key = $2", until, key)
+	return err
+}
+
+func (r *LockoutRepository) Clear(key string) error {
+	_, err := r.db.Exec("DELETE FROM login_lockouts WHERE key = $1", key)
+	return err
+}
+
+func (r *LockoutRepository) RecordEvent(event *domain.LoginEvent) error {
+	query := `
+		INSERT INTO login_events (user_id, ip_address, event)
+		VALUES ($1, $2, $3)`
+
+	_, err := r.db.Exec(
+		query,
+		sql.NullInt64{Int64: event.UserID, Valid: event.UserID != 0},
+		event.IPAddress,
+		event.Event,
+	)
+	return err
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/razvan/library-app/internal/domain"
+)
+
+// keyset orders a list for cursor pagination. Its columns are SQL
+// expressions that are never NULL and are sorted in the same direction, the
+// last of them unique, so that the values of one row say exactly where a
+// page starts. Seeking by them instead of using OFFSET keeps deep pages as
+// fast as the first one.
+type keyset struct {
+	sort       string
+	columns    []string
+	descending bool
+}
+
+// name identifies the order in cursors, so that a cursor is only used with
+// the order it came from.
+func (k keyset) name() string {
+	if k.descending {
+		return k.sort + ":desc"
+	}
+	return k.sort + ":asc"
+}
+
+func (k keyset) backwards(page domain.PageRequest) bool {
+	return page.Cursor != nil && page.Cursor.Before
+}
+
+// keys selects the key values as text, to be read with keyRow after the
+// regular columns.
+func (k keyset) keys() string {
+	var keys strings.Builder
+	for _, column := range k.columns {
+		keys.WriteString(", (" + column + ")::text")
+	}
+	return keys.String()
+}
+
+// seek returns the condition that skips to the page's cursor, numbering its
+// arguments after args, or an empty condition for the first page.
+func (k keyset) seek(page domain.PageRequest, args []interface{}) (string, []interface{}, error) {
+	if page.Cursor == nil {
+		return "", args, nil
+	}
+	if page.Cursor.Sort != k.name() || len(page.Cursor.Keys) != len(k.columns) {
+		return "", nil, domain.ErrInvalidCursor
+	}
+
+	placeholders := make([]string, len(page.Cursor.Keys))
+	for i, key := range page.Cursor.Keys {
+		args = append(args, key)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+
+	operator := ">"
+	if k.descending != page.Cursor.Before {
+		operator = "<"
+	}
+
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(k.columns, ", "), operator, strings.Join(placeholders, ", ")), args, nil
+}
+
+// cursorError reports a failed query of a page. The cursor's keys are sent
+// as text and cast to the types of their columns, so a tampered cursor fails
+// with a data exception rather than matching nothing.
+func cursorError(page domain.PageRequest, err error) error {
+	var pqErr *pq.Error
+	if page.Cursor != nil && errors.As(err, &pqErr) && pqErr.Code.Class() == "22" {
+		return domain.ErrInvalidCursor
+	}
+	return err
+}
+
+// orderBy returns the ORDER BY and LIMIT clauses. One row more than the page
+// size is read to tell whether another page follows, and backward pages are
+// read in reverse, which newPage undoes.
+func (k keyset) orderBy(page domain.PageRequest, args []interface{}) (string, []interface{}) {
+	direction := " ASC"
+	if k.descending != k.backwards(page) {
+		direction = " DESC"
+	}
+
+	order := make([]string, len(k.columns))
+	for i, column := range k.columns {
+		order[i] = column + direction
+	}
+
+	args = append(args, page.Size+1)
+	return fmt.Sprintf(" ORDER BY %s LIMIT $%d", strings.Join(order, ", "), len(args)), args
+}
+
+// keyRow reads the key columns selected by keyset.keys after the columns
+// of the wrapped row, so that the usual scan functions can read the rest.
+type keyRow struct {
+	row  rowScanner
+	keys []string
+}
+
+func (k keyset) row(row rowScanner) *keyRow {
+	return &keyRow{row: row, keys: make([]string, len(k.columns))}
+}
+
+func (r *keyRow) Scan(dest ...interface{}) error {
+	for i := range r.keys {
+		dest = append(dest, &r.keys[i])
+	}
+	return r.row.Scan(dest...)
+}
+
+// newPage turns the rows read with orderBy, and the key values of each,
+// into a page with cursors to the pages around it.
+func newPage[T any](k keyset, page domain.PageRequest, items []T, keys [][]string, total int) *domain.Page[T] {
+	backwards := k.backwards(page)
+	more := len(items) > page.Size
+	if more {
+		items, keys = items[:page.Size], keys[:page.Size]
+	}
+	if backwards {
+		slices.Reverse(items)
+		slices.Reverse(keys)
+	}
+
+	result := &domain.Page[T]{Items: items, Total: total}
+	if len(items) == 0 {
+		return result
+	}
+
+	// Paging backwards always leaves the cursor's own item ahead, and paging
+	// forwards from a cursor always leaves one behind.
+	if more || backwards {
+		result.NextCursor = (&domain.Cursor{Sort: k.name(), Keys: keys[len(keys)-1]}).Encode()
+	}
+	if (more && backwards) || (!backwards && page.Cursor != nil) {
+		result.PrevCursor = (&domain.Cursor{Sort: k.name(), Keys: keys[0], Before: true}).Encode()
+	}
+
+	return result
+}
+
+// andWhere adds a condition to a WHERE clause, which may be empty.
+func andWhere(where, condition string) string {
+	switch {
+	case condition == "":
+		return where
+	case where == "":
+		return " WHERE " + condition
+	default:
+		return where + " AND " + condition
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+func (r *PasswordResetRepository) Create(userID int64, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)`
+
+	_, err := r.db.Exec(query, userID, tokenHash, expiresAt)
+	return err
+}
+
+// Consume marks an unused, unexpired token as used and returns the user it
+// belongs to. Each token can only be consumed once.
+func (r *PasswordResetRepository) Consume(tokenHash string) (int64, error) {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id`
+
+	var userID int64
+	err := r.db.QueryRow(query, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("reset token not found")
+	}
+
+	return userID, err
+}
+
+// GetUserID returns the user an unused, unexpired token belongs to without
+// consuming it.
+func (r *PasswordResetRepository) GetUserID(tokenHash string) (int64, error) {
+	query := `
+		SELECT user_id FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+
+	var userID int64
+	err := r.db.QueryRow(query, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("reset token not found")
+	}
+
+	return userID, err
+}
+
+func (r *PasswordResetRepository) DeleteForUser(userID int64) error {
+	_, err := r.db.Exec("DELETE FROM password_reset_tokens WHERE user_id = $1", userID)
+	return err
+}
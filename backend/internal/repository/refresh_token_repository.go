@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type RefreshTokenRepository struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func (r *RefreshTokenRepository) Create(token *domain.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, family_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at`
+
+	return r.db.QueryRow(
+		query,
+		token.ID,
+		token.UserID,
+		token.FamilyID,
+		token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+}
+
+func (r *RefreshTokenRepository) GetByID(id string) (*domain.RefreshToken, error) {
+	token := &domain.RefreshToken{}
+	query := `
+		SELECT id, user_id, family_id, expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens WHERE id = $1`
+
+	var usedAt, revokedAt sql.NullTime
+	err := r.db.QueryRow(query, id).Scan(
+		&token.ID, &token.UserID, &token.FamilyID, &token.ExpiresAt,
+		&usedAt, &revokedAt, &token.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("refresh token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return token, nil
+}
+
+// MarkUsed atomically consumes a refresh token. It returns false when the
+// token had already been used or revoked.
+func (r *RefreshTokenRepository) MarkUsed(id string) (bool, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE family_id = $1 AND revoked_at IS NULL`
+
+	_, err := r.db.Exec(query, familyID)
+	return err
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(userID int64) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL`
+
+	_, err := r.db.Exec(query, userID)
+	return err
+}
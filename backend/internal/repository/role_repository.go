@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type RoleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+const roleColumns = `
+		r.id, r.name, r.description,
+		ARRAY(SELECT rp.permission FROM role_permissions rp
+		      WHERE rp.role_id = r.id ORDER BY rp.permission),
+		r.created_at`
+
+func scanRole(row rowScanner) (*domain.Role, error) {
+	role := &domain.Role{}
+	err := row.Scan(&role.ID, &role.Name, &role.Description, pq.Array(&role.Permissions), &role.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (r *RoleRepository) GetAll() ([]domain.Role, error) {
+	rows, err := r.db.Query(`SELECT ` + roleColumns + ` FROM roles r ORDER BY r.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []domain.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *RoleRepository) GetByName(name string) (*domain.Role, error) {
+	role, err := scanRole(r.db.QueryRow(`SELECT `+roleColumns+` FROM roles r WHERE r.name = $1`, name))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("role not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (r *RoleRepository) AssignToUser(userID, roleID, grantedBy int64) error {
+	query := `
+		INSERT INTO user_roles (user_id, role_id, granted_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, role_id) DO NOTHING`
+
+	_, err := r.db.Exec(query, userID, roleID, grantedBy)
+	return err
+}
+
+// RevokeFromUser returns false if the user did not have the role. It fails
+// with domain.ErrLastAdmin rather than revoke the last admin's role.
+func (r *RoleRepository) RevokeFromUser(userID, roleID int64) (bool, error) {
+	var revoked bool
+	err := keepingAdmin(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2", userID, roleID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := result.RowsAffected()
+		revoked = rows == 1
+		return err
+	})
+
+	return revoked, err
+}
+
+// RevokeAllFromUser removes every role of the user, unless they are the last
+// admin.
+func (r *RoleRepository) RevokeAllFromUser(userID int64) error {
+	return keepingAdmin(r.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM user_roles WHERE user_id = $1", userID)
+		return err
+	})
+}
+
+// adminLock is the advisory lock held by changes that can take away an
+// admin's access, so that two of them never both count on the other admin.
+const adminLock = 0x61646d6e
+
+// activeAdminsQuery counts the admins who can still log in: not suspended
+// and not about to be deleted.
+const activeAdminsQuery = `
+	SELECT COUNT(*) FROM user_roles ur
+	JOIN roles r ON r.id = ur.role_id
+	JOIN users u ON u.id = ur.user_id
+	WHERE r.name = $1 AND u.suspended_at IS NULL AND u.delete_after IS NULL`
+
+// keepingAdmin makes a change that may take away an admin's access in a
+// transaction holding adminLock. It fails with domain.ErrLastAdmin, leaving
+// nothing changed, if the change would leave no active admin.
+func keepingAdmin(db *sql.DB, change func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, adminLock); err != nil {
+		return err
+	}
+
+	var before, after int
+	if err := tx.QueryRow(activeAdminsQuery, domain.RoleAdmin).Scan(&before); err != nil {
+		return err
+	}
+	if err := change(tx); err != nil {
+		return err
+	}
+	if err := tx.QueryRow(activeAdminsQuery, domain.RoleAdmin).Scan(&after); err != nil {
+		return err
+	}
+	if before > 0 && after == 0 {
+		return domain.ErrLastAdmin
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type SeriesRepository struct {
+	db *sql.DB
+}
+
+func NewSeriesRepository(db *sql.DB) *SeriesRepository {
+	return &SeriesRepository{db: db}
+}
+
+// seriesColumns are read by scanSeries.
+const seriesColumns = `
+		s.id, s.name, s.description,
+		(SELECT COUNT(*) FROM series_books WHERE series_id = s.id),
+		s.created_at, s.updated_at`
+
+func scanSeries(row rowScanner) (*domain.Series, error) {
+	series := &domain.Series{}
+	err := row.Scan(
+		&series.ID, &series.Name, &series.Description, &series.VolumeCount,
+		&series.CreatedAt, &series.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func (r *SeriesRepository) Create(series *domain.Series) error {
+	query := `
+		INSERT INTO series (name, description)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query, series.Name, series.Description).Scan(
+		&series.ID, &series.CreatedAt, &series.UpdatedAt,
+	)
+}
+
+func (r *SeriesRepository) GetByID(id int64) (*domain.Series, error) {
+	query := `SELECT ` + seriesColumns + ` FROM series s WHERE s.id = $1`
+
+	series, err := scanSeries(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("series not found")
+	}
+	return series, err
+}
+
+// seriesKeyset orders series by name, regardless of case.
+var seriesKeyset = keyset{sort: "name", columns: []string{"lower(s.name)", "s.id"}}
+
+func (r *SeriesRepository) List(page domain.PageRequest) (*domain.Page[domain.Series], error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM series`).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	seek, args, err := seriesKeyset.seek(page, nil)
+	if err != nil {
+		return nil, err
+	}
+	order, args := seriesKeyset.orderBy(page, args)
+
+	query := `SELECT ` + seriesColumns + seriesKeyset.keys() + ` FROM series s` + andWhere("", seek) + order
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
+	defer rows.Close()
+
+	list := []domain.Series{}
+	var keys [][]string
+	for rows.Next() {
+		row := seriesKeyset.row(rows)
+		series, err := scanSeries(row)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *series)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newPage(seriesKeyset, page, list, keys, total), nil
+}
+
+func (r *SeriesRepository) Update(series *domain.Series) error {
+	query := `
+		UPDATE series SET name = $1, description = $2
+		WHERE id = $3
+		RETURNING updated_at`
+
+	return r.db.QueryRow(query, series.Name, series.Description, series.ID).Scan(&series.UpdatedAt)
+}
+
+// Delete deletes a series. Its books stay in the catalog.
+func (r *SeriesRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM series WHERE id = $1`, id)
+	return err
+}
+
+// GetVolume returns the position of the book in the series, or 0 when it is
+// not a volume of it.
+func (r *SeriesRepository) GetVolume(seriesID, bookID int64) (float64, error) {
+	var position float64
+	err := r.db.QueryRow(
+		`SELECT position FROM series_books WHERE series_id = $1 AND book_id = $2`,
+		seriesID, bookID,
+	).Scan(&position)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return position, err
+}
+
+// SetVolume puts the book at the position in the series, moving it out of
+// any other series.
+func (r *SeriesRepository) SetVolume(seriesID, bookID int64, position float64) error {
+	query := `
+		INSERT INTO series_books (series_id, book_id, position)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (book_id) DO UPDATE SET series_id = $1, position = $3`
+
+	_, err := r.db.Exec(query, seriesID, bookID, position)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("another book is already at position %g of this series", position)
+	}
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("book not found")
+	}
+	return err
+}
+
+func (r *SeriesRepository) RemoveVolume(seriesID, bookID int64) error {
+	_, err := r.db.Exec(`DELETE FROM series_books WHERE series_id = $1 AND book_id = $2`, seriesID, bookID)
+	return err
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(session *domain.Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, last_seen_at`
+
+	return r.db.QueryRow(
+		query,
+		session.ID,
+		session.UserID,
+		session.UserAgent,
+		session.IPAddress,
+	).Scan(&session.CreatedAt, &session.LastSeenAt)
+}
+
+func (r *SessionRepository) GetByID(id string) (*domain.Session, error) {
+	session := &domain.Session{}
+	query := `
+		SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, revoked_at
+		FROM sessions WHERE id = $1`
+
+	var userAgent, ipAddress sql.NullString
+	var revokedAt sql.NullTime
+	err := r.db.QueryRow(query, id).Scan(
+		&session.ID, &session.UserID, &userAgent, &ipAddress,
+		&session.CreatedAt, &session.LastSeenAt, &revokedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session.UserAgent = userAgent.String
+	session.IPAddress = ipAddress.String
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+
+	return session, nil
+}
+
+func (r *SessionRepository) GetActiveByUser(userID int64) ([]domain.Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []domain.Session
+	for rows.Next() {
+		var session domain.Session
+		var userAgent, ipAddress sql.NullString
+
+		err := rows.Scan(
+			&session.ID, &session.UserID, &userAgent, &ipAddress,
+			&session.CreatedAt, &session.LastSeenAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		session.UserAgent = userAgent.String
+		session.IPAddress = ipAddress.String
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// GetAllByUser returns every session of the user, including revoked ones.
+func (r *SessionRepository) GetAllByUser(userID int64) ([]domain.Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []domain.Session
+	for rows.Next() {
+		var session domain.Session
+		var userAgent, ipAddress sql.NullString
+		var revokedAt sql.NullTime
+
+		err := rows.Scan(
+			&session.ID, &session.UserID, &userAgent, &ipAddress,
+			&session.CreatedAt, &session.LastSeenAt, &revokedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		session.UserAgent = userAgent.String
+		session.IPAddress = ipAddress.String
+		if revokedAt.Valid {
+			session.RevokedAt = &revokedAt.Time
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *SessionRepository) Touch(id string) error {
+	query := `UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP WHERE id = $1`
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+func (r *SessionRepository) Revoke(id string) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND revoked_at IS NULL`
+
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+func (r *SessionRepository) RevokeAllForUser(userID int64) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL`
+
+	_, err := r.db.Exec(query, userID)
+	return err
+}
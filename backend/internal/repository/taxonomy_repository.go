@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+// TaxonomyRepository stores the genre tree and the tags that classify books.
+type TaxonomyRepository struct {
+	db *sql.DB
+}
+
+func NewTaxonomyRepository(db *sql.DB) *TaxonomyRepository {
+	return &TaxonomyRepository{db: db}
+}
+
+// genreSubtree selects the IDs of a genre and of every genre below it, for
+// use in an IN clause. Its only placeholder is the genre ID, as $%d. UNION
+// rather than UNION ALL stops the recursion should the tree ever loop.
+const genreSubtree = `
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM genres WHERE id = $%d
+		UNION
+		SELECT g.id FROM genres g JOIN subtree s ON g.parent_id = s.id
+	)
+	SELECT id FROM subtree`
+
+// genreColumns are read by scanGenre.
+const genreColumns = `g.id, g.name, g.parent_id, g.created_at, g.updated_at`
+
+func scanGenre(row rowScanner) (*domain.Genre, error) {
+	genre := &domain.Genre{}
+	var parentID sql.NullInt64
+
+	err := row.Scan(&genre.ID, &genre.Name, &parentID, &genre.CreatedAt, &genre.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		genre.ParentID = &parentID.Int64
+	}
+	return genre, nil
+}
+
+func queryGenres(db *sql.DB, query string, args ...interface{}) ([]domain.Genre, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []domain.Genre{}
+	for rows.Next() {
+		genre, err := scanGenre(rows)
+		if err != nil {
+			return nil, err
+		}
+		genres = append(genres, *genre)
+	}
+
+	return genres, rows.Err()
+}
+
+// tagColumns are read by scanTag.
+const tagColumns = `t.id, t.name, t.created_at`
+
+func scanTag(row rowScanner) (*domain.Tag, error) {
+	tag := &domain.Tag{}
+	if err := row.Scan(&tag.ID, &tag.Name, &tag.CreatedAt); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// Genre methods
+func (r *TaxonomyRepository) CreateGenre(genre *domain.Genre) error {
+	query := `
+		INSERT INTO genres (name, parent_id)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(query, genre.Name, genre.ParentID).Scan(
+		&genre.ID, &genre.CreatedAt, &genre.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("a genre with this name already exists under the same parent")
+	}
+	return err
+}
+
+func (r *TaxonomyRepository) GetGenreByID(id int64) (*domain.Genre, error) {
+	query := `SELECT ` + genreColumns + ` FROM genres g WHERE g.id = $1`
+
+	genre, err := scanGenre(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("genre not found")
+	}
+	return genre, err
+}
+
+// GetAllGenres returns the whole tree as a flat list, ordered by name. Each
+// genre points at its parent.
+func (r *TaxonomyRepository) GetAllGenres() ([]domain.Genre, error) {
+	return queryGenres(r.db, `SELECT `+genreColumns+` FROM genres g ORDER BY g.name, g.id`)
+}
+
+// IsGenreWithin reports whether the genre is the ancestor genre or below it.
+func (r *TaxonomyRepository) IsGenreWithin(genreID, ancestorID int64) (bool, error) {
+	var within bool
+	query := fmt.Sprintf(`SELECT $2 IN (`+genreSubtree+`)`, 1)
+	err := r.db.QueryRow(query, ancestorID, genreID).Scan(&within)
+	return within, err
+}
+
+func (r *TaxonomyRepository) UpdateGenre(genre *domain.Genre) error {
+	query := `
+		UPDATE genres SET name = $1, parent_id = $2
+		WHERE id = $3
+		RETURNING updated_at`
+
+	err := r.db.QueryRow(query, genre.Name, genre.ParentID, genre.ID).Scan(&genre.UpdatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("a genre with this name already exists under the same parent")
+	}
+	return err
+}
+
+// DeleteGenre deletes a genre without subgenres. Its books lose the genre.
+func (r *TaxonomyRepository) DeleteGenre(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM genres WHERE id = $1`, id)
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("genre has subgenres, move or delete them first")
+	}
+	return err
+}
+
+// Tag methods
+func (r *TaxonomyRepository) CreateTag(tag *domain.Tag) error {
+	query := `INSERT INTO tags (name) VALUES ($1) RETURNING id, created_at`
+
+	err := r.db.QueryRow(query, tag.Name).Scan(&tag.ID, &tag.CreatedAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("tag already exists")
+	}
+	return err
+}
+
+func (r *TaxonomyRepository) GetTagByID(id int64) (*domain.Tag, error) {
+	query := `SELECT ` + tagColumns + ` FROM tags t WHERE t.id = $1`
+
+	tag, err := scanTag(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("tag not found")
+	}
+	return tag, err
+}
+
+// tagKeyset orders tags by name, regardless of case.
+var tagKeyset = keyset{sort: "name", columns: []string{"lower(t.name)", "t.id"}}
+
+// ListTags returns a page of tags, only those starting with prefix when it
+// is not empty.
+func (r *TaxonomyRepository) ListTags(prefix string, page domain.PageRequest) (*domain.Page[domain.Tag], error) {
+	var where string
+	var args []interface{}
+	if prefix != "" {
+		args = append(args, escapeLike(prefix)+"%")
+		where = " WHERE t.name ILIKE $1"
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM tags t`+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	seek, args, err := tagKeyset.seek(page, args)
+	if err != nil {
+		return nil, err
+	}
+	order, args := tagKeyset.orderBy(page, args)
+
+	query := `SELECT ` + tagColumns + tagKeyset.keys() + ` FROM tags t` + andWhere(where, seek) + order
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
+	defer rows.Close()
+
+	tags := []domain.Tag{}
+	var keys [][]string
+	for rows.Next() {
+		row := tagKeyset.row(rows)
+		tag, err := scanTag(row)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, *tag)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newPage(tagKeyset, page, tags, keys, total), nil
+}
+
+func (r *TaxonomyRepository) UpdateTag(tag *domain.Tag) error {
+	_, err := r.db.Exec(`UPDATE tags SET name = $1 WHERE id = $2`, tag.Name, tag.ID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("tag already exists")
+	}
+	return err
+}
+
+// DeleteTag deletes a tag and removes it from its books.
+func (r *TaxonomyRepository) DeleteTag(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM tags WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type TwoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// Challenge methods
+func (r *TwoFactorRepository) CreateChallenge(challenge *domain.TwoFactorChallenge) error {
+	query := `
+		INSERT INTO two_factor_challenges (id, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING attempts, created_at`
+
+	return r.db.QueryRow(query, challenge.ID, challenge.UserID, challenge.ExpiresAt).Scan(
+		&challenge.Attempts, &challenge.CreatedAt,
+	)
+}
+
+func (r *TwoFactorRepository) GetChallenge(id string) (*domain.TwoFactorChallenge, error) {
+	challenge := &domain.TwoFactorChallenge{}
+	query := `
+		SELECT id, user_id, attempts, expires_at, consumed_at, created_at
+		FROM two_factor_challenges WHERE id = $1`
+
+	var consumedAt sql.NullTime
+	err := r.db.QueryRow(query, id).Scan(
+		&challenge.ID, &challenge.UserID, &challenge.Attempts,
+		&challenge.ExpiresAt, &consumedAt, &challenge.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("challenge not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if consumedAt.Valid {
+		challenge.ConsumedAt = &consumedAt.Time
+	}
+
+	return challenge, nil
+}
+
+// ReserveAttempt counts an attempt at an open challenge before its second
+// factor is checked and returns the new count. Counting and checking the
+// limit in one statement keeps concurrent attempts from going over it.
+func (r *TwoFactorRepository) ReserveAttempt(id string, maxAttempts int) (int, error) {
+	query := `
+		UPDATE two_factor_challenges
+		SET attempts = attempts + 1
+		WHERE id = $1 AND consumed_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND attempts < $2
+		RETURNING attempts`
+
+	var attempts int
+	err := r.db.QueryRow(query, id, maxAttempts).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("challenge not found")
+	}
+	return attempts, err
+}
+
+// ConsumeChallenge closes a challenge. It returns false if the challenge was
+// already consumed.
+func (r *TwoFactorRepository) ConsumeChallenge(id string) (bool, error) {
+	query := `
+		UPDATE two_factor_challenges
+		SET consumed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND consumed_at IS NULL`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Recovery code methods
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM two_factor_recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err = tx.Exec(
+			"INSERT INTO two_factor_recovery_codes (user_id, code_hash) VALUES ($1, $2)",
+			userID, hash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseRecoveryCode marks a matching unused code as used. It returns false if
+// no such code exists.
+func (r *TwoFactorRepository) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	query := `
+		UPDATE two_factor_recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := r.db.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *TwoFactorRepository) CountUnusedRecoveryCodes(userID int64) (int, error) {
+	query := "SELECT COUNT(*) FROM two_factor_recovery_codes WHERE user_id = $1 AND used_at IS NULL"
+	var count int
+	err := r.db.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *TwoFactorRepository) DeleteRecoveryCodes(userID int64) error {
+	_, err := r.db.Exec("DELETE FROM two_factor_recovery_codes WHERE user_id = $1", userID)
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/lib/pq"
 	"github.com/razvan/library-app/internal/domain"
 )
 
@@ -16,14 +17,16 @@ func NewUserBookRepository(db *sql.DB) *UserBookRepository {
 }
 
 // UserBook methods (reading lists)
-func (r *UserBookRepository) AddToReadingList(userID, bookID int64, status domain.ReadingStatus) error {
+// AddToReadingList sets the status of the book on the user's reading list.
+// A nil editionID keeps the edition an entry already has.
+func (r *UserBookRepository) AddToReadingList(userID, bookID int64, status domain.ReadingStatus, editionID *int64) error {
 	query := `
-		INSERT INTO user_books (user_id, book_id, status)
-		VALUES ($1, $2, $3)
+		INSERT INTO user_books (user_id, book_id, status, edition_id)
+		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (user_id, book_id)
-		DO UPDATE SET status = $3, updated_at = CURRENT_TIMESTAMP`
+		DO UPDATE SET status = $3, edition_id = COALESCE($4, user_books.edition_id), updated_at = CURRENT_TIMESTAMP`
 
-	_, err := r.db.Exec(query, userID, bookID, status)
+	_, err := r.db.Exec(query, userID, bookID, status, editionID)
 	return err
 }
 
@@ -33,64 +36,183 @@ func (r *UserBookRepository) RemoveFromReadingList(userID, bookID int64) error {
 	return err
 }
 
-func (r *UserBookRepository) GetUserBooks(userID int64, status string) ([]domain.UserBook, error) {
-	var query string
-	var args []interface{}
+// userBookKeyset orders a reading list by the last status change, newest
+// first.
+var userBookKeyset = keyset{sort: "updated_at", columns: []string{"ub.updated_at", "ub.id"}, descending: true}
 
+// GetUserBooks returns a page of the user's reading list, optionally only
+// the books with the given status.
+func (r *UserBookRepository) GetUserBooks(userID int64, status string, page domain.PageRequest) (*domain.Page[domain.UserBook], error) {
+	where := " WHERE ub.user_id = $1"
+	args := []interface{}{userID}
 	if status != "" {
-		query = `
-			SELECT ub.id, ub.user_id, ub.book_id, ub.status, ub.created_at, ub.updated_at,
-			       b.id, b.title, b.description, b.cover_url, b.isbn, b.published_at,
-			       b.created_at, b.updated_at
-			FROM user_books ub
-			JOIN books b ON ub.book_id = b.id
-			WHERE ub.user_id = $1 AND ub.status = $2
-			ORDER BY ub.updated_at DESC`
-		args = []interface{}{userID, status}
-	} else {
-		query = `
-			SELECT ub.id, ub.user_id, ub.book_id, ub.status, ub.created_at, ub.updated_at,
-			       b.id, b.title, b.description, b.cover_url, b.isbn, b.published_at,
-			       b.created_at, b.updated_at
-			FROM user_books ub
-			JOIN books b ON ub.book_id = b.id
-			WHERE ub.user_id = $1
-			ORDER BY ub.updated_at DESC`
-		args = []interface{}{userID}
+		args = append(args, status)
+		where += " AND ub.status = $2"
 	}
 
-	rows, err := r.db.Query(query, args...)
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM user_books ub`+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	seek, args, err := userBookKeyset.seek(page, args)
 	if err != nil {
 		return nil, err
 	}
+	order, args := userBookKeyset.orderBy(page, args)
+
+	// The book shows the user's edition, or else its primary one
+	query := `
+		SELECT ub.id, ub.user_id, ub.book_id, ub.edition_id, ub.status, ub.created_at, ub.updated_at,
+		       b.id, b.title, b.description, e.cover_url, e.isbn, e.published_at,
+		       b.created_at, b.updated_at, s.id, s.name, sb.position` + userBookKeyset.keys() + `
+		FROM user_books ub
+		JOIN books b ON ub.book_id = b.id
+		LEFT JOIN LATERAL (
+			SELECT cover_url, isbn, published_at
+			FROM editions WHERE book_id = b.id
+			ORDER BY (id = ub.edition_id) IS TRUE DESC, id
+			LIMIT 1
+		) e ON true
+		LEFT JOIN series_books sb ON sb.book_id = b.id
+		LEFT JOIN series s ON s.id = sb.series_id` + andWhere(where, seek) + order
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
 	defer rows.Close()
 
-	var userBooks []domain.UserBook
+	userBooks := []domain.UserBook{}
+	var keys [][]string
+	var seriesIDs []int64
 	for rows.Next() {
 		var ub domain.UserBook
 		ub.Book = &domain.Book{}
-		var coverURL, isbn sql.NullString
-
-		err := rows.Scan(
-			&ub.ID, &ub.UserID, &ub.BookID, &ub.Status, &ub.CreatedAt, &ub.UpdatedAt,
+		var coverURL, isbn, seriesName sql.NullString
+		var editionID, seriesID sql.NullInt64
+		var publishedAt sql.NullTime
+		var position sql.NullFloat64
+
+		row := userBookKeyset.row(rows)
+		err := row.Scan(
+			&ub.ID, &ub.UserID, &ub.BookID, &editionID, &ub.Status, &ub.CreatedAt, &ub.UpdatedAt,
 			&ub.Book.ID, &ub.Book.Title, &ub.Book.Description, &coverURL, &isbn,
-			&ub.Book.PublishedAt, &ub.Book.CreatedAt, &ub.Book.UpdatedAt,
+			&publishedAt, &ub.Book.CreatedAt, &ub.Book.UpdatedAt,
+			&seriesID, &seriesName, &position,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		if editionID.Valid {
+			ub.EditionID = &editionID.Int64
+		}
+		ub.Book.PublishedAt = publishedAt.Time
+
 		if coverURL.Valid {
 			ub.Book.CoverURL = coverURL.String
 		}
 		if isbn.Valid {
 			ub.Book.ISBN = isbn.String
 		}
+		if seriesID.Valid {
+			ub.Book.Series = &domain.BookSeries{ID: seriesID.Int64, Name: seriesName.String, Position: position.Float64}
+			seriesIDs = append(seriesIDs, seriesID.Int64)
+		}
 
 		userBooks = append(userBooks, ub)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := newPage(userBookKeyset, page, userBooks, keys, total)
+	if len(seriesIDs) == 0 {
+		return result, nil
+	}
+
+	progress, err := r.GetSeriesProgress(userID, seriesIDs)
+	if err != nil {
+		return nil, err
+	}
+	bySeries := make(map[int64]*domain.SeriesProgress, len(progress))
+	for i := range progress {
+		bySeries[progress[i].SeriesID] = &progress[i]
+	}
+	for i := range result.Items {
+		if series := result.Items[i].Book.Series; series != nil {
+			result.Items[i].SeriesProgress = bySeries[series.ID]
+		}
 	}
 
-	return userBooks, nil
+	return result, nil
+}
+
+// GetSeriesProgress returns the user's progress through each series with a
+// volume on their reading list, only the given series when seriesIDs is not
+// nil.
+func (r *UserBookRepository) GetSeriesProgress(userID int64, seriesIDs []int64) ([]domain.SeriesProgress, error) {
+	where := ""
+	args := []interface{}{userID}
+	if seriesIDs != nil {
+		args = append(args, pq.Array(seriesIDs))
+		where = " WHERE s.id = ANY($2)"
+	}
+
+	query := `
+		SELECT s.id, s.name, COUNT(*),
+		       COUNT(*) FILTER (WHERE ub.status = 'read'),
+		       COUNT(*) FILTER (WHERE ub.status = 'reading'),
+		       nv.book_id, nv.title, nv.position
+		FROM series s
+		JOIN series_books sb ON sb.series_id = s.id
+		LEFT JOIN user_books ub ON ub.book_id = sb.book_id AND ub.user_id = $1
+		LEFT JOIN LATERAL (
+			SELECT nsb.book_id, nb.title, nsb.position
+			FROM series_books nsb
+			JOIN books nb ON nb.id = nsb.book_id
+			WHERE nsb.series_id = s.id
+			  AND NOT EXISTS (
+				SELECT 1 FROM user_books r
+				WHERE r.user_id = $1 AND r.book_id = nsb.book_id AND r.status = 'read'
+			  )
+			ORDER BY nsb.position
+			LIMIT 1
+		) nv ON true` + where + `
+		GROUP BY s.id, nv.book_id, nv.title, nv.position
+		HAVING COUNT(ub.id) > 0
+		ORDER BY lower(s.name), s.id`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := []domain.SeriesProgress{}
+	for rows.Next() {
+		var p domain.SeriesProgress
+		var nextID sql.NullInt64
+		var nextTitle sql.NullString
+		var nextPosition sql.NullFloat64
+
+		err := rows.Scan(
+			&p.SeriesID, &p.SeriesName, &p.Volumes, &p.Read, &p.Reading,
+			&nextID, &nextTitle, &nextPosition,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if nextID.Valid {
+			p.NextVolume = &domain.SeriesVolume{BookID: nextID.Int64, Title: nextTitle.String, Position: nextPosition.Float64}
+		}
+		progress = append(progress, p)
+	}
+
+	return progress, rows.Err()
 }
 
 // Favorite methods
@@ -123,15 +245,65 @@ func (r *UserBookRepository) RemoveFromFavorites(userID, bookID int64) error {
 	return nil
 }
 
-func (r *UserBookRepository) GetUserFavorites(userID int64) ([]domain.Favorite, error) {
+// SetRating adds or replaces the user's rating of a book.
+func (r *UserBookRepository) SetRating(rating *domain.Rating) error {
 	query := `
-		SELECT f.id, f.user_id, f.book_id, f.created_at,
-		       b.id, b.title, b.description, b.cover_url, b.isbn, b.published_at,
-		       b.created_at, b.updated_at
-		FROM favorites f
-		JOIN books b ON f.book_id = b.id
-		WHERE f.user_id = $1
-		ORDER BY f.created_at DESC`
+		INSERT INTO book_ratings (user_id, book_id, rating)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, book_id) DO UPDATE SET rating = EXCLUDED.rating
+		RETURNING created_at, updated_at`
+
+	return r.db.QueryRow(query, rating.UserID, rating.BookID, rating.Rating).Scan(
+		&rating.CreatedAt, &rating.UpdatedAt,
+	)
+}
+
+func (r *UserBookRepository) GetRating(userID, bookID int64) (*domain.Rating, error) {
+	query := `
+		SELECT user_id, book_id, rating, created_at, updated_at
+		FROM book_ratings WHERE user_id = $1 AND book_id = $2`
+
+	rating := &domain.Rating{}
+	err := r.db.QueryRow(query, userID, bookID).Scan(
+		&rating.UserID, &rating.BookID, &rating.Rating, &rating.CreatedAt, &rating.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("rating not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return rating, nil
+}
+
+func (r *UserBookRepository) DeleteRating(userID, bookID int64) error {
+	result, err := r.db.Exec("DELETE FROM book_ratings WHERE user_id = $1 AND book_id = $2", userID, bookID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("rating not found")
+	}
+
+	return nil
+}
+
+// GetUserRatings returns every rating of the user with the book's title,
+// newest first.
+func (r *UserBookRepository) GetUserRatings(userID int64) ([]domain.Rating, error) {
+	query := `
+		SELECT r.user_id, r.book_id, r.rating, r.created_at, r.updated_at, b.title
+		FROM book_ratings r
+		JOIN books b ON r.book_id = b.id
+		WHERE r.user_id = $1
+		ORDER BY r.updated_at DESC`
 
 	rows, err := r.db.Query(query, userID)
 	if err != nil {
@@ -139,21 +311,70 @@ func (r *UserBookRepository) GetUserFavorites(userID int64) ([]domain.Favorite,
 	}
 	defer rows.Close()
 
-	var favorites []domain.Favorite
+	ratings := []domain.Rating{}
+	for rows.Next() {
+		var rating domain.Rating
+		err := rows.Scan(
+			&rating.UserID, &rating.BookID, &rating.Rating,
+			&rating.CreatedAt, &rating.UpdatedAt, &rating.BookTitle,
+		)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, rating)
+	}
+
+	return ratings, rows.Err()
+}
+
+// favoriteKeyset orders favorites newest first.
+var favoriteKeyset = keyset{sort: "created_at", columns: []string{"f.created_at", "f.id"}, descending: true}
+
+func (r *UserBookRepository) GetUserFavorites(userID int64, page domain.PageRequest) (*domain.Page[domain.Favorite], error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM favorites WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	seek, args, err := favoriteKeyset.seek(page, []interface{}{userID})
+	if err != nil {
+		return nil, err
+	}
+	order, args := favoriteKeyset.orderBy(page, args)
+
+	query := `
+		SELECT f.id, f.user_id, f.book_id, f.created_at,
+		       b.id, b.title, b.description, pe.cover_url, pe.isbn, pe.published_at,
+		       b.created_at, b.updated_at` + favoriteKeyset.keys() + `
+		FROM favorites f
+		JOIN books b ON f.book_id = b.id` + primaryEditionJoin + andWhere(" WHERE f.user_id = $1", seek) + order
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
+	defer rows.Close()
+
+	favorites := []domain.Favorite{}
+	var keys [][]string
 	for rows.Next() {
 		var fav domain.Favorite
 		fav.Book = &domain.Book{}
 		var coverURL, isbn sql.NullString
+		var publishedAt sql.NullTime
 
-		err := rows.Scan(
+		row := favoriteKeyset.row(rows)
+		err := row.Scan(
 			&fav.ID, &fav.UserID, &fav.BookID, &fav.CreatedAt,
 			&fav.Book.ID, &fav.Book.Title, &fav.Book.Description, &coverURL, &isbn,
-			&fav.Book.PublishedAt, &fav.Book.CreatedAt, &fav.Book.UpdatedAt,
+			&publishedAt, &fav.Book.CreatedAt, &fav.Book.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		fav.Book.PublishedAt = publishedAt.Time
+
 		if coverURL.Valid {
 			fav.Book.CoverURL = coverURL.String
 		}
@@ -162,9 +383,13 @@ func (r *UserBookRepository) GetUserFavorites(userID int64) ([]domain.Favorite,
 		}
 
 		favorites = append(favorites, fav)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return favorites, nil
+	return newPage(favoriteKeyset, page, favorites, keys, total), nil
 }
 
 func (r *UserBookRepository) IsFavorite(userID, bookID int64) (bool, error) {
@@ -186,15 +411,85 @@ func (r *UserBookRepository) CreateComment(comment *domain.Comment) error {
 	)
 }
 
-func (r *UserBookRepository) GetBookComments(bookID int64) ([]domain.Comment, error) {
+func (r *UserBookRepository) GetCommentByID(id int64) (*domain.Comment, error) {
+	query := `
+		SELECT id, COALESCE(user_id, 0), book_id, content, created_at, updated_at
+		FROM comments WHERE id = $1`
+
+	comment := &domain.Comment{}
+	err := r.db.QueryRow(query, id).Scan(
+		&comment.ID, &comment.UserID, &comment.BookID, &comment.Content,
+		&comment.CreatedAt, &comment.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("comment not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// commentKeyset orders comments newest first.
+var commentKeyset = keyset{sort: "created_at", columns: []string{"c.created_at", "c.id"}, descending: true}
+
+func (r *UserBookRepository) GetBookComments(bookID int64, page domain.PageRequest) (*domain.Page[domain.Comment], error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM comments WHERE book_id = $1`, bookID).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	seek, args, err := commentKeyset.seek(page, []interface{}{bookID})
+	if err != nil {
+		return nil, err
+	}
+	order, args := commentKeyset.orderBy(page, args)
+
+	query := `
+		SELECT c.id, COALESCE(c.user_id, 0), c.book_id, c.content, c.created_at, c.updated_at,
+		       COALESCE(u.username, 'Deleted user')` + commentKeyset.keys() + `
+		FROM comments c
+		LEFT JOIN users u ON c.user_id = u.id` + andWhere(" WHERE c.book_id = $1", seek) + order
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
+	}
+	defer rows.Close()
+
+	comments := []domain.Comment{}
+	var keys [][]string
+	for rows.Next() {
+		var comment domain.Comment
+		row := commentKeyset.row(rows)
+		err := row.Scan(
+			&comment.ID, &comment.UserID, &comment.BookID, &comment.Content,
+			&comment.CreatedAt, &comment.UpdatedAt, &comment.Username,
+		)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newPage(commentKeyset, page, comments, keys, total), nil
+}
+
+// GetUserComments returns every comment the user wrote, with the book title.
+func (r *UserBookRepository) GetUserComments(userID int64) ([]domain.Comment, error) {
 	query := `
-		SELECT c.id, c.user_id, c.book_id, c.content, c.created_at, c.updated_at, u.username
+		SELECT c.id, c.user_id, c.book_id, c.content, c.created_at, c.updated_at, b.title
 		FROM comments c
-		JOIN users u ON c.user_id = u.id
-		WHERE c.book_id = $1
+		JOIN books b ON c.book_id = b.id
+		WHERE c.user_id = $1
 		ORDER BY c.created_at DESC`
 
-	rows, err := r.db.Query(query, bookID)
+	rows, err := r.db.Query(query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -205,7 +500,7 @@ func (r *UserBookRepository) GetBookComments(bookID int64) ([]domain.Comment, er
 		var comment domain.Comment
 		err := rows.Scan(
 			&comment.ID, &comment.UserID, &comment.BookID, &comment.Content,
-			&comment.CreatedAt, &comment.UpdatedAt, &comment.Username,
+			&comment.CreatedAt, &comment.UpdatedAt, &comment.BookTitle,
 		)
 		if err != nil {
 			return nil, err
@@ -213,7 +508,7 @@ func (r *UserBookRepository) GetBookComments(bookID int64) ([]domain.Comment, er
 		comments = append(comments, comment)
 	}
 
-	return comments, nil
+	return comments, rows.Err()
 }
 
 func (r *UserBookRepository) UpdateComment(commentID, userID int64, content string) error {
@@ -235,6 +530,24 @@ func (r *UserBookRepository) UpdateComment(commentID, userID int64, content stri
 	return nil
 }
 
+func (r *UserBookRepository) DeleteAnyComment(commentID int64) error {
+	result, err := r.db.Exec(`DELETE FROM comments WHERE id = $1`, commentID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("comment not found")
+	}
+
+	return nil
+}
+
 func (r *UserBookRepository) DeleteComment(commentID, userID int64) error {
 	query := `DELETE FROM comments WHERE id = $1 AND user_id = $2`
 	result, err := r.db.Exec(query, commentID, userID)
@@ -2,8 +2,12 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/razvan/library-app/internal/domain"
 )
 
@@ -17,68 +21,70 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 func (r *UserRepository) Create(user *domain.User) error {
 	query := `
-		INSERT INTO users (email, username, password_hash, google_id, email_verified)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (email, username, password_hash, email_verified)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at, updated_at`
 
 	err := r.db.QueryRow(
 		query,
 		user.Email,
 		user.Username,
-		user.PasswordHash,
-		sql.NullString{String: user.GoogleID, Valid: user.GoogleID != ""},
+		sql.NullString{String: user.PasswordHash, Valid: user.PasswordHash != ""},
 		user.EmailVerified,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 
 	return err
 }
 
-func (r *UserRepository) GetByEmail(email string) (*domain.User, error) {
+// userColumns lists the columns read by scanUser, in order. Roles and the
+// permissions they grant are aggregated into arrays.
+const userColumns = `
+		id, email, username, COALESCE(avatar_url, ''), COALESCE(bio, ''),
+		COALESCE(pending_email, ''), COALESCE(password_hash, ''),
+		COALESCE(two_factor_secret, ''), two_factor_enabled, totp_last_step,
+		email_verified,
+		ARRAY(SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+		      WHERE ur.user_id = users.id ORDER BY r.name),
+		ARRAY(SELECT DISTINCT rp.permission FROM user_roles ur
+		      JOIN role_permissions rp ON rp.role_id = ur.role_id
+		      WHERE ur.user_id = users.id ORDER BY rp.permission),
+		suspended_at, COALESCE(suspension_reason, ''), delete_after,
+		created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*domain.User, error) {
 	user := &domain.User{}
-	query := `
-		SELECT id, email, username, password_hash, is_admin, google_id,
-		       two_factor_secret, two_factor_enabled, email_verified,
-		       created_at, updated_at
-		FROM users WHERE email = $1`
-
-	var googleID sql.NullString
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID, &user.Email, &user.Username, &user.PasswordHash,
-		&user.IsAdmin, &googleID, &user.TwoFactorSecret,
-		&user.TwoFactorEnabled, &user.EmailVerified,
+	var suspendedAt, deleteAfter sql.NullTime
+
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Username, &user.AvatarURL, &user.Bio,
+		&user.PendingEmail, &user.PasswordHash,
+		&user.TwoFactorSecret, &user.TwoFactorEnabled, &user.TOTPLastStep,
+		&user.EmailVerified, pq.Array(&user.Roles), pq.Array(&user.Permissions),
+		&suspendedAt, &user.SuspensionReason, &deleteAfter,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
-	}
 	if err != nil {
 		return nil, err
 	}
 
-	if googleID.Valid {
-		user.GoogleID = googleID.String
+	if suspendedAt.Valid {
+		user.SuspendedAt = &suspendedAt.Time
+	}
+	if deleteAfter.Valid {
+		user.DeleteAfter = &deleteAfter.Time
 	}
 
 	return user, nil
 }
 
-func (r *UserRepository) GetByID(id int64) (*domain.User, error) {
-	user := &domain.User{}
-	query := `
-		SELECT id, email, username, password_hash, is_admin, google_id,
-		       two_factor_secret, two_factor_enabled, email_verified,
-		       created_at, updated_at
-		FROM users WHERE id = $1`
-
-	var googleID sql.NullString
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Email, &user.Username, &user.PasswordHash,
-		&user.IsAdmin, &googleID, &user.TwoFactorSecret,
-		&user.TwoFactorEnabled, &user.EmailVerified,
-		&user.CreatedAt, &user.UpdatedAt,
-	)
+func (r *UserRepository) getOne(where string, args ...interface{}) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + where
 
+	user, err := scanUser(r.db.QueryRow(query, args...))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -86,54 +92,120 @@ func (r *UserRepository) GetByID(id int64) (*domain.User, error) {
 		return nil, err
 	}
 
-	if googleID.Valid {
-		user.GoogleID = googleID.String
-	}
-
 	return user, nil
 }
 
-func (r *UserRepository) GetByGoogleID(googleID string) (*domain.User, error) {
-	user := &domain.User{}
-	query := `
-		SELECT id, email, username, password_hash, is_admin, google_id,
-		       two_factor_secret, two_factor_enabled, email_verified,
-		       created_at, updated_at
-		FROM users WHERE google_id = $1`
-
-	var gID sql.NullString
-	err := r.db.QueryRow(query, googleID).Scan(
-		&user.ID, &user.Email, &user.Username, &user.PasswordHash,
-		&user.IsAdmin, &gID, &user.TwoFactorSecret,
-		&user.TwoFactorEnabled, &user.EmailVerified,
-		&user.CreatedAt, &user.UpdatedAt,
-	)
+func (r *UserRepository) GetByEmail(email string) (*domain.User, error) {
+	return r.getOne("email = $1", email)
+}
 
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+func (r *UserRepository) GetByID(id int64) (*domain.User, error) {
+	return r.getOne("id = $1", id)
+}
+
+// userKeyset orders users newest first.
+var userKeyset = keyset{sort: "created_at", columns: []string{"created_at", "id"}, descending: true}
+
+// List returns one page of users matching the filter.
+func (r *UserRepository) List(filter domain.UserFilter, page domain.PageRequest) (*domain.Page[domain.User], error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Email != "" {
+		addCondition("email ILIKE $%d ESCAPE '\\'", "%"+escapeLike(filter.Email)+"%")
+	}
+	if filter.Username != "" {
+		addCondition("username ILIKE $%d ESCAPE '\\'", "%"+escapeLike(filter.Username)+"%")
 	}
+	if filter.Role != "" {
+		addCondition(`EXISTS (SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+		                      WHERE ur.user_id = users.id AND r.name = $%d)`, filter.Role)
+	}
+	if filter.TwoFactor != nil {
+		addCondition("two_factor_enabled = $%d", *filter.TwoFactor)
+	}
+	if filter.Verified != nil {
+		addCondition("email_verified = $%d", *filter.Verified)
+	}
+	if filter.Suspended != nil {
+		addCondition("(suspended_at IS NOT NULL) = $%d", *filter.Suspended)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	seek, args, err := userKeyset.seek(page, args)
 	if err != nil {
 		return nil, err
 	}
+	order, args := userKeyset.orderBy(page, args)
 
-	if gID.Valid {
-		user.GoogleID = gID.String
+	rows, err := r.db.Query(`SELECT `+userColumns+userKeyset.keys()+` FROM users`+andWhere(where, seek)+order, args...)
+	if err != nil {
+		return nil, cursorError(page, err)
 	}
+	defer rows.Close()
 
-	return user, nil
+	users := []domain.User{}
+	var keys [][]string
+	for rows.Next() {
+		row := userKeyset.row(rows)
+		user, err := scanUser(row)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+		keys = append(keys, row.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newPage(userKeyset, page, users, keys, total), nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// escapeLike escapes the LIKE wildcards in user input.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func (r *UserRepository) UsernameExists(username string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)", username).Scan(&exists)
+	return exists, err
 }
 
 func (r *UserRepository) Update(user *domain.User) error {
 	query := `
 		UPDATE users
-		SET username = $1, is_admin = $2, two_factor_secret = $3,
-		    two_factor_enabled = $4, email_verified = $5
-		WHERE id = $6`
+		SET username = $1, two_factor_secret = $2,
+		    two_factor_enabled = $3, email_verified = $4
+		WHERE id = $5`
 
 	_, err := r.db.Exec(
 		query,
 		user.Username,
-		user.IsAdmin,
 		user.TwoFactorSecret,
 		user.TwoFactorEnabled,
 		user.EmailVerified,
@@ -143,8 +215,189 @@ func (r *UserRepository) Update(user *domain.User) error {
 	return err
 }
 
+// UpdateProfile stores the username, bio and pending email address.
+func (r *UserRepository) UpdateProfile(user *domain.User) error {
+	query := `
+		UPDATE users
+		SET username = $1, bio = NULLIF($2, ''), pending_email = NULLIF($3, '')
+		WHERE id = $4`
+
+	_, err := r.db.Exec(query, user.Username, user.Bio, user.PendingEmail, user.ID)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("username is already taken")
+	}
+	return err
+}
+
+func (r *UserRepository) UpdateAvatar(userID int64, avatarURL string) error {
+	_, err := r.db.Exec(`UPDATE users SET avatar_url = NULLIF($1, '') WHERE id = $2`, avatarURL, userID)
+	return err
+}
+
+// ConfirmEmailChange replaces the user's email with the pending address, as
+// long as it still is the address the verification was sent to.
+func (r *UserRepository) ConfirmEmailChange(userID int64, email string) (bool, error) {
+	query := `
+		UPDATE users
+		SET email = pending_email, pending_email = NULL, email_verified = TRUE
+		WHERE id = $1 AND pending_email = $2`
+
+	result, err := r.db.Exec(query, userID, email)
+	if isUniqueViolation(err) {
+		return false, fmt.Errorf("email address is already in use")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// ScheduleDeletion marks the account for deletion at the given time, unless
+// it is the last admin's. A nil time cancels a scheduled deletion.
+func (r *UserRepository) ScheduleDeletion(userID int64, deleteAfter *time.Time) error {
+	query := `UPDATE users SET delete_after = $1 WHERE id = $2`
+	if deleteAfter == nil {
+		_, err := r.db.Exec(query, deleteAfter, userID)
+		return err
+	}
+
+	return keepingAdmin(r.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(query, deleteAfter, userID)
+		return err
+	})
+}
+
+// DeleteScheduled deletes the accounts whose deletion is due and returns
+// their avatar URLs, so the files can be removed too.
+func (r *UserRepository) DeleteScheduled() ([]string, error) {
+	rows, err := r.db.Query(`
+		DELETE FROM users
+		WHERE delete_after IS NOT NULL AND delete_after <= CURRENT_TIMESTAMP
+		RETURNING COALESCE(avatar_url, '')`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var avatars []string
+	for rows.Next() {
+		var avatarURL string
+		if err := rows.Scan(&avatarURL); err != nil {
+			return nil, err
+		}
+		avatars = append(avatars, avatarURL)
+	}
+
+	return avatars, rows.Err()
+}
+
 func (r *UserRepository) UpdatePassword(userID int64, passwordHash string) error {
 	query := `UPDATE users SET password_hash = $1 WHERE id = $2`
 	_, err := r.db.Exec(query, passwordHash, userID)
 	return err
 }
+
+// ClearPassword removes the user's password, so they can only log in again
+// after resetting it.
+func (r *UserRepository) ClearPassword(userID int64) error {
+	_, err := r.db.Exec(`UPDATE users SET password_hash = NULL WHERE id = $1`, userID)
+	return err
+}
+
+// Suspend blocks the account, unless it is the last admin's.
+func (r *UserRepository) Suspend(userID int64, reason string) error {
+	return keepingAdmin(r.db, func(tx *sql.Tx) error {
+		query := `UPDATE users SET suspended_at = CURRENT_TIMESTAMP, suspension_reason = $1 WHERE id = $2`
+		_, err := tx.Exec(query, reason, userID)
+		return err
+	})
+}
+
+func (r *UserRepository) Unsuspend(userID int64) error {
+	query := `UPDATE users SET suspended_at = NULL, suspension_reason = NULL WHERE id = $1`
+	_, err := r.db.Exec(query, userID)
+	return err
+}
+
+// Delete removes the account, unless it is the last admin's.
+func (r *UserRepository) Delete(userID int64) error {
+	return keepingAdmin(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM users WHERE id = $1`, userID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return fmt.Errorf("user not found")
+		}
+
+		return nil
+	})
+}
+
+// ConsumeTOTPStep records the TOTP time step of an accepted code. It returns
+// false if the same or a later step was already used, which means the code
+// is being replayed.
+func (r *UserRepository) ConsumeTOTPStep(userID, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1`
+	result, err := r.db.Exec(query, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// MarkEmailVerified verifies the user's email, as long as it still is the
+// address the verification was sent to.
+func (r *UserRepository) MarkEmailVerified(userID int64, email string) (bool, error) {
+	query := `UPDATE users SET email_verified = TRUE WHERE id = $1 AND email = $2`
+	result, err := r.db.Exec(query, userID, email)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// ClaimVerificationSend records that a verification email is being sent. It
+// returns false if one was already sent within the cooldown.
+func (r *UserRepository) ClaimVerificationSend(userID int64, cooldown time.Duration) (bool, error) {
+	query := `
+		UPDATE users SET verification_sent_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		  AND (verification_sent_at IS NULL
+		       OR verification_sent_at < CURRENT_TIMESTAMP - make_interval(secs => $2))`
+
+	result, err := r.db.Exec(query, userID, cooldown.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
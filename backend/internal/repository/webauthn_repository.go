@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+type WebAuthnRepository struct {
+	db *sql.DB
+}
+
+func NewWebAuthnRepository(db *sql.DB) *WebAuthnRepository {
+	return &WebAuthnRepository{db: db}
+}
+
+// User handle methods
+func (r *WebAuthnRepository) GetUserHandle(userID int64) ([]byte, error) {
+	var handle []byte
+	err := r.db.QueryRow("SELECT webauthn_handle FROM users WHERE id = $1", userID).Scan(&handle)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	return handle, err
+}
+
+func (r *WebAuthnRepository) SetUserHandle(userID int64, handle []byte) error {
+	_, err := r.db.Exec(
+		"UPDATE users SET webauthn_handle = $1 WHERE id = $2 AND webauthn_handle IS NULL",
+		handle, userID,
+	)
+	return err
+}
+
+func (r *WebAuthnRepository) GetUserIDByHandle(handle []byte) (int64, error) {
+	var userID int64
+	err := r.db.QueryRow("SELECT id FROM users WHERE webauthn_handle = $1", handle).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("user not found")
+	}
+	return userID, err
+}
+
+// Credential methods
+func (r *WebAuthnRepository) CreateCredential(credential *domain.WebAuthnCredential) error {
+	query := `
+		INSERT INTO webauthn_credentials (user_id, credential_id, name, credential)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(
+		query,
+		credential.UserID,
+		credential.CredentialID,
+		credential.Name,
+		credential.Data,
+	).Scan(&credential.ID, &credential.CreatedAt)
+}
+
+func (r *WebAuthnRepository) GetUserCredentials(userID int64) ([]domain.WebAuthnCredential, error) {
+	query := `
+		SELECT id, user_id, credential_id, name, credential, created_at, last_used_at
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at ASC`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credentials []domain.WebAuthnCredential
+	for rows.Next() {
+		var credential domain.WebAuthnCredential
+		var lastUsedAt sql.NullTime
+
+		err := rows.Scan(
+			&credential.ID, &credential.UserID, &credential.CredentialID,
+			&credential.Name, &credential.Data, &credential.CreatedAt, &lastUsedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if lastUsedAt.Valid {
+			credential.LastUsedAt = &lastUsedAt.Time
+		}
+
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, nil
+}
+
+func (r *WebAuthnRepository) CountUserCredentials(userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = $1", userID).Scan(&count)
+	return count, err
+}
+
+// UpdateCredentialUsage stores the credential record returned by a successful
+// assertion, which carries the new signature counter.
+func (r *WebAuthnRepository) UpdateCredentialUsage(credentialID, data []byte) error {
+	query := `
+		UPDATE webauthn_credentials
+		SET credential = $1, last_used_at = CURRENT_TIMESTAMP
+		WHERE credential_id = $2`
+
+	_, err := r.db.Exec(query, data, credentialID)
+	return err
+}
+
+func (r *WebAuthnRepository) DeleteCredential(id, userID int64) error {
+	result, err := r.db.Exec("DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("credential not found")
+	}
+
+	return nil
+}
+
+func (r *WebAuthnRepository) DeleteUserCredentials(userID int64) error {
+	_, err := r.db.Exec("DELETE FROM webauthn_credentials WHERE user_id = $1", userID)
+	return err
+}
+
+// Ceremony methods
+func (r *WebAuthnRepository) CreateCeremony(ceremony *domain.WebAuthnCeremony) error {
+	query := `
+		INSERT INTO webauthn_ceremonies (id, user_id, purpose, session_data, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.Exec(
+		query,
+		ceremony.ID,
+		sql.NullInt64{Int64: ceremony.UserID, Valid: ceremony.UserID != 0},
+		ceremony.Purpose,
+		ceremony.SessionData,
+		ceremony.ExpiresAt,
+	)
+	return err
+}
+
+// TakeCeremony loads and deletes a ceremony so that it can only be finished
+// once.
+func (r *WebAuthnRepository) TakeCeremony(id string, purpose domain.WebAuthnPurpose) (*domain.WebAuthnCeremony, error) {
+	ceremony := &domain.WebAuthnCeremony{}
+	query := `
+		DELETE FROM webauthn_ceremonies
+		WHERE id = $1 AND purpose = $2
+		RETURNING id, user_id, purpose, session_data, expires_at`
+
+	var userID sql.NullInt64
+	err := r.db.QueryRow(query, id, purpose).Scan(
+		&ceremony.ID, &userID, &ceremony.Purpose,
+		&ceremony.SessionData, &ceremony.ExpiresAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("ceremony not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ceremony.UserID = userID.Int64
+	return ceremony, nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+	"github.com/razvan/library-app/pkg/auth"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so keys can be told apart from
+	// JWTs in an Authorization header and picked up by secret scanners.
+	APIKeyPrefix = "lib_"
+
+	maxAPIKeysPerUser = 20
+)
+
+// APIKeyService manages personal access tokens. A key acts on behalf of its
+// owner with the owner's current permissions, further limited by its scopes.
+type APIKeyService struct {
+	apiKeyRepo   *repository.APIKeyRepository
+	userRepo     *repository.UserRepository
+	auditService *AuditService
+}
+
+func NewAPIKeyService(apiKeyRepo *repository.APIKeyRepository, userRepo *repository.UserRepository, auditService *AuditService) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo:   apiKeyRepo,
+		userRepo:     userRepo,
+		auditService: auditService,
+	}
+}
+
+// IsAPIKey reports whether a bearer token looks like an API key rather than
+// a JWT.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// CreateKey generates a new key. The plain key is only part of the returned
+// value; afterwards just its hash is known.
+func (s *APIKeyService) CreateKey(userID int64, req *domain.APIKeyCreate, client domain.ClientInfo) (*domain.CreatedAPIKey, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expiry must be in the future")
+	}
+
+	count, err := s.apiKeyRepo.CountByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count api keys: %w", err)
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, fmt.Errorf("you can have at most %d api keys", maxAPIKeysPerUser)
+	}
+
+	plain := APIKeyPrefix + auth.NewTokenID() + auth.NewTokenID()
+	key := domain.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    plain[:len(APIKeyPrefix)+8],
+		KeyHash:   auth.HashToken(plain),
+		Scopes:    uniqueScopes(req.Scopes),
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := s.apiKeyRepo.Create(&key); err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditAPIKeyCreated,
+		TargetType:  domain.AuditTargetAPIKey,
+		TargetID:    key.ID,
+		Client:      client,
+		After:       key,
+	})
+
+	return &domain.CreatedAPIKey{APIKey: key, Key: plain}, nil
+}
+
+func (s *APIKeyService) GetKeys(userID int64) ([]domain.APIKey, error) {
+	return s.apiKeyRepo.GetByUser(userID)
+}
+
+func (s *APIKeyService) RevokeKey(userID, keyID int64, client domain.ClientInfo) error {
+	if err := s.apiKeyRepo.Delete(userID, keyID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditAPIKeyRevoked,
+		TargetType:  domain.AuditTargetAPIKey,
+		TargetID:    keyID,
+		Client:      client,
+	})
+
+	return nil
+}
+
+// Authenticate resolves a plain key to the key and its owner, and records
+// the use.
+func (s *APIKeyService) Authenticate(plain, ipAddress string) (*domain.APIKey, *domain.User, error) {
+	key, err := s.apiKeyRepo.GetByHash(auth.HashToken(plain))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid api key")
+	}
+
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, nil, fmt.Errorf("api key expired")
+	}
+
+	user, err := s.userRepo.GetByID(key.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid api key")
+	}
+
+	if err := checkNotSuspended(user); err != nil {
+		return nil, nil, err
+	}
+
+	if user.DeleteAfter != nil {
+		return nil, nil, fmt.Errorf("account scheduled for deletion")
+	}
+
+	if err := s.apiKeyRepo.Touch(key.ID, ipAddress); err != nil {
+		return nil, nil, fmt.Errorf("failed to record api key use: %w", err)
+	}
+
+	return key, user, nil
+}
+
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	var unique []string
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+)
+
+var errAuditChainBroken = errors.New("audit chain broken")
+
+// AuditService writes and reads the audit log. Recording never fails the
+// action being audited; errors are logged instead.
+type AuditService struct {
+	auditRepo *repository.AuditRepository
+	userRepo  *repository.UserRepository
+}
+
+func NewAuditService(auditRepo *repository.AuditRepository, userRepo *repository.UserRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo, userRepo: userRepo}
+}
+
+// AuditEntry describes an action to record. ActorUserID and TargetID are
+// left out of the event when zero. Before and After are snapshots of the
+// target; when both are set, only the fields that changed are kept.
+type AuditEntry struct {
+	ActorUserID int64
+	Action      string
+	TargetType  string
+	TargetID    int64
+	Client      domain.ClientInfo
+	Before      interface{}
+	After       interface{}
+	Reason      string
+}
+
+func (s *AuditService) Record(entry AuditEntry) {
+	event := &domain.AuditEvent{
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		IPAddress:  entry.Client.IPAddress,
+		UserAgent:  entry.Client.UserAgent,
+		Reason:     entry.Reason,
+	}
+	if entry.ActorUserID != 0 {
+		event.ActorUserID = &entry.ActorUserID
+	}
+	if entry.TargetID != 0 {
+		event.TargetID = &entry.TargetID
+	}
+
+	before, after, err := auditDiff(entry.Before, entry.After)
+	if err != nil {
+		log.Printf("Failed to encode audit event %s: %v", entry.Action, err)
+	}
+	event.Before, event.After = before, after
+
+	if err := s.auditRepo.Append(event); err != nil {
+		log.Printf("Failed to record audit event %s actor=%d target=%s/%d: %v",
+			entry.Action, entry.ActorUserID, entry.TargetType, entry.TargetID, err)
+	}
+}
+
+func (s *AuditService) ListEvents(actorUserID int64, filter domain.AuditFilter, page domain.PageRequest) (*domain.Page[domain.AuditEvent], error) {
+	if err := s.requireAuditor(actorUserID); err != nil {
+		return nil, err
+	}
+
+	events, err := s.auditRepo.List(filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// VerifyChain recomputes every hash and checks that each event follows the
+// one before it.
+func (s *AuditService) VerifyChain(actorUserID int64) (*domain.AuditVerification, error) {
+	if err := s.requireAuditor(actorUserID); err != nil {
+		return nil, err
+	}
+
+	result := &domain.AuditVerification{Valid: true}
+	// The first event follows the all-zero hash
+	prevHash := strings.Repeat("0", 64)
+	err := s.auditRepo.Walk(func(event *domain.AuditEvent) error {
+		if event.PrevHash != prevHash || event.ComputeHash() != event.Hash {
+			result.Valid = false
+			result.FirstInvalidID = &event.ID
+			return errAuditChainBroken
+		}
+
+		prevHash = event.Hash
+		result.Checked++
+		return nil
+	})
+	if err != nil && !errors.Is(err, errAuditChainBroken) {
+		return nil, fmt.Errorf("failed to verify audit log: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *AuditService) requireAuditor(actorUserID int64) error {
+	actor, err := s.userRepo.GetByID(actorUserID)
+	if err != nil || !actor.HasPermission(domain.PermissionUsersManage) {
+		return fmt.Errorf("unauthorized: you cannot read the audit log")
+	}
+	return nil
+}
+
+// auditDiff encodes the before and after snapshots. When both are objects,
+// the fields that are equal in both are dropped.
+func auditDiff(before, after interface{}) (json.RawMessage, json.RawMessage, error) {
+	beforeJSON, err := marshalAudit(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterJSON, err := marshalAudit(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if beforeJSON == nil || afterJSON == nil {
+		return beforeJSON, afterJSON, nil
+	}
+
+	var beforeFields, afterFields map[string]json.RawMessage
+	if json.Unmarshal(beforeJSON, &beforeFields) != nil || json.Unmarshal(afterJSON, &afterFields) != nil {
+		return beforeJSON, afterJSON, nil
+	}
+
+	for key, value := range beforeFields {
+		if other, ok := afterFields[key]; ok && bytes.Equal(value, other) {
+			delete(beforeFields, key)
+			delete(afterFields, key)
+		}
+	}
+
+	// Maps are encoded with sorted keys, so the result is stable
+	if beforeJSON, err = json.Marshal(beforeFields); err != nil {
+		return nil, nil, err
+	}
+	if afterJSON, err = json.Marshal(afterFields); err != nil {
+		return nil, nil, err
+	}
+
+	return beforeJSON, afterJSON, nil
+}
+
+func marshalAudit(value interface{}) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
@@ -2,23 +2,66 @@ package service
 
 import (
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/razvan/library-app/internal/domain"
 	"github.com/razvan/library-app/internal/repository"
 	"github.com/razvan/library-app/pkg/auth"
 )
 
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	maxTwoFactorAttempts  = 5
+)
+
+// Second factors a login challenge can be completed with
+const (
+	SecondFactorTOTP         = "totp"
+	SecondFactorRecoveryCode = "recovery_code"
+	SecondFactorWebAuthn     = "webauthn"
+)
+
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	jwtSecret string
-	appName   string
+	userRepo         *repository.UserRepository
+	refreshTokenRepo *repository.RefreshTokenRepository
+	twoFactorRepo    *repository.TwoFactorRepository
+	webAuthnRepo     *repository.WebAuthnRepository
+	sessionService   *SessionService
+	emailService     *EmailVerificationService
+	lockoutService   *LockoutService
+	auditService     *AuditService
+	passwordPolicy   *PasswordPolicy
+	keys             *auth.KeySet
+	jwtSecret        string
+	appName          string
+}
+
+// LoginResult is the outcome of a successful password check. When the user
+// has 2FA enabled, Tokens is nil and ChallengeToken has to be redeemed with
+// VerifyTwoFactorLogin.
+type LoginResult struct {
+	Tokens             *auth.TokenPair
+	User               *domain.User
+	ChallengeToken     string
+	ChallengeExpiresAt time.Time
+	SecondFactors      []string
 }
 
-func NewAuthService(userRepo *repository.UserRepository, jwtSecret, appName string) *AuthService {
+func NewAuthService(userRepo *repository.UserRepository, refreshTokenRepo *repository.RefreshTokenRepository, twoFactorRepo *repository.TwoFactorRepository, webAuthnRepo *repository.WebAuthnRepository, sessionService *SessionService, emailService *EmailVerificationService, lockoutService *LockoutService, auditService *AuditService, passwordPolicy *PasswordPolicy, keys *auth.KeySet, jwtSecret, appName string) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
-		appName:   appName,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		twoFactorRepo:    twoFactorRepo,
+		webAuthnRepo:     webAuthnRepo,
+		sessionService:   sessionService,
+		emailService:     emailService,
+		lockoutService:   lockoutService,
+		auditService:     auditService,
+		passwordPolicy:   passwordPolicy,
+		keys:             keys,
+		jwtSecret:        jwtSecret,
+		appName:          appName,
 	}
 }
 
@@ -29,6 +72,10 @@ func (s *AuthService) Register(reg *domain.UserRegistration) (*domain.User, erro
 		return nil, fmt.Errorf("user with this email already exists")
 	}
 
+	if err := s.passwordPolicy.Check(reg.Password, reg.Email, reg.Username); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	passwordHash, err := auth.HashPassword(reg.Password)
 	if err != nil {
@@ -40,7 +87,6 @@ func (s *AuthService) Register(reg *domain.UserRegistration) (*domain.User, erro
 		Username:      reg.Username,
 		PasswordHash:  passwordHash,
 		EmailVerified: false,
-		IsAdmin:       false,
 	}
 
 	err = s.userRepo.Create(user)
@@ -48,60 +94,149 @@ func (s *AuthService) Register(reg *domain.UserRegistration) (*domain.User, erro
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// The account is usable without the email, it can be resent later
+	if err := s.emailService.SendVerification(user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
 	return user, nil
 }
 
-func (s *AuthService) Login(login *domain.UserLogin) (*auth.TokenPair, *domain.User, error) {
+// verifyPassword checks the user's password. Hashes made with bcrypt or
+// with outdated argon2id settings are replaced once the password is known to
+// be correct.
+func (s *AuthService) verifyPassword(user *domain.User, password string) bool {
+	if user.PasswordHash == "" || !auth.CheckPassword(password, user.PasswordHash) {
+		return false
+	}
+
+	if auth.NeedsRehash(user.PasswordHash) {
+		passwordHash, err := auth.HashPassword(password)
+		if err == nil {
+			err = s.userRepo.UpdatePassword(user.ID, passwordHash)
+		}
+		if err != nil {
+			// The old hash still works, try again next time
+			log.Printf("Failed to rehash password of user %d: %v", user.ID, err)
+		} else {
+			user.PasswordHash = passwordHash
+		}
+	}
+
+	return true
+}
+
+func (s *AuthService) Login(login *domain.UserLogin, client domain.ClientInfo) (*LoginResult, error) {
 	user, err := s.userRepo.GetByEmail(login.Email)
 	if err != nil {
-		return nil, nil, fmt.Errorf("invalid credentials")
+		// Unknown emails still count against the client IP
+		if err := s.lockoutService.Check(0, client.IPAddress); err != nil {
+			return nil, err
+		}
+		s.lockoutService.RecordFailure(0, client.IPAddress, domain.LoginEventPasswordFailed)
+		// The input is not recorded, since the audit log is permanent and
+		// users sometimes type their password as the email
+		s.auditService.Record(AuditEntry{
+			Action: domain.AuditLoginFailed,
+			Client: client,
+			Reason: "unknown email",
+		})
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	if err := s.lockoutService.Check(user.ID, client.IPAddress); err != nil {
+		return nil, err
 	}
 
 	// Check password
-	if !auth.CheckPassword(login.Password, user.PasswordHash) {
-		return nil, nil, fmt.Errorf("invalid credentials")
+	if !s.verifyPassword(user, login.Password) {
+		s.lockoutService.RecordFailure(user.ID, client.IPAddress, domain.LoginEventPasswordFailed)
+		s.auditService.Record(AuditEntry{
+			Action:     domain.AuditLoginFailed,
+			TargetType: domain.AuditTargetUser,
+			TargetID:   user.ID,
+			Client:     client,
+			Reason:     "wrong password",
+		})
+		return nil, fmt.Errorf("invalid credentials")
 	}
 
-	// Don't generate token yet if 2FA is enabled
-	if user.TwoFactorEnabled {
-		return nil, user, nil
+	return s.finishPrimaryLogin(user, client)
+}
+
+// finishPrimaryLogin runs after the user proved their identity with a
+// password or an external provider. It starts a session, or opens a 2FA
+// challenge when the user has a second factor configured.
+func (s *AuthService) finishPrimaryLogin(user *domain.User, client domain.ClientInfo) (*LoginResult, error) {
+	if err := checkNotSuspended(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.emailService.CheckLogin(user); err != nil {
+		return nil, err
+	}
+
+	// Don't generate token yet if a second factor is configured
+	methods, err := s.secondFactorMethods(user)
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) > 0 {
+		return s.createTwoFactorChallenge(user, methods)
 	}
 
 	// Generate JWT tokens
-	tokens, err := auth.GenerateTokenPair(user.ID, user.Email, user.IsAdmin, s.jwtSecret)
+	tokens, err := s.startSession(user, client)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return nil, err
 	}
 
-	return tokens, user, nil
+	return &LoginResult{Tokens: tokens, User: user}, nil
 }
 
-func (s *AuthService) LoginWithGoogle(googleID, email, username string) (*auth.TokenPair, *domain.User, error) {
-	// Try to find user by Google ID
-	user, err := s.userRepo.GetByGoogleID(googleID)
+// createTwoFactorChallenge opens a single-use challenge bound to the user who
+// just passed the password check.
+func (s *AuthService) createTwoFactorChallenge(user *domain.User, methods []string) (*LoginResult, error) {
+	challenge := &domain.TwoFactorChallenge{
+		ID:        auth.NewTokenID(),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(twoFactorChallengeTTL),
+	}
+
+	if err := s.twoFactorRepo.CreateChallenge(challenge); err != nil {
+		return nil, fmt.Errorf("failed to create 2FA challenge: %w", err)
+	}
+
+	token, err := auth.GenerateChallengeToken(user.ID, challenge.ID, challenge.ExpiresAt, s.jwtSecret)
 	if err != nil {
-		// User doesn't exist, create new user
-		user = &domain.User{
-			Email:         email,
-			Username:      username,
-			GoogleID:      googleID,
-			EmailVerified: true,
-			IsAdmin:       false,
-		}
+		return nil, fmt.Errorf("failed to generate 2FA challenge: %w", err)
+	}
 
-		err = s.userRepo.Create(user)
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to create user: %w", err)
-		}
+	return &LoginResult{
+		User:               user,
+		ChallengeToken:     token,
+		ChallengeExpiresAt: challenge.ExpiresAt,
+		SecondFactors:      methods,
+	}, nil
+}
+
+// secondFactorMethods lists the second factors the user can complete a login
+// challenge with. An empty list means the password alone is enough.
+func (s *AuthService) secondFactorMethods(user *domain.User) ([]string, error) {
+	var methods []string
+	if user.TwoFactorEnabled {
+		methods = append(methods, SecondFactorTOTP, SecondFactorRecoveryCode)
 	}
 
-	// Generate JWT tokens (no 2FA for Google OAuth users in this implementation)
-	tokens, err := auth.GenerateTokenPair(user.ID, user.Email, user.IsAdmin, s.jwtSecret)
+	keys, err := s.webAuthnRepo.CountUserCredentials(user.ID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return nil, fmt.Errorf("failed to load security keys: %w", err)
+	}
+	if keys > 0 {
+		methods = append(methods, SecondFactorWebAuthn)
 	}
 
-	return tokens, user, nil
+	return methods, nil
 }
 
 func (s *AuthService) SetupTwoFactor(userID int64) (*domain.TwoFactorSetup, error) {
@@ -133,56 +268,285 @@ func (s *AuthService) SetupTwoFactor(userID int64) (*domain.TwoFactorSetup, erro
 	}, nil
 }
 
-func (s *AuthService) VerifyAndEnableTwoFactor(userID int64, code string) error {
+// VerifyAndEnableTwoFactor confirms the TOTP setup and returns a fresh set of
+// recovery codes. The codes are only stored hashed, so this is the one time
+// they can be shown to the user.
+func (s *AuthService) VerifyAndEnableTwoFactor(userID int64, code string, client domain.ClientInfo) ([]string, error) {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
-		return fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user not found")
 	}
 
 	if user.TwoFactorSecret == "" {
-		return fmt.Errorf("2FA setup not initiated")
+		return nil, fmt.Errorf("2FA setup not initiated")
 	}
 
 	// Verify the code
-	if !auth.ValidateTOTP(code, user.TwoFactorSecret) {
-		return fmt.Errorf("invalid verification code")
+	if !s.verifyTOTP(user, code) {
+		return nil, fmt.Errorf("invalid verification code")
 	}
 
 	// Enable 2FA
 	user.TwoFactorEnabled = true
 	err = s.userRepo.Update(user)
 	if err != nil {
-		return fmt.Errorf("failed to enable 2FA: %w", err)
+		return nil, fmt.Errorf("failed to enable 2FA: %w", err)
 	}
 
-	return nil
+	s.auditService.Record(AuditEntry{
+		ActorUserID: user.ID,
+		Action:      domain.AuditTwoFactorEnabled,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    user.ID,
+		Client:      client,
+	})
+
+	return s.generateRecoveryCodes(user.ID)
 }
 
-func (s *AuthService) VerifyTwoFactorLogin(userID int64, code string) (*auth.TokenPair, error) {
+// RegenerateRecoveryCodes replaces all of the user's recovery codes after
+// checking a current TOTP code.
+func (s *AuthService) RegenerateRecoveryCodes(userID int64, code string, client domain.ClientInfo) ([]string, error) {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found")
 	}
 
 	if !user.TwoFactorEnabled {
-		return nil, fmt.Errorf("2FA is not enabled for this user")
+		return nil, fmt.Errorf("2FA is not enabled")
 	}
 
-	// Verify the code
-	if !auth.ValidateTOTP(code, user.TwoFactorSecret) {
+	if !s.verifyTOTP(user, code) {
 		return nil, fmt.Errorf("invalid verification code")
 	}
 
+	codes, err := s.generateRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: user.ID,
+		Action:      domain.AuditRecoveryCodesRenewed,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    user.ID,
+		Client:      client,
+	})
+
+	return codes, nil
+}
+
+func (s *AuthService) CountRecoveryCodes(userID int64) (int, error) {
+	return s.twoFactorRepo.CountUnusedRecoveryCodes(userID)
+}
+
+func (s *AuthService) VerifyTwoFactorLogin(req *domain.TwoFactorLoginVerify, client domain.ClientInfo) (*LoginResult, error) {
+	challenge, user, err := s.openTwoFactorChallenge(req.ChallengeToken, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reserveTwoFactorAttempt(challenge); err != nil {
+		return nil, err
+	}
+
+	// Verify the TOTP code, or fall back to a recovery code
+	var verified bool
+	if req.RecoveryCode != "" {
+		verified = s.useRecoveryCode(user.ID, req.RecoveryCode)
+	} else {
+		verified = user.TwoFactorEnabled && s.verifyTOTP(user, req.Code)
+	}
+
+	if !verified {
+		return nil, s.failTwoFactorChallenge(challenge, client)
+	}
+
+	return s.completeTwoFactorChallenge(challenge, user, client)
+}
+
+// openTwoFactorChallenge resolves a challenge token to its open challenge and
+// the user it was issued for. Locked out accounts cannot use their challenge.
+func (s *AuthService) openTwoFactorChallenge(challengeToken string, client domain.ClientInfo) (*domain.TwoFactorChallenge, *domain.User, error) {
+	claims, err := auth.ValidateChallengeToken(challengeToken, s.jwtSecret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid or expired 2FA challenge")
+	}
+
+	challenge, err := s.twoFactorRepo.GetChallenge(claims.ID)
+	if err != nil || challenge.UserID != claims.UserID {
+		return nil, nil, fmt.Errorf("invalid or expired 2FA challenge")
+	}
+
+	if challenge.ConsumedAt != nil || time.Now().After(challenge.ExpiresAt) {
+		return nil, nil, fmt.Errorf("invalid or expired 2FA challenge")
+	}
+
+	user, err := s.userRepo.GetByID(challenge.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("user not found")
+	}
+
+	if err := s.lockoutService.Check(user.ID, client.IPAddress); err != nil {
+		return nil, nil, err
+	}
+
+	return challenge, user, nil
+}
+
+// reserveTwoFactorAttempt counts an attempt at the challenge before its
+// second factor is checked, so that concurrent requests with the same
+// challenge cannot check more guesses than the limit allows.
+func (s *AuthService) reserveTwoFactorAttempt(challenge *domain.TwoFactorChallenge) error {
+	attempts, err := s.twoFactorRepo.ReserveAttempt(challenge.ID, maxTwoFactorAttempts)
+	if err != nil {
+		return fmt.Errorf("invalid or expired 2FA challenge")
+	}
+	challenge.Attempts = attempts
+	return nil
+}
+
+// failTwoFactorChallenge records a wrong second factor and closes the
+// challenge once its last attempt is used. The failure also counts
+// towards the account and IP lockouts, so that logging in again does not
+// allow more guesses.
+func (s *AuthService) failTwoFactorChallenge(challenge *domain.TwoFactorChallenge, client domain.ClientInfo) error {
+	s.lockoutService.RecordFailure(challenge.UserID, client.IPAddress, domain.LoginEventTwoFactorFailed)
+	s.auditService.Record(AuditEntry{
+		Action:     domain.AuditLoginFailed,
+		TargetType: domain.AuditTargetUser,
+		TargetID:   challenge.UserID,
+		Client:     client,
+		Reason:     "wrong second factor",
+	})
+
+	if challenge.Attempts >= maxTwoFactorAttempts {
+		if _, err := s.twoFactorRepo.ConsumeChallenge(challenge.ID); err != nil {
+			return fmt.Errorf("failed to close 2FA challenge: %w", err)
+		}
+		return fmt.Errorf("too many failed attempts, please log in again")
+	}
+
+	return fmt.Errorf("invalid verification code")
+}
+
+// completeTwoFactorChallenge closes the challenge and logs the user in.
+func (s *AuthService) completeTwoFactorChallenge(challenge *domain.TwoFactorChallenge, user *domain.User, client domain.ClientInfo) (*LoginResult, error) {
+	consumed, err := s.twoFactorRepo.ConsumeChallenge(challenge.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete 2FA challenge: %w", err)
+	}
+	if !consumed {
+		return nil, fmt.Errorf("invalid or expired 2FA challenge")
+	}
+
 	// Generate JWT tokens
-	tokens, err := auth.GenerateTokenPair(user.ID, user.Email, user.IsAdmin, s.jwtSecret)
+	tokens, err := s.startSession(user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{Tokens: tokens, User: user}, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. Each refresh
+// token can be used once; presenting a used token again revokes its family.
+func (s *AuthService) RefreshTokens(refreshToken string) (*auth.TokenPair, error) {
+	claims, err := auth.ValidateRefreshToken(refreshToken, s.keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired refresh token")
+	}
+
+	stored, err := s.refreshTokenRepo.GetByID(claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired refresh token")
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, fmt.Errorf("refresh token has been revoked")
+	}
+
+	consumed, err := s.refreshTokenRepo.MarkUsed(stored.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	if !consumed {
+		// The token was already exchanged once, so someone is replaying it.
+		if err := s.sessionService.RevokeSession(stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("refresh token reuse detected, please log in again")
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	if err := checkNotSuspended(user); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, stored.FamilyID)
+}
+
+// startSession registers a new session for the user and issues its first
+// token pair. A completed login resets the account's failed attempts and
+// cancels a scheduled account deletion.
+func (s *AuthService) startSession(user *domain.User, client domain.ClientInfo) (*auth.TokenPair, error) {
+	// Every login ends here, whichever factors it used
+	if err := checkNotSuspended(user); err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionService.CreateSession(user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lockoutService.RecordSuccess(user.ID)
+
+	// Logging in during the grace period keeps an account scheduled for deletion
+	if user.DeleteAfter != nil {
+		if err := s.userRepo.ScheduleDeletion(user.ID, nil); err != nil {
+			return nil, fmt.Errorf("failed to cancel account deletion: %w", err)
+		}
+		user.DeleteAfter = nil
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: user.ID,
+		Action:      domain.AuditLogin,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    user.ID,
+		Client:      client,
+	})
+
+	return s.issueTokens(user, session.ID)
+}
+
+// issueTokens generates a token pair for the user and records the refresh
+// token under the given session, which doubles as the token family.
+func (s *AuthService) issueTokens(user *domain.User, sessionID string) (*auth.TokenPair, error) {
+	tokens, err := auth.GenerateTokenPair(user.ID, user.Email, user.Roles, user.Permissions, sessionID, s.keys)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
 
+	err = s.refreshTokenRepo.Create(&domain.RefreshToken{
+		ID:        tokens.RefreshTokenID,
+		UserID:    user.ID,
+		FamilyID:  sessionID,
+		ExpiresAt: tokens.RefreshExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
 	return tokens, nil
 }
 
-func (s *AuthService) DisableTwoFactor(userID int64, code string) error {
+func (s *AuthService) DisableTwoFactor(userID int64, code string, client domain.ClientInfo) error {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		return fmt.Errorf("user not found")
@@ -193,7 +557,7 @@ func (s *AuthService) DisableTwoFactor(userID int64, code string) error {
 	}
 
 	// Verify the code before disabling
-	if !auth.ValidateTOTP(code, user.TwoFactorSecret) {
+	if !s.verifyTOTP(user, code) {
 		return fmt.Errorf("invalid verification code")
 	}
 
@@ -205,32 +569,148 @@ func (s *AuthService) DisableTwoFactor(userID int64, code string) error {
 		return fmt.Errorf("failed to disable 2FA: %w", err)
 	}
 
+	if err := s.twoFactorRepo.DeleteRecoveryCodes(user.ID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: user.ID,
+		Action:      domain.AuditTwoFactorDisabled,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    user.ID,
+		Client:      client,
+	})
+
 	return nil
 }
 
-func (s *AuthService) MakeAdmin(adminUserID, targetUserID int64) error {
-	// Verify that the requesting user is an admin
+// ResetTwoFactor lets an admin turn off 2FA for a user who lost access to both
+// their authenticator and their recovery codes. All of the user's sessions
+// are revoked and the action is written to the audit log.
+func (s *AuthService) ResetTwoFactor(adminUserID, targetUserID int64, reason string, client domain.ClientInfo) error {
 	admin, err := s.userRepo.GetByID(adminUserID)
-	if err != nil {
-		return fmt.Errorf("admin user not found")
+	if err != nil || !admin.HasPermission(domain.PermissionUsersManage) {
+		return fmt.Errorf("unauthorized: only user managers can reset 2FA")
 	}
 
-	if !admin.IsAdmin {
-		return fmt.Errorf("unauthorized: only admins can make other users admins")
+	if adminUserID == targetUserID {
+		return fmt.Errorf("admins cannot reset their own 2FA")
 	}
 
-	// Get target user
-	targetUser, err := s.userRepo.GetByID(targetUserID)
+	user, err := s.userRepo.GetByID(targetUserID)
 	if err != nil {
 		return fmt.Errorf("target user not found")
 	}
 
-	// Make admin
-	targetUser.IsAdmin = true
-	err = s.userRepo.Update(targetUser)
+	methods, err := s.secondFactorMethods(user)
 	if err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
+		return err
+	}
+	if len(methods) == 0 {
+		return fmt.Errorf("2FA is not enabled for this user")
+	}
+
+	user.TwoFactorEnabled = false
+	user.TwoFactorSecret = ""
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to reset 2FA: %w", err)
+	}
+
+	if err := s.twoFactorRepo.DeleteRecoveryCodes(user.ID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if err := s.webAuthnRepo.DeleteUserCredentials(user.ID); err != nil {
+		return fmt.Errorf("failed to delete security keys: %w", err)
+	}
+
+	if err := s.sessionService.RevokeAllSessions(user.ID); err != nil {
+		return err
 	}
 
+	s.auditService.Record(AuditEntry{
+		ActorUserID: admin.ID,
+		Action:      domain.AuditTwoFactorReset,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    user.ID,
+		Client:      client,
+		Before:      map[string]interface{}{"second_factors": methods},
+		After:       map[string]interface{}{"second_factors": []string{}},
+		Reason:      reason,
+	})
+
 	return nil
 }
+
+// UnlockAccount lets an admin lift a brute-force lockout before it expires.
+func (s *AuthService) UnlockAccount(adminUserID, targetUserID int64, client domain.ClientInfo) error {
+	admin, err := s.userRepo.GetByID(adminUserID)
+	if err != nil || !admin.HasPermission(domain.PermissionUsersManage) {
+		return fmt.Errorf("unauthorized: only user managers can unlock accounts")
+	}
+
+	if _, err := s.userRepo.GetByID(targetUserID); err != nil {
+		return fmt.Errorf("target user not found")
+	}
+
+	if err := s.lockoutService.Unlock(targetUserID, client); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: admin.ID,
+		Action:      domain.AuditAccountUnlocked,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    targetUserID,
+		Client:      client,
+	})
+
+	return nil
+}
+
+func checkNotSuspended(user *domain.User) error {
+	if user.SuspendedAt != nil {
+		return fmt.Errorf("account suspended")
+	}
+	return nil
+}
+
+// verifyTOTP checks a TOTP code against the user's secret and rejects codes
+// whose time step was already accepted once.
+func (s *AuthService) verifyTOTP(user *domain.User, code string) bool {
+	step, ok := auth.ValidateTOTPStep(code, user.TwoFactorSecret, user.TOTPLastStep)
+	if !ok {
+		return false
+	}
+
+	consumed, err := s.userRepo.ConsumeTOTPStep(user.ID, step)
+	if err != nil || !consumed {
+		return false
+	}
+
+	user.TOTPLastStep = step
+	return true
+}
+
+func (s *AuthService) generateRecoveryCodes(userID int64) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+func (s *AuthService) useRecoveryCode(userID int64, code string) bool {
+	used, err := s.twoFactorRepo.UseRecoveryCode(userID, auth.HashRecoveryCode(code))
+	return err == nil && used
+}
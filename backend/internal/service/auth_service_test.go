@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/razvan/library-app/internal/domain"
+)
+
+// enableTOTP turns on 2FA for the user and returns the TOTP secret.
+func enableTOTP(t *testing.T, s *AuthService, user *domain.User) string {
+	t.Helper()
+
+	setup, err := s.SetupTwoFactor(user.ID)
+	if err != nil {
+		t.Fatalf("SetupTwoFactor() error = %v", err)
+	}
+	code, err := totp.GenerateCode(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.VerifyAndEnableTwoFactor(user.ID, code, testClient); err != nil {
+		t.Fatalf("VerifyAndEnableTwoFactor() error = %v", err)
+	}
+	return setup.Secret
+}
+
+func TestTwoFactorAttemptLimitUnderConcurrency(t *testing.T) {
+	services := newTestServices(t)
+	user := services.createUser(t, "reader@example.com", "correct-horse-battery")
+	secret := enableTOTP(t, services.auth, user)
+
+	login, err := services.auth.Login(&domain.UserLogin{Email: user.Email, Password: "correct-horse-battery"}, testClient)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if login.ChallengeToken == "" {
+		t.Fatalf("Login() = %+v, want a 2FA challenge", login)
+	}
+
+	// A code that is not the current one
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, _ := strconv.Atoi(code)
+	wrong := fmt.Sprintf("%06d", (current+1)%1000000)
+
+	const requests = 3 * maxTwoFactorAttempts
+	errs := make(chan error, requests)
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for i := 0; i < requests; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			start.Wait()
+			_, err := services.auth.VerifyTwoFactorLogin(&domain.TwoFactorLoginVerify{
+				ChallengeToken: login.ChallengeToken,
+				Code:           wrong,
+			}, testClient)
+			errs <- err
+		}()
+	}
+	start.Done()
+	done.Wait()
+	close(errs)
+
+	// Only the requests that got an attempt had their code checked. The
+	// others were turned away by the used up challenge or the lockout.
+	var evaluated int
+	for err := range errs {
+		switch {
+		case err == nil:
+			t.Fatal("VerifyTwoFactorLogin() accepted a wrong code")
+		case err.Error() == "invalid verification code", err.Error() == "too many failed attempts, please log in again":
+			evaluated++
+		case err.Error() == "invalid or expired 2FA challenge":
+		default:
+			var locked *LockedError
+			if !errors.As(err, &locked) {
+				t.Errorf("VerifyTwoFactorLogin() error = %v", err)
+			}
+		}
+	}
+	if evaluated == 0 || evaluated > maxTwoFactorAttempts {
+		t.Errorf("%d codes were checked, want 1 to %d", evaluated, maxTwoFactorAttempts)
+	}
+}
@@ -2,60 +2,112 @@ package service
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/razvan/library-app/internal/domain"
 	"github.com/razvan/library-app/internal/repository"
+	"github.com/razvan/library-app/pkg/validator"
 )
 
 type BookService struct {
-	bookRepo *repository.BookRepository
+	bookRepo     *repository.BookRepository
+	auditService *AuditService
 }
 
-func NewBookService(bookRepo *repository.BookRepository) *BookService {
-	return &BookService{bookRepo: bookRepo}
+func NewBookService(bookRepo *repository.BookRepository, auditService *AuditService) *BookService {
+	return &BookService{bookRepo: bookRepo, auditService: auditService}
 }
 
-func (s *BookService) CreateBook(req *domain.BookCreate) (*domain.Book, error) {
+func (s *BookService) CreateBook(actorUserID int64, req *domain.BookCreate, client domain.ClientInfo) (*domain.Book, error) {
 	publishedAt, err := time.Parse("2006-01-02", req.PublishedAt)
 	if err != nil {
 		return nil, fmt.Errorf("invalid date format, use YYYY-MM-DD")
 	}
 
+	isbn, err := normalizeISBN(req.ISBN)
+	if err != nil {
+		return nil, err
+	}
+
 	book := &domain.Book{
 		Title:       req.Title,
 		Description: req.Description,
-		ISBN:        req.ISBN,
+		ISBN:        isbn,
 		PublishedAt: publishedAt,
+		Language:    req.Language,
 	}
 
-	err = s.bookRepo.Create(book, req.AuthorIDs)
+	err = s.bookRepo.Create(book, req.AuthorIDs, req.GenreIDs, req.TagIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create book: %w", err)
 	}
 
-	return book, nil
+	// Read it back with its authors, genres and tags
+	created, err := s.bookRepo.GetByID(book.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditBookCreated,
+		TargetType:  domain.AuditTargetBook,
+		TargetID:    created.ID,
+		Client:      client,
+		After:       bookSnapshot(created),
+	})
+
+	return created, nil
 }
 
 func (s *BookService) GetBook(id int64) (*domain.Book, error) {
 	return s.bookRepo.GetByID(id)
 }
 
-func (s *BookService) GetAllBooks(page, pageSize int) ([]domain.Book, error) {
-	offset := (page - 1) * pageSize
-	return s.bookRepo.GetAll(pageSize, offset)
+// GetBookByISBN finds the book with an edition of the ISBN, given as an
+// ISBN-10 or ISBN-13.
+func (s *BookService) GetBookByISBN(isbn string) (*domain.Book, error) {
+	normalized, err := validator.NormalizeISBN(isbn)
+	if err != nil {
+		return nil, err
+	}
+	return s.bookRepo.GetByISBN(normalized)
 }
 
-func (s *BookService) SearchBooks(query string, page, pageSize int) ([]domain.Book, error) {
-	offset := (page - 1) * pageSize
-	return s.bookRepo.Search(query, pageSize, offset)
+// ListBooks returns a page of the books matching the filter, along with the
+// facet counts for the filter sidebar.
+func (s *BookService) ListBooks(filter domain.BookFilter, page domain.PageRequest) (*domain.BookPage, error) {
+	books, err := s.bookRepo.List(filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list books: %w", err)
+	}
+
+	facets, err := s.bookRepo.Facets(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count facets: %w", err)
+	}
+
+	return &domain.BookPage{Page: *books, Facets: *facets}, nil
+}
+
+// SearchBooks runs a ranked full-text search. Only when no book matches
+// does it fall back to a fuzzy search for misspelled titles and authors.
+func (s *BookService) SearchBooks(query string, page domain.PageRequest) (*domain.Page[domain.BookSearchResult], error) {
+	results, err := s.bookRepo.Search(query, page)
+	if err != nil || results.Total > 0 {
+		return results, err
+	}
+
+	return s.bookRepo.SearchFuzzy(query, page)
 }
 
-func (s *BookService) UpdateBook(id int64, req *domain.BookUpdate) (*domain.Book, error) {
+func (s *BookService) UpdateBook(actorUserID, id int64, req *domain.BookUpdate, client domain.ClientInfo) (*domain.Book, error) {
 	book, err := s.bookRepo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
+	before := bookSnapshot(book)
 
 	if req.Title != "" {
 		book.Title = req.Title
@@ -64,7 +116,9 @@ func (s *BookService) UpdateBook(id int64, req *domain.BookUpdate) (*domain.Book
 		book.Description = req.Description
 	}
 	if req.ISBN != "" {
-		book.ISBN = req.ISBN
+		if book.ISBN, err = normalizeISBN(req.ISBN); err != nil {
+			return nil, err
+		}
 	}
 	if req.PublishedAt != "" {
 		publishedAt, err := time.Parse("2006-01-02", req.PublishedAt)
@@ -73,31 +127,82 @@ func (s *BookService) UpdateBook(id int64, req *domain.BookUpdate) (*domain.Book
 		}
 		book.PublishedAt = publishedAt
 	}
+	if req.Language != "" {
+		book.Language = req.Language
+	}
 
-	err = s.bookRepo.Update(book, req.AuthorIDs)
+	err = s.bookRepo.Update(book, req.AuthorIDs, req.GenreIDs, req.TagIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update book: %w", err)
 	}
 
-	return book, nil
+	updated, err := s.bookRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditBookUpdated,
+		TargetType:  domain.AuditTargetBook,
+		TargetID:    id,
+		Client:      client,
+		Before:      before,
+		After:       bookSnapshot(updated),
+	})
+
+	return updated, nil
 }
 
-func (s *BookService) DeleteBook(id int64) error {
-	return s.bookRepo.Delete(id)
+func (s *BookService) DeleteBook(actorUserID, id int64, client domain.ClientInfo) error {
+	book, err := s.bookRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.bookRepo.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditBookDeleted,
+		TargetType:  domain.AuditTargetBook,
+		TargetID:    id,
+		Client:      client,
+		Before:      bookSnapshot(book),
+	})
+
+	return nil
 }
 
-func (s *BookService) UpdateBookCover(bookID int64, coverURL string) error {
+func (s *BookService) UpdateBookCover(actorUserID, bookID int64, coverURL string, client domain.ClientInfo) error {
 	book, err := s.bookRepo.GetByID(bookID)
 	if err != nil {
 		return err
 	}
+	previous := book.CoverURL
 
 	book.CoverURL = coverURL
-	return s.bookRepo.Update(book, nil)
+	if err := s.bookRepo.Update(book, nil, nil, nil); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditBookUpdated,
+		TargetType:  domain.AuditTargetBook,
+		TargetID:    bookID,
+		Client:      client,
+		Before:      map[string]string{"cover_url": previous},
+		After:       map[string]string{"cover_url": coverURL},
+	})
+
+	return nil
 }
 
 // Author methods
-func (s *BookService) CreateAuthor(req *domain.AuthorCreate) (*domain.Author, error) {
+func (s *BookService) CreateAuthor(actorUserID int64, req *domain.AuthorCreate, client domain.ClientInfo) (*domain.Author, error) {
 	author := &domain.Author{
 		Name: req.Name,
 		Bio:  req.Bio,
@@ -108,6 +213,15 @@ func (s *BookService) CreateAuthor(req *domain.AuthorCreate) (*domain.Author, er
 		return nil, fmt.Errorf("failed to create author: %w", err)
 	}
 
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditAuthorCreated,
+		TargetType:  domain.AuditTargetAuthor,
+		TargetID:    author.ID,
+		Client:      client,
+		After:       author,
+	})
+
 	return author, nil
 }
 
@@ -115,16 +229,16 @@ func (s *BookService) GetAuthor(id int64) (*domain.Author, error) {
 	return s.bookRepo.GetAuthorByID(id)
 }
 
-func (s *BookService) GetAllAuthors(page, pageSize int) ([]domain.Author, error) {
-	offset := (page - 1) * pageSize
-	return s.bookRepo.GetAllAuthors(pageSize, offset)
+func (s *BookService) GetAllAuthors(page domain.PageRequest) (*domain.Page[domain.Author], error) {
+	return s.bookRepo.GetAllAuthors(page)
 }
 
-func (s *BookService) UpdateAuthor(id int64, req *domain.AuthorCreate) (*domain.Author, error) {
+func (s *BookService) UpdateAuthor(actorUserID, id int64, req *domain.AuthorCreate, client domain.ClientInfo) (*domain.Author, error) {
 	author, err := s.bookRepo.GetAuthorByID(id)
 	if err != nil {
 		return nil, err
 	}
+	before := *author
 
 	author.Name = req.Name
 	author.Bio = req.Bio
@@ -134,9 +248,79 @@ func (s *BookService) UpdateAuthor(id int64, req *domain.AuthorCreate) (*domain.
 		return nil, fmt.Errorf("failed to update author: %w", err)
 	}
 
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditAuthorUpdated,
+		TargetType:  domain.AuditTargetAuthor,
+		TargetID:    id,
+		Client:      client,
+		Before:      &before,
+		After:       author,
+	})
+
 	return author, nil
 }
 
-func (s *BookService) DeleteAuthor(id int64) error {
-	return s.bookRepo.DeleteAuthor(id)
+func (s *BookService) DeleteAuthor(actorUserID, id int64, client domain.ClientInfo) error {
+	author, err := s.bookRepo.GetAuthorByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.bookRepo.DeleteAuthor(id); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditAuthorDeleted,
+		TargetType:  domain.AuditTargetAuthor,
+		TargetID:    id,
+		Client:      client,
+		Before:      author,
+	})
+
+	return nil
+}
+
+// bookSnapshot is the audited state of a book: its fields and the IDs of
+// its authors, genres and tags rather than the full objects.
+func bookSnapshot(book *domain.Book) map[string]interface{} {
+	authorIDs := []int64{}
+	for _, author := range book.Authors {
+		authorIDs = append(authorIDs, author.ID)
+	}
+	sort.Slice(authorIDs, func(i, j int) bool { return authorIDs[i] < authorIDs[j] })
+
+	genreIDs := []int64{}
+	for _, genre := range book.Genres {
+		genreIDs = append(genreIDs, genre.ID)
+	}
+	sort.Slice(genreIDs, func(i, j int) bool { return genreIDs[i] < genreIDs[j] })
+
+	tagIDs := []int64{}
+	for _, tag := range book.Tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	sort.Slice(tagIDs, func(i, j int) bool { return tagIDs[i] < tagIDs[j] })
+
+	return map[string]interface{}{
+		"title":        book.Title,
+		"description":  book.Description,
+		"isbn":         book.ISBN,
+		"cover_url":    book.CoverURL,
+		"published_at": book.PublishedAt.Format("2006-01-02"),
+		"language":     book.Language,
+		"author_ids":   authorIDs,
+		"genre_ids":    genreIDs,
+		"tag_ids":      tagIDs,
+	}
+}
+
+// normalizeISBN turns an optional ISBN-10 or ISBN-13 into a bare ISBN-13.
+func normalizeISBN(isbn string) (string, error) {
+	if isbn == "" {
+		return "", nil
+	}
+	return validator.NormalizeISBN(isbn)
 }
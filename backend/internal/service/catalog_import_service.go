@@ -0,0 +1,582 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+	"github.com/razvan/library-app/pkg/auth"
+	"github.com/razvan/library-app/pkg/catalog"
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+// importProgressInterval is the number of records between two saves of an
+// import's progress.
+const importProgressInterval = 200
+
+// CatalogImportService imports books from catalog files in the background.
+// Only one import runs at a time, so that two files with the same ISBN
+// cannot both create it.
+type CatalogImportService struct {
+	importRepo   *repository.CatalogImportRepository
+	bookRepo     *repository.BookRepository
+	editionRepo  *repository.EditionRepository
+	auditService *AuditService
+	importDir    string
+	slots        chan struct{}
+}
+
+func NewCatalogImportService(importRepo *repository.CatalogImportRepository, bookRepo *repository.BookRepository, editionRepo *repository.EditionRepository, auditService *AuditService, importDir string) *CatalogImportService {
+	return &CatalogImportService{
+		importRepo:   importRepo,
+		bookRepo:     bookRepo,
+		editionRepo:  editionRepo,
+		auditService: auditService,
+		importDir:    importDir,
+		slots:        make(chan struct{}, 1),
+	}
+}
+
+// StartImport saves the uploaded file and queues its import. A CSV file's
+// header is checked against the column mapping first, so that a wrong
+// mapping is reported before the job starts.
+func (s *CatalogImportService) StartImport(actorUserID int64, req *domain.CatalogImportRequest, sourceName string, file io.Reader) (*domain.CatalogImport, error) {
+	if err := os.MkdirAll(s.importDir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to save the file: %w", err)
+	}
+
+	imp := &domain.CatalogImport{
+		UserID:     &actorUserID,
+		Format:     req.Format,
+		DryRun:     req.DryRun,
+		SourceName: sourceName,
+		FileName:   auth.NewTokenID() + ".import",
+		Status:     domain.ImportPending,
+	}
+	if req.Format == catalog.FormatCSV {
+		imp.Mapping = req.Mapping
+	}
+
+	path := filepath.Join(s.importDir, imp.FileName)
+	if err := saveImportFile(path, file); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to save the file: %w", err)
+	}
+
+	if err := s.checkFile(imp); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	if err := s.importRepo.Create(imp); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to create import: %w", err)
+	}
+
+	go s.run(*imp)
+
+	return imp, nil
+}
+
+func saveImportFile(path string, file io.Reader) error {
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, file); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// checkFile opens a reader on the import's file, which fails for a CSV
+// header that does not match the mapping.
+func (s *CatalogImportService) checkFile(imp *domain.CatalogImport) error {
+	file, err := os.Open(filepath.Join(s.importDir, imp.FileName))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = catalog.NewReader(imp.Format, file, imp.Mapping)
+	return err
+}
+
+func (s *CatalogImportService) GetImports(page domain.PageRequest) (*domain.Page[domain.CatalogImport], error) {
+	return s.importRepo.List(page)
+}
+
+func (s *CatalogImportService) GetImport(id int64) (*domain.CatalogImport, error) {
+	return s.importRepo.GetByID(id)
+}
+
+// GetImportErrors lists the skipped records of an import in file order.
+func (s *CatalogImportService) GetImportErrors(id int64, page domain.PageRequest) (*domain.Page[domain.CatalogImportError], error) {
+	return s.importRepo.ListErrors(id, page)
+}
+
+// ResumeUnfinished restarts the imports that were interrupted, for example
+// by a restart of the server. They start over from the first record, which
+// is safe since records are matched by ISBN: those already imported are
+// counted as unchanged.
+func (s *CatalogImportService) ResumeUnfinished() error {
+	imports, err := s.importRepo.GetUnfinished()
+	if err != nil {
+		return err
+	}
+
+	for _, imp := range imports {
+		go s.run(imp)
+	}
+
+	return nil
+}
+
+func (s *CatalogImportService) run(imp domain.CatalogImport) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	path := filepath.Join(s.importDir, imp.FileName)
+	defer os.Remove(path)
+
+	// A malformed file must fail its import, not stop the server
+	var job *importJob
+	defer func() {
+		if r := recover(); r != nil {
+			reason := s.fail(&imp, fmt.Errorf("panic: %v\n%s", r, debug.Stack()))
+			if job != nil {
+				s.audit(&imp, job, reason)
+			}
+		}
+	}()
+
+	// Reading the whole file first gives the total for the progress, and
+	// a file that breaks off fails before anything is imported.
+	total, err := s.countRecords(path, &imp)
+	if err != nil {
+		s.fail(&imp, err)
+		return
+	}
+
+	if err := s.importRepo.MarkRunning(imp.ID, total); err != nil {
+		log.Printf("Failed to start import %d: %v", imp.ID, err)
+		return
+	}
+	// The counts of an interrupted run were cleared with it
+	imp.Total = total
+	imp.Processed, imp.Created, imp.Updated, imp.Unchanged, imp.Failed = 0, 0, 0, 0, 0
+
+	job = &importJob{
+		service: s,
+		imp:     &imp,
+		authors: make(map[string]int64),
+		seen:    make(map[string]bool),
+	}
+	err = job.run(path)
+
+	if err == nil {
+		err = s.importRepo.MarkCompleted(imp.ID)
+	}
+	var reason string
+	if err != nil {
+		reason = s.fail(&imp, err)
+	}
+	s.audit(&imp, job, reason)
+}
+
+// audit records what an import changed in the catalog. A failed import is
+// recorded as such, with the reason, since the records read before the
+// failure stay imported.
+func (s *CatalogImportService) audit(imp *domain.CatalogImport, job *importJob, reason string) {
+	if imp.DryRun {
+		return
+	}
+
+	action := domain.AuditCatalogImported
+	if reason != "" {
+		action = domain.AuditCatalogImportFailed
+	}
+
+	var actorUserID int64
+	if imp.UserID != nil {
+		actorUserID = *imp.UserID
+	}
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      action,
+		TargetType:  domain.AuditTargetImport,
+		TargetID:    imp.ID,
+		Reason:      reason,
+		After: map[string]interface{}{
+			"format":          imp.Format,
+			"source_name":     imp.SourceName,
+			"created":         imp.Created,
+			"updated":         imp.Updated,
+			"unchanged":       imp.Unchanged,
+			"failed":          imp.Failed,
+			"authors_created": job.authorsCreated,
+		},
+	})
+}
+
+// errUnreadableFile wraps the errors of a file that cannot be read to the
+// end, which are shown to the user as they are.
+var errUnreadableFile = errors.New("the file could not be read")
+
+func (s *CatalogImportService) countRecords(path string, imp *domain.CatalogImport) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, fmt.Errorf("%w: the uploaded file is missing, start the import again", errUnreadableFile)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader, err := catalog.NewReader(imp.Format, file, imp.Mapping)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errUnreadableFile, err)
+	}
+
+	total := 0
+	for {
+		_, err := reader.Read()
+		if err == io.EOF {
+			return total, nil
+		}
+		var recordErr *catalog.RecordError
+		if err != nil && !errors.As(err, &recordErr) {
+			return 0, fmt.Errorf("%w: %v", errUnreadableFile, err)
+		}
+		total++
+	}
+}
+
+// fail marks the import as failed and returns the reason it shows. Errors
+// other than an unreadable file are logged rather than shown.
+func (s *CatalogImportService) fail(imp *domain.CatalogImport, err error) string {
+	reason := "the import stopped because of a server error, please run it again"
+	if errors.Is(err, errUnreadableFile) {
+		reason = err.Error()
+	} else {
+		log.Printf("Import %d failed: %v", imp.ID, err)
+	}
+
+	if err := s.importRepo.MarkFailed(imp.ID, reason); err != nil {
+		log.Printf("Failed to update import %d: %v", imp.ID, err)
+	}
+	return reason
+}
+
+// importJob is one run of an import.
+type importJob struct {
+	service *CatalogImportService
+	imp     *domain.CatalogImport
+	// authors caches author IDs by lowercase name
+	authors        map[string]int64
+	authorsCreated int
+	// seen holds the ISBNs a dry run would have created
+	seen   map[string]bool
+	errors []domain.CatalogImportError
+}
+
+type importOutcome int
+
+const (
+	importCreated importOutcome = iota
+	importUpdated
+	importUnchanged
+)
+
+func (j *importJob) run(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := catalog.NewReader(j.imp.Format, file, j.imp.Mapping)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUnreadableFile, err)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var recordErr *catalog.RecordError
+		switch {
+		case errors.As(err, &recordErr):
+			j.skip(recordErr.Number, recordErr.ISBN, recordErr.Err)
+		case err != nil:
+			return fmt.Errorf("%w: %v", errUnreadableFile, err)
+		default:
+			if err := j.importRecord(record); err != nil {
+				return err
+			}
+		}
+
+		j.imp.Processed++
+		if j.imp.Processed%importProgressInterval == 0 {
+			if err := j.saveProgress(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return j.saveProgress()
+}
+
+func (j *importJob) saveProgress() error {
+	if err := j.service.importRepo.UpdateProgress(j.imp, j.errors); err != nil {
+		return err
+	}
+	j.errors = j.errors[:0]
+	return nil
+}
+
+func (j *importJob) skip(number int, isbn string, err error) {
+	j.imp.Failed++
+	j.errors = append(j.errors, domain.CatalogImportError{
+		ImportID: j.imp.ID,
+		Record:   number,
+		ISBN:     isbn,
+		Message:  err.Error(),
+	})
+}
+
+// importRecord adds the record's edition, or updates it when its ISBN is
+// already in the catalog. Only a book's primary edition updates the book's
+// title, description and authors, so that importing a translation does not
+// rename the work. Fields the record does not have are left as they are.
+func (j *importJob) importRecord(record *catalog.Record) error {
+	edition, err := validateImportRecord(record)
+	if err != nil {
+		j.skip(record.Number, record.ISBN, err)
+		return nil
+	}
+
+	outcome, err := j.save(record, edition)
+	if err != nil {
+		return err
+	}
+
+	switch outcome {
+	case importCreated:
+		j.imp.Created++
+	case importUpdated:
+		j.imp.Updated++
+	default:
+		j.imp.Unchanged++
+	}
+	return nil
+}
+
+func (j *importJob) save(record *catalog.Record, edition *domain.Edition) (importOutcome, error) {
+	s := j.service
+	existing, err := s.editionRepo.FindByISBN(edition.ISBN)
+	if err != nil {
+		return 0, err
+	}
+
+	if existing == nil {
+		if j.imp.DryRun {
+			if j.seen[edition.ISBN] {
+				return importUpdated, nil
+			}
+			j.seen[edition.ISBN] = true
+			return importCreated, nil
+		}
+
+		authorIDs, err := j.authorIDs(record.Authors)
+		if err != nil {
+			return 0, err
+		}
+
+		book := &domain.Book{Title: record.Title, Description: record.Description}
+		if err := s.bookRepo.SaveImported(book, edition, authorIDs); err != nil {
+			return 0, err
+		}
+		return importCreated, nil
+	}
+
+	book, err := s.bookRepo.GetByID(existing.BookID)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := *existing
+	mergeImportedEdition(&updated, edition)
+	changed := !sameEdition(existing, &updated)
+
+	var authorIDs []int64
+	if len(book.Editions) > 0 && book.Editions[0].ID == existing.ID {
+		if record.Title != book.Title {
+			book.Title, changed = record.Title, true
+		}
+		if record.Description != "" && record.Description != book.Description {
+			book.Description, changed = record.Description, true
+		}
+
+		if !sameAuthors(book.Authors, record.Authors) {
+			changed = true
+			if !j.imp.DryRun {
+				if authorIDs, err = j.authorIDs(record.Authors); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+
+	if !changed {
+		return importUnchanged, nil
+	}
+	if j.imp.DryRun {
+		return importUpdated, nil
+	}
+
+	if err := s.bookRepo.SaveImported(book, &updated, authorIDs); err != nil {
+		return 0, err
+	}
+	return importUpdated, nil
+}
+
+// authorIDs matches the names to authors, ignoring case, and creates the
+// authors that do not exist yet.
+func (j *importJob) authorIDs(names []string) ([]int64, error) {
+	var ids []int64
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if id, ok := j.authors[key]; ok {
+			ids = append(ids, id)
+			continue
+		}
+
+		author, err := j.service.bookRepo.FindAuthorByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if author == nil {
+			author = &domain.Author{Name: name}
+			if err := j.service.bookRepo.CreateAuthor(author); err != nil {
+				return nil, err
+			}
+			j.authorsCreated++
+		}
+
+		j.authors[key] = author.ID
+		ids = append(ids, author.ID)
+	}
+	return ids, nil
+}
+
+// importFieldNames names the fields of an edition in record errors.
+var importFieldNames = map[string]string{
+	"ISBN":        "ISBN",
+	"Publisher":   "publisher",
+	"Format":      "format",
+	"Language":    "language",
+	"PageCount":   "page count",
+	"PublishedAt": "publication date",
+}
+
+// validateImportRecord checks a record with the rules of the book and
+// edition forms, and returns its edition.
+func validateImportRecord(record *catalog.Record) (*domain.Edition, error) {
+	switch {
+	case record.ISBN == "":
+		return nil, errors.New("the ISBN is missing")
+	case record.Title == "":
+		return nil, errors.New("the title is missing")
+	case utf8.RuneCountInString(record.Title) > 255:
+		return nil, errors.New("the title is longer than 255 characters")
+	case len(record.Authors) == 0:
+		return nil, errors.New("the authors are missing")
+	}
+	for _, author := range record.Authors {
+		if utf8.RuneCountInString(author) > 255 {
+			return nil, fmt.Errorf("the author %q is longer than 255 characters", author)
+		}
+	}
+
+	req := &domain.EditionCreate{
+		ISBN:        record.ISBN,
+		Publisher:   record.Publisher,
+		Format:      record.Format,
+		Language:    record.Language,
+		PageCount:   record.PageCount,
+		PublishedAt: record.PublishedAt,
+	}
+	if err := validator.Validate(req); err != nil {
+		if fields := validator.InvalidFields(err); len(fields) > 0 {
+			return nil, fmt.Errorf("invalid %s", importFieldNames[fields[0]])
+		}
+		return nil, err
+	}
+
+	edition := &domain.Edition{}
+	if err := applyEdition(edition, req); err != nil {
+		return nil, err
+	}
+	return edition, nil
+}
+
+// mergeImportedEdition copies the fields the import has onto the edition.
+func mergeImportedEdition(edition, imported *domain.Edition) {
+	if imported.Publisher != "" {
+		edition.Publisher = imported.Publisher
+	}
+	if imported.Format != "" {
+		edition.Format = imported.Format
+	}
+	if imported.Language != "" {
+		edition.Language = imported.Language
+	}
+	if imported.PageCount > 0 {
+		edition.PageCount = imported.PageCount
+	}
+	if imported.PublishedAt != nil {
+		edition.PublishedAt = imported.PublishedAt
+	}
+}
+
+func sameEdition(a, b *domain.Edition) bool {
+	samePublishedAt := a.PublishedAt == nil && b.PublishedAt == nil ||
+		a.PublishedAt != nil && b.PublishedAt != nil && a.PublishedAt.Equal(*b.PublishedAt)
+
+	return samePublishedAt &&
+		a.Publisher == b.Publisher &&
+		a.Format == b.Format &&
+		a.Language == b.Language &&
+		a.PageCount == b.PageCount
+}
+
+// sameAuthors tells whether the book is by exactly the named authors,
+// ignoring case and order.
+func sameAuthors(authors []domain.Author, names []string) bool {
+	current := make(map[string]bool, len(authors))
+	for _, author := range authors {
+		current[strings.ToLower(author.Name)] = true
+	}
+
+	imported := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !current[strings.ToLower(name)] {
+			return false
+		}
+		imported[strings.ToLower(name)] = true
+	}
+	return len(imported) == len(current)
+}
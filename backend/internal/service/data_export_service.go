@@ -0,0 +1,473 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+	"github.com/razvan/library-app/pkg/auth"
+	"github.com/razvan/library-app/pkg/mailer"
+)
+
+// maxConcurrentExports limits how many archives are built at the same time.
+const maxConcurrentExports = 2
+
+// DataExportService builds ZIP archives of everything stored about a user.
+// Archives are built in the background and can be downloaded until they
+// expire, after which they are deleted.
+type DataExportService struct {
+	exportRepo   *repository.DataExportRepository
+	userRepo     *repository.UserRepository
+	userBookRepo *repository.UserBookRepository
+	sessionRepo  *repository.SessionRepository
+	identityRepo *repository.IdentityRepository
+	webAuthnRepo *repository.WebAuthnRepository
+	apiKeyRepo   *repository.APIKeyRepository
+	auditRepo    *repository.AuditRepository
+	mailer       mailer.Mailer
+	exportDir    string
+	ttl          time.Duration
+	appName      string
+	frontendURL  string
+	slots        chan struct{}
+}
+
+func NewDataExportService(exportRepo *repository.DataExportRepository, userRepo *repository.UserRepository, userBookRepo *repository.UserBookRepository, sessionRepo *repository.SessionRepository, identityRepo *repository.IdentityRepository, webAuthnRepo *repository.WebAuthnRepository, apiKeyRepo *repository.APIKeyRepository, auditRepo *repository.AuditRepository, m mailer.Mailer, exportDir string, ttl time.Duration, appName, frontendURL string) *DataExportService {
+	return &DataExportService{
+		exportRepo:   exportRepo,
+		userRepo:     userRepo,
+		userBookRepo: userBookRepo,
+		sessionRepo:  sessionRepo,
+		identityRepo: identityRepo,
+		webAuthnRepo: webAuthnRepo,
+		apiKeyRepo:   apiKeyRepo,
+		auditRepo:    auditRepo,
+		mailer:       m,
+		exportDir:    exportDir,
+		ttl:          ttl,
+		appName:      appName,
+		frontendURL:  frontendURL,
+		slots:        make(chan struct{}, maxConcurrentExports),
+	}
+}
+
+// RequestExport queues a new export. A user can only have one export in
+// progress at a time.
+func (s *DataExportService) RequestExport(userID int64) (*domain.DataExport, error) {
+	busy, err := s.exportRepo.HasUnfinished(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check exports: %w", err)
+	}
+	if busy {
+		return nil, fmt.Errorf("an export is already being prepared")
+	}
+
+	export := &domain.DataExport{
+		UserID:   userID,
+		Status:   domain.ExportPending,
+		FileName: auth.NewTokenID() + ".zip",
+	}
+	if err := s.exportRepo.Create(export); err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+
+	go s.build(*export)
+
+	return export, nil
+}
+
+func (s *DataExportService) GetExports(userID int64) ([]domain.DataExport, error) {
+	exports, err := s.exportRepo.GetByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exports: %w", err)
+	}
+	return exports, nil
+}
+
+// OpenExport opens the archive of a finished export of the user. The caller
+// must close the file.
+func (s *DataExportService) OpenExport(userID, exportID int64) (*os.File, *domain.DataExport, error) {
+	export, err := s.exportRepo.GetByID(userID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if export.Status != domain.ExportReady {
+		return nil, nil, fmt.Errorf("export is not ready")
+	}
+	if export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt) {
+		return nil, nil, fmt.Errorf("export has expired")
+	}
+
+	file, err := os.Open(filepath.Join(s.exportDir, export.FileName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("export file is missing")
+	}
+
+	return file, export, nil
+}
+
+// ResumeUnfinished restarts the exports that were interrupted, for example
+// by a restart of the server.
+func (s *DataExportService) ResumeUnfinished() error {
+	exports, err := s.exportRepo.GetUnfinished()
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		go s.build(export)
+	}
+
+	return nil
+}
+
+// CleanUp deletes expired exports and archives that no longer belong to an
+// export, such as those of deleted users. It returns the number of files
+// removed.
+func (s *DataExportService) CleanUp() (int, error) {
+	if _, err := s.exportRepo.DeleteExpired(); err != nil {
+		return 0, fmt.Errorf("failed to delete expired exports: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(s.exportDir, "*.zip"))
+	if err != nil || len(paths) == 0 {
+		return 0, err
+	}
+
+	fileNames := make([]string, len(paths))
+	for i, path := range paths {
+		fileNames[i] = filepath.Base(path)
+	}
+
+	known, err := s.exportRepo.FilterKnown(fileNames)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check export files: %w", err)
+	}
+
+	removed := 0
+	for i, fileName := range fileNames {
+		if known[fileName] {
+			continue
+		}
+		if err := os.Remove(paths[i]); err == nil {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+func (s *DataExportService) build(export domain.DataExport) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	if err := s.exportRepo.MarkRunning(export.ID); err != nil {
+		log.Printf("Failed to start export %d: %v", export.ID, err)
+		return
+	}
+
+	expiresAt := time.Now().Add(s.ttl)
+
+	user, size, err := s.writeFile(export)
+	if err != nil {
+		log.Printf("Export %d of user %d failed: %v", export.ID, export.UserID, err)
+		if err := s.exportRepo.MarkFailed(export.ID, "the export could not be created, please try again", expiresAt); err != nil {
+			log.Printf("Failed to update export %d: %v", export.ID, err)
+		}
+		return
+	}
+
+	if err := s.exportRepo.MarkReady(export.ID, size, expiresAt); err != nil {
+		log.Printf("Failed to update export %d: %v", export.ID, err)
+		return
+	}
+
+	err = s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your " + s.appName + " data export is ready",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nThe export of your data is ready. Download it from your profile before %s:\n\n%s\n\nAfter that it is deleted.\n",
+			user.Username, expiresAt.Format("January 2, 2006 15:04 MST"), s.frontendURL+"/profile",
+		),
+	})
+	if err != nil {
+		log.Printf("Failed to send export email to user %d: %v", user.ID, err)
+	}
+}
+
+// writeFile writes the archive to a temporary file first, so a download
+// never sees a partial archive.
+func (s *DataExportService) writeFile(export domain.DataExport) (*domain.User, int64, error) {
+	user, err := s.userRepo.GetByID(export.UserID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := os.MkdirAll(s.exportDir, 0750); err != nil {
+		return nil, 0, err
+	}
+
+	path := filepath.Join(s.exportDir, export.FileName)
+	file, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer os.Remove(path + ".tmp")
+
+	if err := s.writeArchive(file, user); err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, 0, err
+	}
+
+	return user, info.Size(), nil
+}
+
+// exportAccount is account.json: the profile and how the user logs in. No
+// secrets are included, only what identifies them.
+type exportAccount struct {
+	ExportedAt   time.Time                   `json:"exported_at"`
+	Profile      *domain.User                `json:"profile"`
+	Identities   []domain.UserIdentity       `json:"identities"`
+	SecurityKeys []domain.WebAuthnCredential `json:"security_keys"`
+	APIKeys      []domain.APIKey             `json:"api_keys"`
+}
+
+func (s *DataExportService) writeArchive(w io.Writer, user *domain.User) error {
+	account := exportAccount{ExportedAt: time.Now().UTC(), Profile: user}
+
+	var err error
+	if account.Identities, err = s.identityRepo.GetByUser(user.ID); err != nil {
+		return err
+	}
+	if account.SecurityKeys, err = s.webAuthnRepo.GetUserCredentials(user.ID); err != nil {
+		return err
+	}
+	if account.APIKeys, err = s.apiKeyRepo.GetByUser(user.ID); err != nil {
+		return err
+	}
+
+	readingList, err := allPages(func(page domain.PageRequest) (*domain.Page[domain.UserBook], error) {
+		return s.userBookRepo.GetUserBooks(user.ID, "", page)
+	})
+	if err != nil {
+		return err
+	}
+	favorites, err := allPages(func(page domain.PageRequest) (*domain.Page[domain.Favorite], error) {
+		return s.userBookRepo.GetUserFavorites(user.ID, page)
+	})
+	if err != nil {
+		return err
+	}
+	comments, err := s.userBookRepo.GetUserComments(user.ID)
+	if err != nil {
+		return err
+	}
+	ratings, err := s.userBookRepo.GetUserRatings(user.ID)
+	if err != nil {
+		return err
+	}
+	sessions, err := s.sessionRepo.GetAllByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	auditEvents, err := s.auditRepo.GetByActor(user.ID)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+
+	if err := writeJSONFile(zw, "account.json", account); err != nil {
+		return err
+	}
+
+	readingListRows := make([][]string, 0, len(readingList))
+	for _, entry := range readingList {
+		readingListRows = append(readingListRows, []string{
+			strconv.FormatInt(entry.BookID, 10), bookTitle(entry.Book), bookISBN(entry.Book),
+			string(entry.Status), formatTime(&entry.CreatedAt), formatTime(&entry.UpdatedAt),
+		})
+	}
+	if err := writeDataFiles(zw, "reading_list", readingList,
+		[]string{"book_id", "title", "isbn", "status", "added_at", "updated_at"}, readingListRows); err != nil {
+		return err
+	}
+
+	favoriteRows := make([][]string, 0, len(favorites))
+	for _, favorite := range favorites {
+		favoriteRows = append(favoriteRows, []string{
+			strconv.FormatInt(favorite.BookID, 10), bookTitle(favorite.Book), bookISBN(favorite.Book),
+			formatTime(&favorite.CreatedAt),
+		})
+	}
+	if err := writeDataFiles(zw, "favorites", favorites,
+		[]string{"book_id", "title", "isbn", "added_at"}, favoriteRows); err != nil {
+		return err
+	}
+
+	commentRows := make([][]string, 0, len(comments))
+	for _, comment := range comments {
+		commentRows = append(commentRows, []string{
+			strconv.FormatInt(comment.ID, 10), strconv.FormatInt(comment.BookID, 10), comment.BookTitle,
+			comment.Content, formatTime(&comment.CreatedAt), formatTime(&comment.UpdatedAt),
+		})
+	}
+	if err := writeDataFiles(zw, "comments", comments,
+		[]string{"id", "book_id", "book_title", "content", "created_at", "updated_at"}, commentRows); err != nil {
+		return err
+	}
+
+	ratingRows := make([][]string, 0, len(ratings))
+	for _, rating := range ratings {
+		ratingRows = append(ratingRows, []string{
+			strconv.FormatInt(rating.BookID, 10), rating.BookTitle, strconv.Itoa(rating.Rating),
+			formatTime(&rating.CreatedAt), formatTime(&rating.UpdatedAt),
+		})
+	}
+	if err := writeDataFiles(zw, "ratings", ratings,
+		[]string{"book_id", "book_title", "rating", "created_at", "updated_at"}, ratingRows); err != nil {
+		return err
+	}
+
+	sessionRows := make([][]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionRows = append(sessionRows, []string{
+			session.ID, session.UserAgent, session.IPAddress,
+			formatTime(&session.CreatedAt), formatTime(&session.LastSeenAt), formatTime(session.RevokedAt),
+		})
+	}
+	if err := writeDataFiles(zw, "sessions", sessions,
+		[]string{"id", "user_agent", "ip_address", "created_at", "last_seen_at", "revoked_at"}, sessionRows); err != nil {
+		return err
+	}
+
+	auditRows := make([][]string, 0, len(auditEvents))
+	for _, event := range auditEvents {
+		targetID := ""
+		if event.TargetID != nil {
+			targetID = strconv.FormatInt(*event.TargetID, 10)
+		}
+		auditRows = append(auditRows, []string{
+			strconv.FormatInt(event.ID, 10), event.Action, event.TargetType, targetID,
+			event.IPAddress, event.UserAgent, formatTime(&event.CreatedAt),
+		})
+	}
+	if err := writeDataFiles(zw, "audit_events", auditEvents,
+		[]string{"id", "action", "target_type", "target_id", "ip_address", "user_agent", "created_at"}, auditRows); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writeDataFiles writes a collection both as <name>.json and as <name>.csv.
+func writeDataFiles(zw *zip.Writer, name string, data interface{}, header []string, rows [][]string) error {
+	if err := writeJSONFile(zw, name+".json", data); err != nil {
+		return err
+	}
+
+	f, err := zw.Create(name + ".csv")
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(f)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for i, value := range row {
+			row[i] = csvSafe(value)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// allPages reads every page of a list, following the next cursors.
+func allPages[T any](list func(domain.PageRequest) (*domain.Page[T], error)) ([]T, error) {
+	items := []T{}
+	page := domain.PageRequest{Size: domain.MaxPageSize}
+	for {
+		result, err := list(page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Items...)
+
+		if result.NextCursor == "" {
+			return items, nil
+		}
+		if page.Cursor, err = domain.DecodeCursor(result.NextCursor); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func writeJSONFile(zw *zip.Writer, name string, data interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// csvSafe stops spreadsheets from running values that look like formulas.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func bookTitle(book *domain.Book) string {
+	if book == nil {
+		return ""
+	}
+	return book.Title
+}
+
+func bookISBN(book *domain.Book) string {
+	if book == nil {
+		return ""
+	}
+	return book.ISBN
+}
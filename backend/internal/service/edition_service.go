@@ -0,0 +1,171 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+)
+
+type EditionService struct {
+	editionRepo  *repository.EditionRepository
+	bookRepo     *repository.BookRepository
+	auditService *AuditService
+}
+
+func NewEditionService(editionRepo *repository.EditionRepository, bookRepo *repository.BookRepository, auditService *AuditService) *EditionService {
+	return &EditionService{editionRepo: editionRepo, bookRepo: bookRepo, auditService: auditService}
+}
+
+func (s *EditionService) CreateEdition(actorUserID, bookID int64, req *domain.EditionCreate, client domain.ClientInfo) (*domain.Edition, error) {
+	if _, err := s.bookRepo.GetByID(bookID); err != nil {
+		return nil, err
+	}
+
+	edition := &domain.Edition{BookID: bookID}
+	if err := applyEdition(edition, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.editionRepo.Create(edition); err != nil {
+		return nil, fmt.Errorf("failed to create edition: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditEditionCreated,
+		TargetType:  domain.AuditTargetEdition,
+		TargetID:    edition.ID,
+		Client:      client,
+		After:       edition,
+	})
+
+	return edition, nil
+}
+
+func (s *EditionService) GetEdition(id int64) (*domain.Edition, error) {
+	return s.editionRepo.GetByID(id)
+}
+
+func (s *EditionService) GetBookEditions(bookID int64) ([]domain.Edition, error) {
+	if _, err := s.bookRepo.GetByID(bookID); err != nil {
+		return nil, err
+	}
+	return s.bookRepo.GetBookEditions(bookID)
+}
+
+// GetLegacyISBNEditions lists the editions whose ISBN has to be corrected.
+func (s *EditionService) GetLegacyISBNEditions(page domain.PageRequest) (*domain.Page[domain.Edition], error) {
+	return s.editionRepo.ListLegacyISBN(page)
+}
+
+// UpdateEdition replaces the edition's fields, keeping its cover.
+func (s *EditionService) UpdateEdition(actorUserID, id int64, req *domain.EditionCreate, client domain.ClientInfo) (*domain.Edition, error) {
+	edition, err := s.editionRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := *edition
+
+	if err := applyEdition(edition, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.editionRepo.Update(edition); err != nil {
+		return nil, fmt.Errorf("failed to update edition: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditEditionUpdated,
+		TargetType:  domain.AuditTargetEdition,
+		TargetID:    id,
+		Client:      client,
+		Before:      &before,
+		After:       edition,
+	})
+
+	return edition, nil
+}
+
+// DeleteEdition deletes an edition. A book keeps at least one, and when its
+// primary edition goes the next one takes its place.
+func (s *EditionService) DeleteEdition(actorUserID, id int64, client domain.ClientInfo) error {
+	edition, err := s.editionRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	editions, err := s.bookRepo.GetBookEditions(edition.BookID)
+	if err != nil {
+		return err
+	}
+	if len(editions) <= 1 {
+		return fmt.Errorf("cannot delete the only edition of a book")
+	}
+
+	if err := s.editionRepo.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditEditionDeleted,
+		TargetType:  domain.AuditTargetEdition,
+		TargetID:    id,
+		Client:      client,
+		Before:      edition,
+	})
+
+	return nil
+}
+
+func (s *EditionService) UpdateEditionCover(actorUserID, id int64, coverURL string, client domain.ClientInfo) error {
+	edition, err := s.editionRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	previous := edition.CoverURL
+
+	edition.CoverURL = coverURL
+	if err := s.editionRepo.Update(edition); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditEditionUpdated,
+		TargetType:  domain.AuditTargetEdition,
+		TargetID:    id,
+		Client:      client,
+		Before:      map[string]string{"cover_url": previous},
+		After:       map[string]string{"cover_url": coverURL},
+	})
+
+	return nil
+}
+
+// applyEdition copies the request onto the edition.
+func applyEdition(edition *domain.Edition, req *domain.EditionCreate) error {
+	edition.PublishedAt = nil
+	if req.PublishedAt != "" {
+		publishedAt, err := time.Parse("2006-01-02", req.PublishedAt)
+		if err != nil {
+			return fmt.Errorf("invalid date format, use YYYY-MM-DD")
+		}
+		edition.PublishedAt = &publishedAt
+	}
+
+	isbn, err := normalizeISBN(req.ISBN)
+	if err != nil {
+		return err
+	}
+
+	edition.ISBN = isbn
+	edition.Publisher = req.Publisher
+	edition.Format = req.Format
+	edition.Language = req.Language
+	edition.PageCount = req.PageCount
+	return nil
+}
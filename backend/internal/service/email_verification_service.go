@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+	"github.com/razvan/library-app/pkg/auth"
+	"github.com/razvan/library-app/pkg/mailer"
+)
+
+const verificationResendCooldown = time.Minute
+
+var errVerificationThrottled = errors.New("a verification email was sent recently, please wait before requesting another")
+
+// EmailVerificationPolicy decides what unverified accounts are allowed to do.
+type EmailVerificationPolicy string
+
+const (
+	// EmailVerificationOptional lets unverified accounts do everything.
+	EmailVerificationOptional EmailVerificationPolicy = "off"
+	// EmailVerificationForWrites blocks writes such as commenting.
+	EmailVerificationForWrites EmailVerificationPolicy = "write"
+	// EmailVerificationForLogin blocks logging in altogether.
+	EmailVerificationForLogin EmailVerificationPolicy = "login"
+)
+
+func ParseEmailVerificationPolicy(value string) (EmailVerificationPolicy, error) {
+	switch policy := EmailVerificationPolicy(value); policy {
+	case EmailVerificationOptional, EmailVerificationForWrites, EmailVerificationForLogin:
+		return policy, nil
+	case "":
+		return EmailVerificationOptional, nil
+	default:
+		return "", fmt.Errorf("unknown email verification policy %q", value)
+	}
+}
+
+type EmailVerificationService struct {
+	userRepo    *repository.UserRepository
+	mailer      mailer.Mailer
+	policy      EmailVerificationPolicy
+	jwtSecret   string
+	appName     string
+	frontendURL string
+}
+
+func NewEmailVerificationService(userRepo *repository.UserRepository, m mailer.Mailer, policy EmailVerificationPolicy, jwtSecret, appName, frontendURL string) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:    userRepo,
+		mailer:      m,
+		policy:      policy,
+		jwtSecret:   jwtSecret,
+		appName:     appName,
+		frontendURL: frontendURL,
+	}
+}
+
+// SendVerification emails the user a signed verification link. At most one
+// email is sent per cooldown period.
+func (s *EmailVerificationService) SendVerification(user *domain.User) error {
+	return s.sendLink(user, user.Email, "If you did not create an account, you can ignore this email.")
+}
+
+// SendEmailChange asks the user to confirm their new address. Their email
+// only changes once they open the link sent there.
+func (s *EmailVerificationService) SendEmailChange(user *domain.User, newEmail string) error {
+	return s.sendLink(user, newEmail, "Your address changes once you open this link. If you did not ask for this, you can ignore this email.")
+}
+
+func (s *EmailVerificationService) sendLink(user *domain.User, email, footer string) error {
+	claimed, err := s.userRepo.ClaimVerificationSend(user.ID, verificationResendCooldown)
+	if err != nil {
+		return fmt.Errorf("failed to record verification email: %w", err)
+	}
+	if !claimed {
+		return errVerificationThrottled
+	}
+
+	token, err := auth.GenerateEmailVerificationToken(user.ID, email, s.jwtSecret)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	link := s.frontendURL + "/verify-email?token=" + url.QueryEscape(token)
+	return s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Verify your " + s.appName + " email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %d hours. %s\n",
+			user.Username, link, int(auth.EmailVerificationTokenTTL.Hours()), footer,
+		),
+	})
+}
+
+// ResendVerification sends a new verification email. Unknown and already
+// verified addresses, as well as throttled requests, are ignored silently so
+// the response does not reveal which emails have accounts.
+func (s *EmailVerificationService) ResendVerification(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil || user.EmailVerified {
+		return nil
+	}
+
+	err = s.SendVerification(user)
+	if errors.Is(err, errVerificationThrottled) {
+		return nil
+	}
+	return err
+}
+
+func (s *EmailVerificationService) VerifyEmail(token string) error {
+	claims, err := auth.ValidateEmailVerificationToken(token, s.jwtSecret)
+	if err != nil {
+		return fmt.Errorf("invalid or expired verification token")
+	}
+
+	verified, err := s.userRepo.MarkEmailVerified(claims.UserID, claims.Email)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	if verified {
+		return nil
+	}
+
+	// Not the current address, so it may confirm a pending change
+	changed, err := s.userRepo.ConfirmEmailChange(claims.UserID, claims.Email)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return fmt.Errorf("invalid or expired verification token")
+	}
+
+	return nil
+}
+
+// CheckLogin rejects logins from unverified accounts when the policy
+// requires it.
+func (s *EmailVerificationService) CheckLogin(user *domain.User) error {
+	if s.policy == EmailVerificationForLogin && !user.EmailVerified {
+		return fmt.Errorf("email address not verified")
+	}
+	return nil
+}
+
+// CheckWrite rejects writes from unverified accounts when the policy
+// requires it.
+func (s *EmailVerificationService) CheckWrite(userID int64) error {
+	if s.policy == EmailVerificationOptional {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if !user.EmailVerified {
+		return fmt.Errorf("please verify your email address first")
+	}
+
+	return nil
+}
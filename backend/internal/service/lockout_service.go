@@ -0,0 +1,158 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+)
+
+// LockoutPolicy configures brute-force protection. Once an account or a
+// client IP reaches its threshold of failed attempts within Window, every
+// further failure locks it for an exponentially growing duration, starting
+// at BaseLockout and capped at MaxLockout.
+type LockoutPolicy struct {
+	AccountThreshold int
+	IPThreshold      int
+	Window           time.Duration
+	BaseLockout      time.Duration
+	MaxLockout       time.Duration
+}
+
+// LockedError is returned while an account or client IP is locked out.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed attempts, try again in %d seconds", e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds is the value for the Retry-After header.
+func (e *LockedError) RetryAfterSeconds() int {
+	return int(math.Max(1, math.Ceil(time.Until(e.Until).Seconds())))
+}
+
+type LockoutService struct {
+	lockoutRepo *repository.LockoutRepository
+	policy      LockoutPolicy
+}
+
+func NewLockoutService(lockoutRepo *repository.LockoutRepository, policy LockoutPolicy) *LockoutService {
+	return &LockoutService{
+		lockoutRepo: lockoutRepo,
+		policy:      policy,
+	}
+}
+
+// Check returns a *LockedError if the account or the client IP is locked.
+// Pass a zero userID when the account is unknown.
+func (s *LockoutService) Check(userID int64, ip string) error {
+	var until time.Time
+	for _, key := range lockoutKeys(userID, ip) {
+		lockedUntil, err := s.lockoutRepo.GetLockedUntil(key)
+		if err != nil {
+			return fmt.Errorf("failed to check lockout: %w", err)
+		}
+		if lockedUntil != nil && lockedUntil.After(until) {
+			until = *lockedUntil
+		}
+	}
+
+	if !until.IsZero() {
+		return &LockedError{Until: until}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt against the account and the client
+// IP, locking either of them once it is over its threshold.
+func (s *LockoutService) RecordFailure(userID int64, ip, event string) {
+	s.recordEvent(userID, ip, event)
+
+	if userID != 0 {
+		s.recordFailure(accountKey(userID), s.policy.AccountThreshold, userID, ip, domain.LoginEventAccountLocked)
+	}
+	if ip != "" {
+		s.recordFailure(ipKey(ip), s.policy.IPThreshold, userID, ip, domain.LoginEventIPLocked)
+	}
+}
+
+// RecordSuccess resets the failure counter of the account. The IP counter
+// is left alone, so logging into one account does not reset the attempts
+// made against others.
+func (s *LockoutService) RecordSuccess(userID int64) {
+	if err := s.lockoutRepo.Clear(accountKey(userID)); err != nil {
+		log.Printf("Failed to reset lockout of user %d: %v", userID, err)
+	}
+}
+
+// Unlock lifts the lockout of an account and resets its failure counter.
+func (s *LockoutService) Unlock(userID int64, client domain.ClientInfo) error {
+	if err := s.lockoutRepo.Clear(accountKey(userID)); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+
+	s.recordEvent(userID, client.IPAddress, domain.LoginEventAccountUnlocked)
+	return nil
+}
+
+func (s *LockoutService) recordFailure(key string, threshold int, userID int64, ip, lockEvent string) {
+	failures, err := s.lockoutRepo.RecordFailure(key, s.policy.Window)
+	if err != nil {
+		log.Printf("Failed to record login failure for %s: %v", key, err)
+		return
+	}
+	if threshold <= 0 || failures < threshold {
+		return
+	}
+
+	if err := s.lockoutRepo.Lock(key, time.Now().Add(s.lockoutDuration(failures-threshold))); err != nil {
+		log.Printf("Failed to lock %s: %v", key, err)
+		return
+	}
+
+	s.recordEvent(userID, ip, lockEvent)
+}
+
+// lockoutDuration doubles the lockout for every failure past the threshold.
+func (s *LockoutService) lockoutDuration(excess int) time.Duration {
+	duration := s.policy.BaseLockout
+	for i := 0; i < excess && duration < s.policy.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > s.policy.MaxLockout {
+		duration = s.policy.MaxLockout
+	}
+	return duration
+}
+
+func (s *LockoutService) recordEvent(userID int64, ip, event string) {
+	err := s.lockoutRepo.RecordEvent(&domain.LoginEvent{UserID: userID, IPAddress: ip, Event: event})
+	if err != nil {
+		log.Printf("Failed to record login event %s: %v", event, err)
+	}
+}
+
+func accountKey(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func lockoutKeys(userID int64, ip string) []string {
+	var keys []string
+	if userID != 0 {
+		keys = append(keys, accountKey(userID))
+	}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
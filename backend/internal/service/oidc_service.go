@@ -0,0 +1,438 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+	"github.com/razvan/library-app/pkg/auth"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStateTTL       = 10 * time.Minute
+	oidcLinkRequestTTL = 10 * time.Minute
+	oidcHTTPTimeout    = 10 * time.Second
+)
+
+// OIDCProviderConfig describes an OpenID Connect provider. Endpoints and
+// signing keys are discovered from the issuer URL, so any compliant provider
+// (Google, Keycloak, Auth0, ...) can be used.
+type OIDCProviderConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDCAuthorization is returned by Start. The client sends the user to
+// AuthorizationURL; the provider then redirects back with a code and state.
+type OIDCAuthorization struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// OIDCLoginResult is the outcome of a provider callback. When the identity
+// matches an existing account that it is not linked to yet, LoginResult is
+// nil and LinkToken has to be confirmed with ConfirmLink.
+type OIDCLoginResult struct {
+	*LoginResult
+	LinkToken     string
+	LinkExpiresAt time.Time
+	Email         string
+}
+
+// oidcClaims are the ID token claims used to find or create the local user.
+type oidcClaims struct {
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"`
+	PreferredUsername string      `json:"preferred_username"`
+}
+
+// emailVerified accepts both booleans and the "true" strings some providers
+// send.
+func (c *oidcClaims) emailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// oidcProvider discovers its endpoints lazily, so that an unreachable
+// provider does not prevent the API from starting.
+type oidcProvider struct {
+	config   OIDCProviderConfig
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func (p *oidcProvider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.verifier != nil {
+		return nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, p.config.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("failed to discover provider %s: %w", p.config.Name, err)
+	}
+
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.config.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+
+	return nil
+}
+
+// authCodeURL is where the user is sent to sign in, with the state, nonce
+// and PKCE challenge of the flow.
+func (p *oidcProvider) authCodeURL(state *domain.OIDCState) string {
+	return p.oauth2.AuthCodeURL(
+		state.State,
+		oidc.Nonce(state.Nonce),
+		oauth2.S256ChallengeOption(state.CodeVerifier),
+	)
+}
+
+// redeem exchanges the authorization code with the flow's PKCE verifier and
+// returns the ID token once its signature, issuer, audience, expiry and
+// nonce are verified.
+func (p *oidcProvider) redeem(ctx context.Context, state *domain.OIDCState, code string) (*oidc.IDToken, *oidcClaims, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(state.CodeVerifier))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to exchange authorization code")
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("provider did not return an ID token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid ID token")
+	}
+	if idToken.Nonce != state.Nonce {
+		return nil, nil, fmt.Errorf("invalid ID token nonce")
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, nil, fmt.Errorf("invalid ID token claims")
+	}
+
+	return idToken, &claims, nil
+}
+
+type OIDCService struct {
+	providers    map[string]*oidcProvider
+	identityRepo *repository.IdentityRepository
+	userRepo     *repository.UserRepository
+	authService  *AuthService
+	httpClient   *http.Client
+}
+
+func NewOIDCService(configs []OIDCProviderConfig, identityRepo *repository.IdentityRepository, userRepo *repository.UserRepository, authService *AuthService) *OIDCService {
+	providers := make(map[string]*oidcProvider, len(configs))
+	for _, cfg := range configs {
+		if len(cfg.Scopes) == 0 {
+			cfg.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+		}
+		providers[cfg.Name] = &oidcProvider{config: cfg}
+	}
+
+	return &OIDCService{
+		providers:    providers,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		authService:  authService,
+		httpClient:   &http.Client{Timeout: oidcHTTPTimeout},
+	}
+}
+
+// Providers lists the names of the configured providers.
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start begins an authorization-code flow with PKCE. The state, nonce and
+// code verifier are stored server side until the callback.
+func (s *OIDCService) Start(ctx context.Context, providerName string) (*OIDCAuthorization, error) {
+	provider, err := s.provider(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state := newOIDCState(providerName)
+	if err := s.identityRepo.CreateState(state); err != nil {
+		return nil, fmt.Errorf("failed to save OIDC state: %w", err)
+	}
+
+	return &OIDCAuthorization{AuthorizationURL: provider.authCodeURL(state), ExpiresAt: state.ExpiresAt}, nil
+}
+
+// Callback redeems the authorization code, verifies the ID token against the
+// provider's published keys and logs in the user the identity belongs to.
+// Unknown identities get a new account, unless their email is already taken,
+// in which case the account owner has to confirm the link first.
+func (s *OIDCService) Callback(ctx context.Context, providerName, code, stateID string, client domain.ClientInfo) (*OIDCLoginResult, error) {
+	provider, err := s.provider(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.identityRepo.TakeState(stateID, providerName)
+	if err != nil || time.Now().After(state.ExpiresAt) {
+		return nil, fmt.Errorf("invalid or expired OIDC state")
+	}
+
+	idToken, claims, err := provider.redeem(oidc.ClientContext(ctx, s.httpClient), state, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// Returning user
+	identity, err := s.identityRepo.GetBySubject(providerName, idToken.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("user not found")
+		}
+
+		s.identityRepo.Touch(identity.ID)
+		return s.login(user, client)
+	}
+
+	if claims.Email == "" {
+		return nil, fmt.Errorf("provider did not return an email address")
+	}
+
+	// Existing account with the same email, ask its owner to confirm
+	if existing, err := s.userRepo.GetByEmail(claims.Email); err == nil {
+		return s.requestLink(existing, providerName, idToken.Subject, claims.Email)
+	}
+
+	user, err := s.createUser(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.identityRepo.Create(&domain.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  idToken.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return s.login(user, client)
+}
+
+// ConfirmLink links a pending identity to the existing account after the
+// owner proved they know its password, then logs them in.
+func (s *OIDCService) ConfirmLink(req *domain.OIDCLinkConfirm, client domain.ClientInfo) (*LoginResult, error) {
+	link, err := s.getLinkRequest(req.LinkToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(link.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	if err := s.authService.lockoutService.Check(user.ID, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	// Accounts created through a provider, or whose password was reset by
+	// an admin, confirm from a session instead (see LinkIdentity)
+	if user.PasswordHash == "" {
+		return nil, fmt.Errorf("this account has no password, sign in to it another way, such as with a passkey or a linked provider, to link this provider")
+	}
+
+	if !s.authService.verifyPassword(user, req.Password) {
+		s.authService.lockoutService.RecordFailure(user.ID, client.IPAddress, domain.LoginEventPasswordFailed)
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	if _, err := s.completeLink(link); err != nil {
+		return nil, err
+	}
+
+	return s.authService.finishPrimaryLogin(user, client)
+}
+
+// LinkIdentity links a pending identity to the account the user is signed
+// in to, which proves they own it whichever way they signed in.
+func (s *OIDCService) LinkIdentity(userID int64, req *domain.OIDCLinkIdentity) (*domain.UserIdentity, error) {
+	link, err := s.getLinkRequest(req.LinkToken)
+	if err != nil {
+		return nil, err
+	}
+	if link.UserID != userID {
+		return nil, fmt.Errorf("invalid or expired link request")
+	}
+
+	return s.completeLink(link)
+}
+
+func (s *OIDCService) getLinkRequest(token string) (*domain.OIDCLinkRequest, error) {
+	link, err := s.identityRepo.GetLinkRequest(token)
+	if err != nil || time.Now().After(link.ExpiresAt) {
+		return nil, fmt.Errorf("invalid or expired link request")
+	}
+	return link, nil
+}
+
+// completeLink uses up the link request and links its identity.
+func (s *OIDCService) completeLink(link *domain.OIDCLinkRequest) (*domain.UserIdentity, error) {
+	deleted, err := s.identityRepo.DeleteLinkRequest(link.ID)
+	if err != nil || !deleted {
+		return nil, fmt.Errorf("invalid or expired link request")
+	}
+
+	identity := &domain.UserIdentity{
+		UserID:   link.UserID,
+		Provider: link.Provider,
+		Subject:  link.Subject,
+		Email:    link.Email,
+	}
+	if err := s.identityRepo.Create(identity); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+func newOIDCState(providerName string) *domain.OIDCState {
+	return &domain.OIDCState{
+		State:        auth.NewTokenID(),
+		Provider:     providerName,
+		Nonce:        auth.NewTokenID(),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}
+}
+
+func (s *OIDCService) provider(ctx context.Context, name string) (*oidcProvider, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown OIDC provider")
+	}
+
+	ctx = oidc.ClientContext(ctx, s.httpClient)
+	if err := provider.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+func (s *OIDCService) login(user *domain.User, client domain.ClientInfo) (*OIDCLoginResult, error) {
+	result, err := s.authService.finishPrimaryLogin(user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCLoginResult{LoginResult: result}, nil
+}
+
+func (s *OIDCService) requestLink(user *domain.User, providerName, subject, email string) (*OIDCLoginResult, error) {
+	link := &domain.OIDCLinkRequest{
+		ID:        auth.NewTokenID(),
+		UserID:    user.ID,
+		Provider:  providerName,
+		Subject:   subject,
+		Email:     email,
+		ExpiresAt: time.Now().Add(oidcLinkRequestTTL),
+	}
+	if err := s.identityRepo.CreateLinkRequest(link); err != nil {
+		return nil, fmt.Errorf("failed to create link request: %w", err)
+	}
+
+	return &OIDCLoginResult{
+		LinkToken:     link.ID,
+		LinkExpiresAt: link.ExpiresAt,
+		Email:         email,
+	}, nil
+}
+
+// createUser registers a password-less account for a new identity.
+func (s *OIDCService) createUser(claims *oidcClaims) (*domain.User, error) {
+	username, err := s.uniqueUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &domain.User{
+		Email:         claims.Email,
+		Username:      username,
+		EmailVerified: claims.emailVerified(),
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+var usernameDisallowed = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// uniqueUsername derives a username from the ID token and adds a random
+// suffix if it is already taken.
+func (s *OIDCService) uniqueUsername(claims *oidcClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = usernameDisallowed.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = "user" + base
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	username := base
+	for i := 0; i < 5; i++ {
+		exists, err := s.userRepo.UsernameExists(username)
+		if err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+		if !exists {
+			return username, nil
+		}
+		username = base + "_" + auth.NewTokenID()[:6]
+	}
+
+	return "", fmt.Errorf("failed to pick a username")
+}
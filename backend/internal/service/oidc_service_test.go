@@ -0,0 +1,472 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/razvan/library-app/internal/domain"
+)
+
+const (
+	mockClientID    = "library-app"
+	mockRedirectURL = "http://localhost:3000/auth/oidc/mock/callback"
+)
+
+// mockIssuer is an OpenID provider that signs in whoever it is told to. It
+// checks the PKCE challenge of each code and can be made to issue ID tokens
+// for another audience or nonce.
+type mockIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu            sync.Mutex
+	authorization map[string]mockAuthorization
+	subject       string
+	email         string
+	audience      string
+	nonce         string
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &mockIssuer{
+		key:           key,
+		authorization: make(map[string]mockAuthorization),
+		subject:       "subject-1",
+		email:         "reader@example.com",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/jwks", issuer.jwks)
+	mux.HandleFunc("/authorize", issuer.authorize)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+
+	return issuer
+}
+
+func (m *mockIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                m.URL,
+		"authorization_endpoint":                m.URL + "/authorize",
+		"token_endpoint":                        m.URL + "/token",
+		"jwks_uri":                              m.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (m *mockIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	encode := base64.RawURLEncoding.EncodeToString
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   encode(m.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(m.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize signs the user in at once and redirects back with a code.
+func (m *mockIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" || query.Get("client_id") != mockClientID ||
+		query.Get("redirect_uri") != mockRedirectURL || query.Get("code_challenge_method") != "S256" ||
+		query.Get("code_challenge") == "" || query.Get("nonce") == "" || query.Get("state") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := newMockCode()
+	m.mu.Lock()
+	m.authorization[code] = mockAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	m.mu.Unlock()
+
+	redirect := mockRedirectURL + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// token redeems a code once, if the verifier matches its challenge.
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+	}
+
+	m.mu.Lock()
+	authorization, found := m.authorization[r.PostForm.Get("code")]
+	delete(m.authorization, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || clientID != mockClientID || r.PostForm.Get("grant_type") != "authorization_code" ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != authorization.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_grant"}`))
+		return
+	}
+
+	m.mu.Lock()
+	audience, nonce := mockClientID, authorization.nonce
+	if m.audience != "" {
+		audience = m.audience
+	}
+	if m.nonce != "" {
+		nonce = m.nonce
+	}
+	claims := jwt.MapClaims{
+		"iss":            m.URL,
+		"sub":            m.subject,
+		"aud":            audience,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"nonce":          nonce,
+		"email":          m.email,
+		"email_verified": true,
+	}
+	m.mu.Unlock()
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = "mock"
+	signed, err := idToken.SignedString(m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+func newMockCode() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// newMockOIDCService signs in with the issuer. Without services, only the
+// calls that do not need a database can be used.
+func newMockOIDCService(issuer *mockIssuer, services *testServices) *OIDCService {
+	configs := []OIDCProviderConfig{{
+		Name:         "mock",
+		IssuerURL:    issuer.URL,
+		ClientID:     mockClientID,
+		ClientSecret: "secret",
+		RedirectURL:  mockRedirectURL,
+	}}
+	if services == nil {
+		return NewOIDCService(configs, nil, nil, nil)
+	}
+	return NewOIDCService(configs, services.identities, services.users, services.auth)
+}
+
+// signIn follows the authorization URL to the issuer and returns the code
+// and state it redirects back with.
+func signIn(t *testing.T, authorizationURL string) (code, state string) {
+	t.Helper()
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorization failed with status %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestOIDCRedeem(t *testing.T) {
+	tests := []struct {
+		name     string
+		audience string
+		nonce    string
+		verifier string
+		wantErr  string
+	}{
+		{name: "valid"},
+		{name: "wrong audience", audience: "another-app", wantErr: "invalid ID token"},
+		{name: "wrong nonce", nonce: "another-nonce", wantErr: "invalid ID token nonce"},
+		{name: "wrong code verifier", verifier: "another-verifier-that-is-long-enough-for-pkce", wantErr: "failed to exchange authorization code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newMockIssuer(t)
+			issuer.audience = tt.audience
+			issuer.nonce = tt.nonce
+
+			s := newMockOIDCService(issuer, nil)
+			provider, err := s.provider(context.Background(), "mock")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			state := newOIDCState("mock")
+			code, returnedState := signIn(t, provider.authCodeURL(state))
+			if returnedState != state.State {
+				t.Fatalf("state = %q, want %q", returnedState, state.State)
+			}
+
+			if tt.verifier != "" {
+				state.CodeVerifier = tt.verifier
+			}
+			idToken, claims, err := provider.redeem(context.Background(), state, code)
+
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("redeem() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("redeem() error = %v", err)
+			}
+			if idToken.Subject != issuer.subject || claims.Email != issuer.email || !claims.emailVerified() {
+				t.Errorf("redeem() = %q, %+v", idToken.Subject, claims)
+			}
+		})
+	}
+}
+
+func TestOIDCRedeemCodeOnce(t *testing.T) {
+	issuer := newMockIssuer(t)
+	s := newMockOIDCService(issuer, nil)
+	provider, err := s.provider(context.Background(), "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := newOIDCState("mock")
+	code, _ := signIn(t, provider.authCodeURL(state))
+	if _, _, err := provider.redeem(context.Background(), state, code); err != nil {
+		t.Fatalf("redeem() error = %v", err)
+	}
+	if _, _, err := provider.redeem(context.Background(), state, code); err == nil {
+		t.Fatal("redeem() accepted a code twice")
+	}
+}
+
+func TestOIDCUnknownProvider(t *testing.T) {
+	s := newMockOIDCService(newMockIssuer(t), nil)
+	if _, err := s.Start(context.Background(), "unknown"); err == nil || !strings.Contains(err.Error(), "unknown OIDC provider") {
+		t.Fatalf("Start() error = %v", err)
+	}
+}
+
+// callback runs a whole flow: Start, signing in at the issuer and Callback.
+func callback(t *testing.T, s *OIDCService) (*OIDCLoginResult, error) {
+	t.Helper()
+
+	authorization, err := s.Start(context.Background(), "mock")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	code, state := signIn(t, authorization.AuthorizationURL)
+	return s.Callback(context.Background(), "mock", code, state, testClient)
+}
+
+func TestOIDCCallback(t *testing.T) {
+	services := newTestServices(t)
+	issuer := newMockIssuer(t)
+	s := newMockOIDCService(issuer, services)
+
+	// A new identity gets an account without a password
+	result, err := callback(t, s)
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if result.LoginResult == nil || result.Tokens == nil {
+		t.Fatalf("Callback() = %+v, want tokens", result)
+	}
+	user := result.User
+	if user.Email != issuer.email || user.PasswordHash != "" || !user.EmailVerified {
+		t.Errorf("created user = %+v", user)
+	}
+
+	// The identity logs in to the same account the next time
+	result, err = callback(t, s)
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if result.LoginResult == nil || result.User.ID != user.ID {
+		t.Errorf("Callback() logged in %+v, want user %d", result.LoginResult, user.ID)
+	}
+
+	// Each state can be redeemed once
+	authorization, err := s.Start(context.Background(), "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := signIn(t, authorization.AuthorizationURL)
+	if _, err := s.Callback(context.Background(), "mock", code, state, testClient); err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if _, err := s.Callback(context.Background(), "mock", code, state, testClient); err == nil || err.Error() != "invalid or expired OIDC state" {
+		t.Errorf("Callback() with a used state error = %v", err)
+	}
+}
+
+func TestOIDCCallbackRejectsIDToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		audience string
+		nonce    string
+		wantErr  string
+	}{
+		{name: "wrong audience", audience: "another-app", wantErr: "invalid ID token"},
+		{name: "wrong nonce", nonce: "another-nonce", wantErr: "invalid ID token nonce"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services := newTestServices(t)
+			issuer := newMockIssuer(t)
+			issuer.audience = tt.audience
+			issuer.nonce = tt.nonce
+
+			_, err := callback(t, newMockOIDCService(issuer, services))
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Callback() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := services.users.GetByEmail(issuer.email); err == nil {
+				t.Error("Callback() created an account for a rejected ID token")
+			}
+		})
+	}
+}
+
+func TestOIDCConfirmLinkWithPassword(t *testing.T) {
+	services := newTestServices(t)
+	issuer := newMockIssuer(t)
+	s := newMockOIDCService(issuer, services)
+	user := services.createUser(t, issuer.email, "correct-horse-battery")
+
+	// The email is taken, so its owner has to confirm
+	result, err := callback(t, s)
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if result.LoginResult != nil || result.LinkToken == "" || result.Email != issuer.email {
+		t.Fatalf("Callback() = %+v, want a link request", result)
+	}
+
+	_, err = s.ConfirmLink(&domain.OIDCLinkConfirm{LinkToken: result.LinkToken, Password: "wrong-password"}, testClient)
+	if err == nil || err.Error() != "invalid credentials" {
+		t.Fatalf("ConfirmLink() with a wrong password error = %v", err)
+	}
+
+	login, err := s.ConfirmLink(&domain.OIDCLinkConfirm{LinkToken: result.LinkToken, Password: "correct-horse-battery"}, testClient)
+	if err != nil {
+		t.Fatalf("ConfirmLink() error = %v", err)
+	}
+	if login.Tokens == nil || login.User.ID != user.ID {
+		t.Errorf("ConfirmLink() = %+v, want tokens for user %d", login, user.ID)
+	}
+
+	_, err = s.ConfirmLink(&domain.OIDCLinkConfirm{LinkToken: result.LinkToken, Password: "correct-horse-battery"}, testClient)
+	if err == nil || err.Error() != "invalid or expired link request" {
+		t.Errorf("ConfirmLink() with a used link error = %v", err)
+	}
+
+	// The identity is linked now
+	result, err = callback(t, s)
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if result.LoginResult == nil || result.User.ID != user.ID {
+		t.Errorf("Callback() after linking = %+v, want a login of user %d", result, user.ID)
+	}
+}
+
+func TestOIDCLinkWithoutPassword(t *testing.T) {
+	services := newTestServices(t)
+	issuer := newMockIssuer(t)
+	s := newMockOIDCService(issuer, services)
+	user := services.createUser(t, issuer.email, "")
+	other := services.createUser(t, "other@example.com", "")
+
+	result, err := callback(t, s)
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if result.LinkToken == "" {
+		t.Fatalf("Callback() = %+v, want a link request", result)
+	}
+
+	// There is no password to confirm with
+	_, err = s.ConfirmLink(&domain.OIDCLinkConfirm{LinkToken: result.LinkToken, Password: "anything"}, testClient)
+	if err == nil || !strings.Contains(err.Error(), "has no password") {
+		t.Fatalf("ConfirmLink() error = %v, want a no password error", err)
+	}
+
+	// Only a session of the account can confirm instead
+	_, err = s.LinkIdentity(other.ID, &domain.OIDCLinkIdentity{LinkToken: result.LinkToken})
+	if err == nil || err.Error() != "invalid or expired link request" {
+		t.Fatalf("LinkIdentity() by another user error = %v", err)
+	}
+
+	identity, err := s.LinkIdentity(user.ID, &domain.OIDCLinkIdentity{LinkToken: result.LinkToken})
+	if err != nil {
+		t.Fatalf("LinkIdentity() error = %v", err)
+	}
+	if identity.UserID != user.ID || identity.Provider != "mock" || identity.Subject != issuer.subject {
+		t.Errorf("LinkIdentity() = %+v", identity)
+	}
+
+	result, err = callback(t, s)
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if result.LoginResult == nil || result.User.ID != user.ID {
+		t.Errorf("Callback() after linking = %+v, want a login of user %d", result, user.ID)
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/razvan/library-app/pkg/auth"
+)
+
+// minPersonalInfoLength keeps very short usernames, such as "al", from
+// ruling out every password that happens to contain them.
+const minPersonalInfoLength = 3
+
+// PasswordPolicy decides which new passwords are accepted. Lengths are
+// counted in characters. Breached is the list of passwords known from data
+// breaches; nil disables the check.
+type PasswordPolicy struct {
+	MinLength          int
+	MaxLength          int
+	Breached           *auth.PasswordList
+	RejectPersonalInfo bool
+}
+
+// Check returns the first rule the password breaks, in a form that can be
+// shown to the user.
+func (p *PasswordPolicy) Check(password, email, username string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("password must be at most %d characters long", p.MaxLength)
+	}
+
+	if p.RejectPersonalInfo {
+		lower := strings.ToLower(password)
+		localPart, _, _ := strings.Cut(email, "@")
+		for _, info := range []string{email, localPart, username} {
+			if len(info) >= minPersonalInfoLength && strings.Contains(lower, strings.ToLower(info)) {
+				return fmt.Errorf("password must not contain your email address or username")
+			}
+		}
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		return fmt.Errorf("this password has appeared in a data breach, please choose a different one")
+	}
+
+	return nil
+}
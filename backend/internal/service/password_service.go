@@ -0,0 +1,187 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+	"github.com/razvan/library-app/pkg/auth"
+	"github.com/razvan/library-app/pkg/mailer"
+)
+
+const passwordResetTokenTTL = time.Hour
+
+// PasswordService handles forgotten and changed passwords. Either way, every
+// existing session and refresh token of the user is revoked.
+type PasswordService struct {
+	userRepo          *repository.UserRepository
+	passwordResetRepo *repository.PasswordResetRepository
+	sessionService    *SessionService
+	authService       *AuthService
+	auditService      *AuditService
+	mailer            mailer.Mailer
+	appName           string
+	frontendURL       string
+}
+
+func NewPasswordService(userRepo *repository.UserRepository, passwordResetRepo *repository.PasswordResetRepository, sessionService *SessionService, authService *AuthService, auditService *AuditService, m mailer.Mailer, appName, frontendURL string) *PasswordService {
+	return &PasswordService{
+		userRepo:          userRepo,
+		passwordResetRepo: passwordResetRepo,
+		sessionService:    sessionService,
+		authService:       authService,
+		auditService:      auditService,
+		mailer:            m,
+		appName:           appName,
+		frontendURL:       frontendURL,
+	}
+}
+
+// ForgotPassword emails a single-use reset link. Unknown addresses are
+// ignored silently, so the response does not reveal which emails have
+// accounts.
+func (s *PasswordService) ForgotPassword(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	err = s.sendResetLink(user, "Someone asked to reset the password of your account.",
+		"If you did not ask for this, you can ignore this email.")
+	if err != nil {
+		// Don't tell the caller, it would reveal that the account exists
+		log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+	}
+
+	return nil
+}
+
+// ForceReset removes the user's password and logs them out everywhere. They
+// are emailed a reset link and cannot log in with a password until they use
+// it.
+func (s *PasswordService) ForceReset(user *domain.User) error {
+	if err := s.userRepo.ClearPassword(user.ID); err != nil {
+		return fmt.Errorf("failed to clear password: %w", err)
+	}
+
+	if err := s.sessionService.RevokeAllSessions(user.ID); err != nil {
+		return err
+	}
+
+	return s.sendResetLink(user, "An administrator has asked you to choose a new password.",
+		"Until you do, you cannot log in with your old password.")
+}
+
+func (s *PasswordService) sendResetLink(user *domain.User, reason, footer string) error {
+	token := auth.NewTokenID() + auth.NewTokenID()
+	if err := s.passwordResetRepo.Create(user.ID, auth.HashToken(token), time.Now().Add(passwordResetTokenTTL)); err != nil {
+		return fmt.Errorf("failed to create reset token: %w", err)
+	}
+
+	link := s.frontendURL + "/reset-password?token=" + url.QueryEscape(token)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your " + s.appName + " password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n%s Open the link below to choose a new one:\n\n%s\n\nThe link expires in %d minutes and can only be used once. %s\n",
+			user.Username, reason, link, int(passwordResetTokenTTL.Minutes()), footer,
+		),
+	})
+}
+
+func (s *PasswordService) ResetPassword(req *domain.ResetPassword, client domain.ClientInfo) error {
+	tokenHash := auth.HashToken(req.Token)
+	userID, err := s.passwordResetRepo.GetUserID(tokenHash)
+	if err != nil {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	// Check the policy first, so a rejected password doesn't use up the token
+	if err := s.authService.passwordPolicy.Check(req.Password, user.Email, user.Username); err != nil {
+		return err
+	}
+
+	if _, err := s.passwordResetRepo.Consume(tokenHash); err != nil {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	if err := s.setPassword(userID, req.Password); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditPasswordReset,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    userID,
+		Client:      client,
+	})
+
+	return nil
+}
+
+// ChangePassword sets a new password for a logged in user. All sessions,
+// including the current one, are revoked and a new session is started.
+func (s *PasswordService) ChangePassword(userID int64, req *domain.ChangePassword, client domain.ClientInfo) (*auth.TokenPair, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	if !s.authService.verifyPassword(user, req.CurrentPassword) {
+		return nil, fmt.Errorf("current password is incorrect")
+	}
+
+	if err := s.authService.passwordPolicy.Check(req.NewPassword, user.Email, user.Username); err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		if req.Code == "" {
+			return nil, fmt.Errorf("2FA code is required")
+		}
+		if !s.authService.verifyTOTP(user, req.Code) {
+			return nil, fmt.Errorf("invalid verification code")
+		}
+	}
+
+	if err := s.setPassword(user.ID, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: user.ID,
+		Action:      domain.AuditPasswordChanged,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    user.ID,
+		Client:      client,
+	})
+
+	return s.authService.startSession(user, client)
+}
+
+// setPassword stores the new password and logs the user out everywhere.
+func (s *PasswordService) setPassword(userID int64, password string) error {
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.userRepo.UpdatePassword(userID, passwordHash); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := s.passwordResetRepo.DeleteForUser(userID); err != nil {
+		return fmt.Errorf("failed to delete reset tokens: %w", err)
+	}
+
+	return s.sessionService.RevokeAllSessions(userID)
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+	"github.com/razvan/library-app/pkg/mailer"
+)
+
+// ProfileService lets users edit their own profile and delete their account.
+// Deleted accounts are kept for a grace period, during which logging in
+// again cancels the deletion.
+type ProfileService struct {
+	userRepo       *repository.UserRepository
+	sessionService *SessionService
+	authService    *AuthService
+	emailService   *EmailVerificationService
+	auditService   *AuditService
+	mailer         mailer.Mailer
+	gracePeriod    time.Duration
+	appName        string
+}
+
+func NewProfileService(userRepo *repository.UserRepository, sessionService *SessionService, authService *AuthService, emailService *EmailVerificationService, auditService *AuditService, m mailer.Mailer, gracePeriod time.Duration, appName string) *ProfileService {
+	return &ProfileService{
+		userRepo:       userRepo,
+		sessionService: sessionService,
+		authService:    authService,
+		emailService:   emailService,
+		auditService:   auditService,
+		mailer:         m,
+		gracePeriod:    gracePeriod,
+		appName:        appName,
+	}
+}
+
+func (s *ProfileService) GetProfile(userID int64) (*domain.User, error) {
+	return s.userRepo.GetByID(userID)
+}
+
+// UpdateProfile applies the fields that are set. A new email address is only
+// stored as pending, and a verification link is sent to it.
+func (s *ProfileService) UpdateProfile(userID int64, req *domain.ProfileUpdate, client domain.ClientInfo) (*domain.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	before := *user
+
+	if req.Username != nil && *req.Username != user.Username {
+		exists, err := s.userRepo.UsernameExists(*req.Username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check username: %w", err)
+		}
+		if exists {
+			return nil, fmt.Errorf("username is already taken")
+		}
+		user.Username = *req.Username
+	}
+
+	if req.Bio != nil {
+		user.Bio = strings.TrimSpace(*req.Bio)
+	}
+
+	newEmail := ""
+	if req.Email != nil && !strings.EqualFold(*req.Email, user.Email) {
+		if user.PasswordHash != "" && !s.authService.verifyPassword(user, req.CurrentPassword) {
+			return nil, fmt.Errorf("current password is required to change your email")
+		}
+		if existing, _ := s.userRepo.GetByEmail(*req.Email); existing != nil {
+			return nil, fmt.Errorf("email address is already in use")
+		}
+		newEmail = *req.Email
+		user.PendingEmail = newEmail
+	}
+
+	if err := s.userRepo.UpdateProfile(user); err != nil {
+		return nil, err
+	}
+
+	if newEmail != "" {
+		if err := s.emailService.SendEmailChange(user, newEmail); err != nil {
+			if errors.Is(err, errVerificationThrottled) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to send verification email: %w", err)
+		}
+		s.notify(user, "Your "+s.appName+" email address is changing", fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to change the email address of your account to %s. It changes once the new address is confirmed. If this was not you, change your password now.\n",
+			user.Username, newEmail,
+		))
+	}
+
+	updated, err := s.userRepo.GetByID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: user.ID,
+		Action:      domain.AuditProfileUpdated,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    user.ID,
+		Client:      client,
+		Before:      &before,
+		After:       updated,
+	})
+
+	return updated, nil
+}
+
+// UpdateAvatar stores the URL of a new avatar and returns the previous one,
+// so the caller can remove the old file.
+func (s *ProfileService) UpdateAvatar(userID int64, avatarURL string) (string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", fmt.Errorf("user not found")
+	}
+
+	if err := s.userRepo.UpdateAvatar(userID, avatarURL); err != nil {
+		return "", fmt.Errorf("failed to update avatar: %w", err)
+	}
+
+	return user.AvatarURL, nil
+}
+
+// RequestDeletion schedules the account for deletion after the grace period
+// and logs the user out everywhere. The password or a 2FA code confirms it.
+func (s *ProfileService) RequestDeletion(userID int64, req *domain.AccountDeletion, client domain.ClientInfo) (*time.Time, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	if user.PasswordHash == "" && !user.TwoFactorEnabled {
+		return nil, fmt.Errorf("set a password before deleting your account")
+	}
+
+	confirmed := false
+	if req.Password != "" && user.PasswordHash != "" {
+		confirmed = s.authService.verifyPassword(user, req.Password)
+	} else if req.Code != "" && user.TwoFactorEnabled {
+		confirmed = s.authService.verifyTOTP(user, req.Code)
+	}
+	if !confirmed {
+		return nil, fmt.Errorf("invalid password or code")
+	}
+
+	deleteAfter := time.Now().Add(s.gracePeriod)
+	err = s.userRepo.ScheduleDeletion(user.ID, &deleteAfter)
+	if errors.Is(err, domain.ErrLastAdmin) {
+		return nil, fmt.Errorf("the last admin cannot delete their account")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule deletion: %w", err)
+	}
+
+	if err := s.sessionService.RevokeAllSessions(user.ID); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: user.ID,
+		Action:      domain.AuditDeletionRequested,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    user.ID,
+		Client:      client,
+		After:       map[string]time.Time{"delete_after": deleteAfter},
+	})
+
+	s.notify(user, "Your "+s.appName+" account will be deleted", fmt.Sprintf(
+		"Hi %s,\n\nYour account will be deleted on %s. Your comments will stay, without your name. To keep your account, log in before then.\n",
+		user.Username, deleteAfter.Format("January 2, 2006 15:04 MST"),
+	))
+
+	return &deleteAfter, nil
+}
+
+// PurgeDeletedAccounts deletes the accounts whose grace period is over and
+// returns the avatar URLs they used. Their comments are kept anonymously.
+func (s *ProfileService) PurgeDeletedAccounts() ([]string, error) {
+	return s.userRepo.DeleteScheduled()
+}
+
+func (s *ProfileService) notify(user *domain.User, subject, body string) {
+	err := s.mailer.Send(mailer.Message{To: user.Email, Subject: subject, Body: body})
+	if err != nil {
+		log.Printf("Failed to send email to user %d: %v", user.ID, err)
+	}
+}
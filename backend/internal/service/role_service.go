@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+)
+
+// RoleService assigns and revokes roles. Permissions are carried in access
+// tokens, so the target's sessions are revoked whenever their roles change.
+type RoleService struct {
+	roleRepo       *repository.RoleRepository
+	userRepo       *repository.UserRepository
+	sessionService *SessionService
+	auditService   *AuditService
+}
+
+func NewRoleService(roleRepo *repository.RoleRepository, userRepo *repository.UserRepository, sessionService *SessionService, auditService *AuditService) *RoleService {
+	return &RoleService{
+		roleRepo:       roleRepo,
+		userRepo:       userRepo,
+		sessionService: sessionService,
+		auditService:   auditService,
+	}
+}
+
+func (s *RoleService) GetRoles() ([]domain.Role, error) {
+	return s.roleRepo.GetAll()
+}
+
+func (s *RoleService) AssignRole(actorUserID, targetUserID int64, roleName string, client domain.ClientInfo) (*domain.User, error) {
+	role, target, err := s.prepareChange(actorUserID, targetUserID, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.AssignToUser(target.ID, role.ID, actorUserID); err != nil {
+		return nil, fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	return s.finishChange(actorUserID, target, domain.AuditRoleAssigned, client)
+}
+
+func (s *RoleService) RevokeRole(actorUserID, targetUserID int64, roleName string, client domain.ClientInfo) (*domain.User, error) {
+	role, target, err := s.prepareChange(actorUserID, targetUserID, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.roleRepo.RevokeFromUser(target.ID, role.ID)
+	if errors.Is(err, domain.ErrLastAdmin) {
+		return nil, fmt.Errorf("cannot revoke the role of the last admin")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke role: %w", err)
+	}
+	if !revoked {
+		return nil, fmt.Errorf("user does not have this role")
+	}
+
+	return s.finishChange(actorUserID, target, domain.AuditRoleRevoked, client)
+}
+
+func (s *RoleService) prepareChange(actorUserID, targetUserID int64, roleName string) (*domain.Role, *domain.User, error) {
+	actor, err := s.userRepo.GetByID(actorUserID)
+	if err != nil || !actor.HasPermission(domain.PermissionUsersManage) {
+		return nil, nil, fmt.Errorf("unauthorized: you cannot manage roles")
+	}
+
+	if actorUserID == targetUserID {
+		return nil, nil, fmt.Errorf("you cannot change your own roles")
+	}
+
+	target, err := s.userRepo.GetByID(targetUserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("target user not found")
+	}
+
+	role, err := s.roleRepo.GetByName(roleName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return role, target, nil
+}
+
+// finishChange logs the user out everywhere, since existing tokens still
+// carry the old permissions, records the change and returns the updated
+// user.
+func (s *RoleService) finishChange(actorUserID int64, target *domain.User, action string, client domain.ClientInfo) (*domain.User, error) {
+	if err := s.sessionService.RevokeAllSessions(target.ID); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.userRepo.GetByID(target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      action,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    target.ID,
+		Client:      client,
+		Before:      map[string][]string{"roles": target.Roles},
+		After:       map[string][]string{"roles": updated.Roles},
+	})
+
+	return updated, nil
+}
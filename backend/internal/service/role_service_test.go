@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+)
+
+// createAdmin adds a user with the admin role.
+func (s *testServices) createAdmin(t *testing.T, email string) *domain.User {
+	t.Helper()
+
+	user := s.createUser(t, email, "correct-horse-battery")
+	role, err := s.roles.GetByName(domain.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.roles.AssignToUser(user.ID, role.ID, user.ID); err != nil {
+		t.Fatalf("failed to make %s an admin: %v", email, err)
+	}
+	return user
+}
+
+func TestLastAdminUnderConcurrency(t *testing.T) {
+	services := newTestServices(t)
+	first := services.createAdmin(t, "first@example.com")
+	second := services.createAdmin(t, "second@example.com")
+
+	// Each admin suspends the other at the same time
+	errs := make(chan error, 2)
+	for _, user := range []*domain.User{first, second} {
+		go func(userID int64) {
+			errs <- services.users.Suspend(userID, "test")
+		}(user.ID)
+	}
+
+	var suspended, refused int
+	for i := 0; i < 2; i++ {
+		switch err := <-errs; {
+		case err == nil:
+			suspended++
+		case errors.Is(err, domain.ErrLastAdmin):
+			refused++
+		default:
+			t.Fatalf("Suspend() error = %v", err)
+		}
+	}
+	if suspended != 1 || refused != 1 {
+		t.Errorf("%d admins suspended and %d refused, want 1 each", suspended, refused)
+	}
+}
+
+func TestLastAdminIgnoresScheduledDeletion(t *testing.T) {
+	services := newTestServices(t)
+	first := services.createAdmin(t, "first@example.com")
+	second := services.createAdmin(t, "second@example.com")
+	role, err := services.roles.GetByName(domain.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleteAfter := time.Now().Add(time.Hour)
+	if err := services.users.ScheduleDeletion(second.ID, &deleteAfter); err != nil {
+		t.Fatalf("ScheduleDeletion() error = %v", err)
+	}
+
+	// The admin about to be deleted does not count
+	if _, err := services.roles.RevokeFromUser(first.ID, role.ID); !errors.Is(err, domain.ErrLastAdmin) {
+		t.Errorf("RevokeFromUser() error = %v, want ErrLastAdmin", err)
+	}
+	if err := services.users.ScheduleDeletion(first.ID, &deleteAfter); !errors.Is(err, domain.ErrLastAdmin) {
+		t.Errorf("ScheduleDeletion() error = %v, want ErrLastAdmin", err)
+	}
+
+	// Other roles can still be revoked from the last admin
+	librarian, err := services.roles.GetByName(domain.RoleLibrarian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := services.roles.AssignToUser(first.ID, librarian.ID, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := services.roles.RevokeFromUser(first.ID, librarian.ID); err != nil || !revoked {
+		t.Errorf("RevokeFromUser() = %v, %v, want the librarian role revoked", revoked, err)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+)
+
+type SeriesService struct {
+	seriesRepo   *repository.SeriesRepository
+	bookRepo     *repository.BookRepository
+	auditService *AuditService
+}
+
+func NewSeriesService(seriesRepo *repository.SeriesRepository, bookRepo *repository.BookRepository, auditService *AuditService) *SeriesService {
+	return &SeriesService{seriesRepo: seriesRepo, bookRepo: bookRepo, auditService: auditService}
+}
+
+func (s *SeriesService) CreateSeries(actorUserID int64, req *domain.SeriesCreate, client domain.ClientInfo) (*domain.Series, error) {
+	series := &domain.Series{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	if err := s.seriesRepo.Create(series); err != nil {
+		return nil, fmt.Errorf("failed to create series: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditSeriesCreated,
+		TargetType:  domain.AuditTargetSeries,
+		TargetID:    series.ID,
+		Client:      client,
+		After:       series,
+	})
+
+	return series, nil
+}
+
+func (s *SeriesService) GetSeries(id int64) (*domain.Series, error) {
+	return s.seriesRepo.GetByID(id)
+}
+
+func (s *SeriesService) ListSeries(page domain.PageRequest) (*domain.Page[domain.Series], error) {
+	return s.seriesRepo.List(page)
+}
+
+func (s *SeriesService) UpdateSeries(actorUserID, id int64, req *domain.SeriesCreate, client domain.ClientInfo) (*domain.Series, error) {
+	series, err := s.seriesRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := *series
+
+	series.Name = req.Name
+	series.Description = req.Description
+
+	if err := s.seriesRepo.Update(series); err != nil {
+		return nil, fmt.Errorf("failed to update series: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditSeriesUpdated,
+		TargetType:  domain.AuditTargetSeries,
+		TargetID:    id,
+		Client:      client,
+		Before:      &before,
+		After:       series,
+	})
+
+	return series, nil
+}
+
+func (s *SeriesService) DeleteSeries(actorUserID, id int64, client domain.ClientInfo) error {
+	series, err := s.seriesRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.seriesRepo.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditSeriesDeleted,
+		TargetType:  domain.AuditTargetSeries,
+		TargetID:    id,
+		Client:      client,
+		Before:      series,
+	})
+
+	return nil
+}
+
+// SetVolume places a book in the series, or moves it to another position.
+// A book in another series leaves that one.
+func (s *SeriesService) SetVolume(actorUserID, seriesID, bookID int64, position float64, client domain.ClientInfo) (*domain.Book, error) {
+	if _, err := s.seriesRepo.GetByID(seriesID); err != nil {
+		return nil, err
+	}
+
+	book, err := s.bookRepo.GetByID(bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.seriesRepo.SetVolume(seriesID, bookID, position); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.bookRepo.GetByID(bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditSeriesVolumeSet,
+		TargetType:  domain.AuditTargetSeries,
+		TargetID:    seriesID,
+		Client:      client,
+		Before:      volumeSnapshot(book),
+		After:       volumeSnapshot(updated),
+	})
+
+	return updated, nil
+}
+
+func (s *SeriesService) RemoveVolume(actorUserID, seriesID, bookID int64, client domain.ClientInfo) error {
+	position, err := s.seriesRepo.GetVolume(seriesID, bookID)
+	if err != nil {
+		return err
+	}
+	if position == 0 {
+		return fmt.Errorf("book is not part of this series")
+	}
+
+	if err := s.seriesRepo.RemoveVolume(seriesID, bookID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditSeriesVolumeRemoved,
+		TargetType:  domain.AuditTargetSeries,
+		TargetID:    seriesID,
+		Client:      client,
+		Before:      map[string]interface{}{"book_id": bookID, "position": position},
+	})
+
+	return nil
+}
+
+// volumeSnapshot is the audited place of a book in its series.
+func volumeSnapshot(book *domain.Book) map[string]interface{} {
+	snapshot := map[string]interface{}{"book_id": book.ID}
+	if book.Series != nil {
+		snapshot["series_id"] = book.Series.ID
+		snapshot["position"] = book.Series.Position
+	}
+	return snapshot
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+	"github.com/razvan/library-app/pkg/auth"
+)
+
+// lastSeenResolution limits how often a session's last_seen_at is written.
+const lastSeenResolution = time.Minute
+
+type SessionService struct {
+	sessionRepo      *repository.SessionRepository
+	refreshTokenRepo *repository.RefreshTokenRepository
+}
+
+func NewSessionService(sessionRepo *repository.SessionRepository, refreshTokenRepo *repository.RefreshTokenRepository) *SessionService {
+	return &SessionService{
+		sessionRepo:      sessionRepo,
+		refreshTokenRepo: refreshTokenRepo,
+	}
+}
+
+func (s *SessionService) CreateSession(userID int64, client domain.ClientInfo) (*domain.Session, error) {
+	session := &domain.Session{
+		ID:        auth.NewTokenID(),
+		UserID:    userID,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+	}
+
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return session, nil
+}
+
+// ValidateSession checks that the session behind a token has not been revoked
+// and records that it was just used.
+func (s *SessionService) ValidateSession(sessionID string) error {
+	if sessionID == "" {
+		return fmt.Errorf("session not found")
+	}
+
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session.RevokedAt != nil {
+		return fmt.Errorf("session has been revoked")
+	}
+
+	if time.Since(session.LastSeenAt) > lastSeenResolution {
+		if err := s.sessionRepo.Touch(session.ID); err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *SessionService) GetUserSessions(userID int64, currentSessionID string) ([]domain.Session, error) {
+	sessions, err := s.sessionRepo.GetActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeUserSession revokes one of the user's own sessions.
+func (s *SessionService) RevokeUserSession(userID int64, sessionID string) error {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil || session.UserID != userID {
+		return fmt.Errorf("session not found")
+	}
+
+	return s.RevokeSession(session.ID)
+}
+
+func (s *SessionService) RevokeSession(sessionID string) error {
+	if err := s.sessionRepo.Revoke(sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if err := s.refreshTokenRepo.RevokeFamily(sessionID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAllSessions logs the user out everywhere.
+func (s *SessionService) RevokeAllSessions(userID int64) error {
+	if err := s.sessionRepo.RevokeAllForUser(userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := s.refreshTokenRepo.RevokeAllForUser(userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+)
+
+type TaxonomyService struct {
+	taxonomyRepo *repository.TaxonomyRepository
+	auditService *AuditService
+}
+
+func NewTaxonomyService(taxonomyRepo *repository.TaxonomyRepository, auditService *AuditService) *TaxonomyService {
+	return &TaxonomyService{taxonomyRepo: taxonomyRepo, auditService: auditService}
+}
+
+// Genre methods
+func (s *TaxonomyService) CreateGenre(actorUserID int64, req *domain.GenreCreate, client domain.ClientInfo) (*domain.Genre, error) {
+	if req.ParentID != nil {
+		if _, err := s.taxonomyRepo.GetGenreByID(*req.ParentID); err != nil {
+			return nil, fmt.Errorf("parent genre not found")
+		}
+	}
+
+	genre := &domain.Genre{
+		Name:     req.Name,
+		ParentID: req.ParentID,
+	}
+
+	if err := s.taxonomyRepo.CreateGenre(genre); err != nil {
+		return nil, fmt.Errorf("failed to create genre: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditGenreCreated,
+		TargetType:  domain.AuditTargetGenre,
+		TargetID:    genre.ID,
+		Client:      client,
+		After:       genre,
+	})
+
+	return genre, nil
+}
+
+func (s *TaxonomyService) GetGenre(id int64) (*domain.Genre, error) {
+	return s.taxonomyRepo.GetGenreByID(id)
+}
+
+func (s *TaxonomyService) GetAllGenres() ([]domain.Genre, error) {
+	return s.taxonomyRepo.GetAllGenres()
+}
+
+// UpdateGenre renames the genre and moves it under another parent, or to
+// the top level. A genre cannot move below itself.
+func (s *TaxonomyService) UpdateGenre(actorUserID, id int64, req *domain.GenreCreate, client domain.ClientInfo) (*domain.Genre, error) {
+	genre, err := s.taxonomyRepo.GetGenreByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := *genre
+
+	if req.ParentID != nil {
+		if _, err := s.taxonomyRepo.GetGenreByID(*req.ParentID); err != nil {
+			return nil, fmt.Errorf("parent genre not found")
+		}
+
+		within, err := s.taxonomyRepo.IsGenreWithin(*req.ParentID, id)
+		if err != nil {
+			return nil, err
+		}
+		if within {
+			return nil, fmt.Errorf("a genre cannot be moved below itself")
+		}
+	}
+
+	genre.Name = req.Name
+	genre.ParentID = req.ParentID
+
+	if err := s.taxonomyRepo.UpdateGenre(genre); err != nil {
+		return nil, fmt.Errorf("failed to update genre: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditGenreUpdated,
+		TargetType:  domain.AuditTargetGenre,
+		TargetID:    id,
+		Client:      client,
+		Before:      &before,
+		After:       genre,
+	})
+
+	return genre, nil
+}
+
+func (s *TaxonomyService) DeleteGenre(actorUserID, id int64, client domain.ClientInfo) error {
+	genre, err := s.taxonomyRepo.GetGenreByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.taxonomyRepo.DeleteGenre(id); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditGenreDeleted,
+		TargetType:  domain.AuditTargetGenre,
+		TargetID:    id,
+		Client:      client,
+		Before:      genre,
+	})
+
+	return nil
+}
+
+// Tag methods
+func (s *TaxonomyService) CreateTag(actorUserID int64, req *domain.TagCreate, client domain.ClientInfo) (*domain.Tag, error) {
+	tag := &domain.Tag{Name: req.Name}
+
+	if err := s.taxonomyRepo.CreateTag(tag); err != nil {
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditTagCreated,
+		TargetType:  domain.AuditTargetTag,
+		TargetID:    tag.ID,
+		Client:      client,
+		After:       tag,
+	})
+
+	return tag, nil
+}
+
+func (s *TaxonomyService) GetTag(id int64) (*domain.Tag, error) {
+	return s.taxonomyRepo.GetTagByID(id)
+}
+
+func (s *TaxonomyService) ListTags(prefix string, page domain.PageRequest) (*domain.Page[domain.Tag], error) {
+	return s.taxonomyRepo.ListTags(prefix, page)
+}
+
+func (s *TaxonomyService) UpdateTag(actorUserID, id int64, req *domain.TagCreate, client domain.ClientInfo) (*domain.Tag, error) {
+	tag, err := s.taxonomyRepo.GetTagByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := *tag
+
+	tag.Name = req.Name
+	if err := s.taxonomyRepo.UpdateTag(tag); err != nil {
+		return nil, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditTagUpdated,
+		TargetType:  domain.AuditTargetTag,
+		TargetID:    id,
+		Client:      client,
+		Before:      &before,
+		After:       tag,
+	})
+
+	return tag, nil
+}
+
+func (s *TaxonomyService) DeleteTag(actorUserID, id int64, client domain.ClientInfo) error {
+	tag, err := s.taxonomyRepo.GetTagByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.taxonomyRepo.DeleteTag(id); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditTagDeleted,
+		TargetType:  domain.AuditTargetTag,
+		TargetID:    id,
+		Client:      client,
+		Before:      tag,
+	})
+
+	return nil
+}
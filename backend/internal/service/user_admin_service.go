@@ -0,0 +1,226 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+)
+
+// UserAdminService lets user managers find, suspend and remove accounts. None
+// of its actions can target the acting user or take away the last admin.
+type UserAdminService struct {
+	userRepo        *repository.UserRepository
+	roleRepo        *repository.RoleRepository
+	sessionService  *SessionService
+	passwordService *PasswordService
+	auditService    *AuditService
+}
+
+func NewUserAdminService(userRepo *repository.UserRepository, roleRepo *repository.RoleRepository, sessionService *SessionService, passwordService *PasswordService, auditService *AuditService) *UserAdminService {
+	return &UserAdminService{
+		userRepo:        userRepo,
+		roleRepo:        roleRepo,
+		sessionService:  sessionService,
+		passwordService: passwordService,
+		auditService:    auditService,
+	}
+}
+
+func (s *UserAdminService) ListUsers(actorUserID int64, filter domain.UserFilter, page domain.PageRequest) (*domain.Page[domain.User], error) {
+	if _, err := s.requireManager(actorUserID); err != nil {
+		return nil, err
+	}
+
+	users, err := s.userRepo.List(filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (s *UserAdminService) GetUser(actorUserID, targetUserID int64) (*domain.User, error) {
+	if _, err := s.requireManager(actorUserID); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.GetByID(targetUserID)
+}
+
+// DemoteUser takes away every role of the user, leaving a regular account.
+func (s *UserAdminService) DemoteUser(actorUserID, targetUserID int64, client domain.ClientInfo) (*domain.User, error) {
+	target, err := s.prepareAction(actorUserID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(target.Roles) == 0 {
+		return nil, fmt.Errorf("user has no roles")
+	}
+
+	if err := s.roleRepo.RevokeAllFromUser(target.ID); err != nil {
+		return nil, lastAdminError(err, "failed to demote user")
+	}
+
+	if err := s.sessionService.RevokeAllSessions(target.ID); err != nil {
+		return nil, err
+	}
+
+	return s.finishAction(domain.AuditUserDemoted, actorUserID, target, client, "")
+}
+
+// SuspendUser blocks the account until it is unsuspended. Its sessions are
+// revoked, so it is logged out immediately, and its API keys stop working.
+func (s *UserAdminService) SuspendUser(actorUserID, targetUserID int64, reason string, client domain.ClientInfo) (*domain.User, error) {
+	target, err := s.prepareAction(actorUserID, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.SuspendedAt != nil {
+		return nil, fmt.Errorf("user is already suspended")
+	}
+
+	if err := s.userRepo.Suspend(target.ID, reason); err != nil {
+		return nil, lastAdminError(err, "failed to suspend user")
+	}
+
+	if err := s.sessionService.RevokeAllSessions(target.ID); err != nil {
+		return nil, err
+	}
+
+	return s.finishAction(domain.AuditUserSuspended, actorUserID, target, client, reason)
+}
+
+func (s *UserAdminService) UnsuspendUser(actorUserID, targetUserID int64, client domain.ClientInfo) (*domain.User, error) {
+	if _, err := s.requireManager(actorUserID); err != nil {
+		return nil, err
+	}
+
+	target, err := s.userRepo.GetByID(targetUserID)
+	if err != nil {
+		return nil, fmt.Errorf("target user not found")
+	}
+
+	if target.SuspendedAt == nil {
+		return nil, fmt.Errorf("user is not suspended")
+	}
+
+	if err := s.userRepo.Unsuspend(target.ID); err != nil {
+		return nil, fmt.Errorf("failed to unsuspend user: %w", err)
+	}
+
+	return s.finishAction(domain.AuditUserUnsuspended, actorUserID, target, client, "")
+}
+
+// ForcePasswordReset invalidates the user's password and emails them a
+// reset link.
+func (s *UserAdminService) ForcePasswordReset(actorUserID, targetUserID int64, client domain.ClientInfo) error {
+	if _, err := s.requireManager(actorUserID); err != nil {
+		return err
+	}
+
+	if actorUserID == targetUserID {
+		return fmt.Errorf("use change password for your own account")
+	}
+
+	target, err := s.userRepo.GetByID(targetUserID)
+	if err != nil {
+		return fmt.Errorf("target user not found")
+	}
+
+	if err := s.passwordService.ForceReset(target); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditPasswordResetForced,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    target.ID,
+		Client:      client,
+	})
+
+	return nil
+}
+
+func (s *UserAdminService) DeleteUser(actorUserID, targetUserID int64, client domain.ClientInfo) error {
+	target, err := s.prepareAction(actorUserID, targetUserID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Delete(target.ID); err != nil {
+		return lastAdminError(err, "failed to delete user")
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      domain.AuditUserDeleted,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    target.ID,
+		Client:      client,
+		Before:      target,
+	})
+
+	return nil
+}
+
+func (s *UserAdminService) requireManager(actorUserID int64) (*domain.User, error) {
+	actor, err := s.userRepo.GetByID(actorUserID)
+	if err != nil || !actor.HasPermission(domain.PermissionUsersManage) {
+		return nil, fmt.Errorf("unauthorized: you cannot manage users")
+	}
+	return actor, nil
+}
+
+// prepareAction checks an action that takes access away from the target.
+func (s *UserAdminService) prepareAction(actorUserID, targetUserID int64) (*domain.User, error) {
+	if _, err := s.requireManager(actorUserID); err != nil {
+		return nil, err
+	}
+
+	if actorUserID == targetUserID {
+		return nil, fmt.Errorf("you cannot do this to your own account")
+	}
+
+	target, err := s.userRepo.GetByID(targetUserID)
+	if err != nil {
+		return nil, fmt.Errorf("target user not found")
+	}
+
+	return target, nil
+}
+
+// lastAdminError explains a change refused because the target is the last
+// admin, and wraps any other error with the message.
+func lastAdminError(err error, message string) error {
+	if errors.Is(err, domain.ErrLastAdmin) {
+		return fmt.Errorf("cannot remove the last admin")
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// finishAction records the change to the target and returns the updated
+// user.
+func (s *UserAdminService) finishAction(action string, actorUserID int64, target *domain.User, client domain.ClientInfo, reason string) (*domain.User, error) {
+	updated, err := s.userRepo.GetByID(target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: actorUserID,
+		Action:      action,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    target.ID,
+		Client:      client,
+		Before:      target,
+		After:       updated,
+		Reason:      reason,
+	})
+
+	return updated, nil
+}
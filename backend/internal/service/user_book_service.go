@@ -10,59 +10,177 @@ import (
 type UserBookService struct {
 	userBookRepo *repository.UserBookRepository
 	bookRepo     *repository.BookRepository
+	auditService *AuditService
 }
 
-func NewUserBookService(userBookRepo *repository.UserBookRepository, bookRepo *repository.BookRepository) *UserBookService {
+func NewUserBookService(userBookRepo *repository.UserBookRepository, bookRepo *repository.BookRepository, auditService *AuditService) *UserBookService {
 	return &UserBookService{
 		userBookRepo: userBookRepo,
 		bookRepo:     bookRepo,
+		auditService: auditService,
 	}
 }
 
 // Reading list methods
-func (s *UserBookService) AddToReadingList(userID, bookID int64, status domain.ReadingStatus) error {
+// AddToReadingList sets the status of a book on the user's reading list,
+// and the edition they own or read when editionID is not nil.
+func (s *UserBookService) AddToReadingList(userID, bookID int64, status domain.ReadingStatus, editionID *int64, client domain.ClientInfo) error {
 	// Verify book exists
-	_, err := s.bookRepo.GetByID(bookID)
+	book, err := s.bookRepo.GetByID(bookID)
 	if err != nil {
 		return fmt.Errorf("book not found")
 	}
 
-	return s.userBookRepo.AddToReadingList(userID, bookID, status)
+	after := map[string]interface{}{"status": status}
+	if editionID != nil {
+		if !hasEdition(book, *editionID) {
+			return fmt.Errorf("edition %d is not an edition of this book", *editionID)
+		}
+		after["edition_id"] = *editionID
+	}
+
+	if err := s.userBookRepo.AddToReadingList(userID, bookID, status, editionID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditReadingListUpdated,
+		TargetType:  domain.AuditTargetBook,
+		TargetID:    bookID,
+		Client:      client,
+		After:       after,
+	})
+
+	return nil
 }
 
-func (s *UserBookService) RemoveFromReadingList(userID, bookID int64) error {
-	return s.userBookRepo.RemoveFromReadingList(userID, bookID)
+func (s *UserBookService) RemoveFromReadingList(userID, bookID int64, client domain.ClientInfo) error {
+	if err := s.userBookRepo.RemoveFromReadingList(userID, bookID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditReadingListRemoved,
+		TargetType:  domain.AuditTargetBook,
+		TargetID:    bookID,
+		Client:      client,
+	})
+
+	return nil
+}
+
+func (s *UserBookService) GetUserReadingList(userID int64, status string, page domain.PageRequest) (*domain.Page[domain.UserBook], error) {
+	return s.userBookRepo.GetUserBooks(userID, status, page)
 }
 
-func (s *UserBookService) GetUserReadingList(userID int64, status string) ([]domain.UserBook, error) {
-	return s.userBookRepo.GetUserBooks(userID, status)
+// GetSeriesProgress returns how far the user is through each series they
+// have a volume of on their reading list.
+func (s *UserBookService) GetSeriesProgress(userID int64) ([]domain.SeriesProgress, error) {
+	return s.userBookRepo.GetSeriesProgress(userID, nil)
 }
 
 // Favorite methods
-func (s *UserBookService) AddToFavorites(userID, bookID int64) error {
+func (s *UserBookService) AddToFavorites(userID, bookID int64, client domain.ClientInfo) error {
 	// Verify book exists
 	_, err := s.bookRepo.GetByID(bookID)
 	if err != nil {
 		return fmt.Errorf("book not found")
 	}
 
-	return s.userBookRepo.AddToFavorites(userID, bookID)
+	if err := s.userBookRepo.AddToFavorites(userID, bookID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditFavoriteAdded,
+		TargetType:  domain.AuditTargetBook,
+		TargetID:    bookID,
+		Client:      client,
+	})
+
+	return nil
 }
 
-func (s *UserBookService) RemoveFromFavorites(userID, bookID int64) error {
-	return s.userBookRepo.RemoveFromFavorites(userID, bookID)
+func (s *UserBookService) RemoveFromFavorites(userID, bookID int64, client domain.ClientInfo) error {
+	if err := s.userBookRepo.RemoveFromFavorites(userID, bookID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditFavoriteRemoved,
+		TargetType:  domain.AuditTargetBook,
+		TargetID:    bookID,
+		Client:      client,
+	})
+
+	return nil
 }
 
-func (s *UserBookService) GetUserFavorites(userID int64) ([]domain.Favorite, error) {
-	return s.userBookRepo.GetUserFavorites(userID)
+func (s *UserBookService) GetUserFavorites(userID int64, page domain.PageRequest) (*domain.Page[domain.Favorite], error) {
+	return s.userBookRepo.GetUserFavorites(userID, page)
 }
 
 func (s *UserBookService) IsFavorite(userID, bookID int64) (bool, error) {
 	return s.userBookRepo.IsFavorite(userID, bookID)
 }
 
+// Rating methods
+func (s *UserBookService) RateBook(userID, bookID int64, stars int, client domain.ClientInfo) (*domain.Rating, error) {
+	// Verify book exists
+	_, err := s.bookRepo.GetByID(bookID)
+	if err != nil {
+		return nil, fmt.Errorf("book not found")
+	}
+
+	var before interface{}
+	if previous, err := s.userBookRepo.GetRating(userID, bookID); err == nil {
+		before = map[string]int{"rating": previous.Rating}
+	}
+
+	rating := &domain.Rating{UserID: userID, BookID: bookID, Rating: stars}
+	if err := s.userBookRepo.SetRating(rating); err != nil {
+		return nil, fmt.Errorf("failed to save rating: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditBookRated,
+		TargetType:  domain.AuditTargetBook,
+		TargetID:    bookID,
+		Client:      client,
+		Before:      before,
+		After:       map[string]int{"rating": stars},
+	})
+
+	return rating, nil
+}
+
+func (s *UserBookService) GetRating(userID, bookID int64) (*domain.Rating, error) {
+	return s.userBookRepo.GetRating(userID, bookID)
+}
+
+func (s *UserBookService) RemoveRating(userID, bookID int64, client domain.ClientInfo) error {
+	if err := s.userBookRepo.DeleteRating(userID, bookID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditRatingRemoved,
+		TargetType:  domain.AuditTargetBook,
+		TargetID:    bookID,
+		Client:      client,
+	})
+
+	return nil
+}
+
 // Comment methods
-func (s *UserBookService) CreateComment(userID, bookID int64, content string) (*domain.Comment, error) {
+func (s *UserBookService) CreateComment(userID, bookID int64, content string, client domain.ClientInfo) (*domain.Comment, error) {
 	// Verify book exists
 	_, err := s.bookRepo.GetByID(bookID)
 	if err != nil {
@@ -80,17 +198,92 @@ func (s *UserBookService) CreateComment(userID, bookID int64, content string) (*
 		return nil, fmt.Errorf("failed to create comment: %w", err)
 	}
 
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditCommentCreated,
+		TargetType:  domain.AuditTargetComment,
+		TargetID:    comment.ID,
+		Client:      client,
+		After:       commentSnapshot(comment),
+	})
+
 	return comment, nil
 }
 
-func (s *UserBookService) GetBookComments(bookID int64) ([]domain.Comment, error) {
-	return s.userBookRepo.GetBookComments(bookID)
+func (s *UserBookService) GetBookComments(bookID int64, page domain.PageRequest) (*domain.Page[domain.Comment], error) {
+	return s.userBookRepo.GetBookComments(bookID, page)
+}
+
+func (s *UserBookService) UpdateComment(commentID, userID int64, content string, client domain.ClientInfo) error {
+	before, err := s.userBookRepo.GetCommentByID(commentID)
+	if err != nil || before.UserID != userID {
+		return fmt.Errorf("comment not found or unauthorized")
+	}
+
+	if err := s.userBookRepo.UpdateComment(commentID, userID, content); err != nil {
+		return err
+	}
+
+	after := *before
+	after.Content = content
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditCommentUpdated,
+		TargetType:  domain.AuditTargetComment,
+		TargetID:    commentID,
+		Client:      client,
+		Before:      commentSnapshot(before),
+		After:       commentSnapshot(&after),
+	})
+
+	return nil
+}
+
+// DeleteComment deletes one of the user's comments. Moderators can delete
+// anyone's comment.
+func (s *UserBookService) DeleteComment(commentID, userID int64, moderate bool, client domain.ClientInfo) error {
+	comment, err := s.userBookRepo.GetCommentByID(commentID)
+	if err != nil {
+		return fmt.Errorf("comment not found or unauthorized")
+	}
+
+	action := domain.AuditCommentDeleted
+	if moderate && comment.UserID != userID {
+		action = domain.AuditCommentDeletedByStaff
+		err = s.userBookRepo.DeleteAnyComment(commentID)
+	} else {
+		err = s.userBookRepo.DeleteComment(commentID, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      action,
+		TargetType:  domain.AuditTargetComment,
+		TargetID:    commentID,
+		Client:      client,
+		Before:      commentSnapshot(comment),
+	})
+
+	return nil
 }
 
-func (s *UserBookService) UpdateComment(commentID, userID int64, content string) error {
-	return s.userBookRepo.UpdateComment(commentID, userID, content)
+// commentSnapshot is the audited state of a comment.
+func commentSnapshot(comment *domain.Comment) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id": comment.UserID,
+		"book_id": comment.BookID,
+		"content": comment.Content,
+	}
 }
 
-func (s *UserBookService) DeleteComment(commentID, userID int64) error {
-	return s.userBookRepo.DeleteComment(commentID, userID)
+func hasEdition(book *domain.Book, editionID int64) bool {
+	for _, edition := range book.Editions {
+		if edition.ID == editionID {
+			return true
+		}
+	}
+	return false
 }
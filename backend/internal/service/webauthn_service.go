@@ -0,0 +1,387 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/razvan/library-app/internal/domain"
+	"github.com/razvan/library-app/internal/repository"
+	"github.com/razvan/library-app/pkg/auth"
+)
+
+const (
+	webAuthnCeremonyTTL = 5 * time.Minute
+	webAuthnHandleSize  = 32
+)
+
+// WebAuthnService runs the registration and assertion ceremonies for security
+// keys and passkeys. Assertions can complete a password login as a second
+// factor or log a user in on their own with a discoverable passkey.
+type WebAuthnService struct {
+	webAuthn     *webauthn.WebAuthn
+	webAuthnRepo *repository.WebAuthnRepository
+	userRepo     *repository.UserRepository
+	authService  *AuthService
+	auditService *AuditService
+}
+
+type WebAuthnConfig struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+}
+
+// WebAuthnOptions is returned by the begin calls. Options must be passed to
+// navigator.credentials.create() or get(), and CeremonyID echoed back.
+type WebAuthnOptions struct {
+	CeremonyID string      `json:"ceremony_id"`
+	Options    interface{} `json:"options"`
+}
+
+func NewWebAuthnService(cfg WebAuthnConfig, webAuthnRepo *repository.WebAuthnRepository, userRepo *repository.UserRepository, authService *AuthService, auditService *AuditService) (*WebAuthnService, error) {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebAuthn configuration: %w", err)
+	}
+
+	return &WebAuthnService{
+		webAuthn:     wa,
+		webAuthnRepo: webAuthnRepo,
+		userRepo:     userRepo,
+		authService:  authService,
+		auditService: auditService,
+	}, nil
+}
+
+// webAuthnUser adapts a domain.User and its stored credentials to the
+// webauthn.User interface.
+type webAuthnUser struct {
+	user        *domain.User
+	handle      []byte
+	credentials []webauthn.Credential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte                         { return u.handle }
+func (u *webAuthnUser) WebAuthnName() string                       { return u.user.Email }
+func (u *webAuthnUser) WebAuthnDisplayName() string                { return u.user.Username }
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
+
+// Registration
+func (s *WebAuthnService) BeginRegistration(userID int64, name string) (*WebAuthnOptions, error) {
+	user, err := s.loadUser(userID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	creation, session, err := s.webAuthn.BeginRegistration(
+		user,
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin registration: %w", err)
+	}
+
+	ceremonyID, err := s.saveCeremony(userID, domain.WebAuthnPurposeRegister, session, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnOptions{CeremonyID: ceremonyID, Options: creation}, nil
+}
+
+func (s *WebAuthnService) FinishRegistration(userID int64, req *domain.WebAuthnFinish, client domain.ClientInfo) (*domain.WebAuthnCredential, error) {
+	ceremony, session, err := s.takeCeremony(req.CeremonyID, domain.WebAuthnPurposeRegister)
+	if err != nil {
+		return nil, err
+	}
+	if ceremony.UserID != userID {
+		return nil, fmt.Errorf("ceremony not found or expired")
+	}
+
+	user, err := s.loadUser(userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential: %w", err)
+	}
+
+	credential, err := s.webAuthn.CreateCredential(user, session.SessionData, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("registration failed: %w", err)
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode credential: %w", err)
+	}
+
+	stored := &domain.WebAuthnCredential{
+		UserID:       userID,
+		CredentialID: credential.ID,
+		Name:         session.Name,
+		Data:         data,
+	}
+	if err := s.webAuthnRepo.CreateCredential(stored); err != nil {
+		return nil, fmt.Errorf("failed to save credential: %w", err)
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditSecurityKeyAdded,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    userID,
+		Client:      client,
+		After:       map[string]interface{}{"credential_id": stored.ID, "name": stored.Name},
+	})
+
+	return stored, nil
+}
+
+func (s *WebAuthnService) GetCredentials(userID int64) ([]domain.WebAuthnCredential, error) {
+	return s.webAuthnRepo.GetUserCredentials(userID)
+}
+
+func (s *WebAuthnService) DeleteCredential(userID, credentialID int64, client domain.ClientInfo) error {
+	if err := s.webAuthnRepo.DeleteCredential(credentialID, userID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(AuditEntry{
+		ActorUserID: userID,
+		Action:      domain.AuditSecurityKeyRemoved,
+		TargetType:  domain.AuditTargetUser,
+		TargetID:    userID,
+		Client:      client,
+		Before:      map[string]interface{}{"credential_id": credentialID},
+	})
+
+	return nil
+}
+
+// Second factor
+func (s *WebAuthnService) BeginSecondFactor(challengeToken string, client domain.ClientInfo) (*WebAuthnOptions, error) {
+	_, domainUser, err := s.authService.openTwoFactorChallenge(challengeToken, client)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.loadUser(domainUser.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.credentials) == 0 {
+		return nil, fmt.Errorf("no security keys registered")
+	}
+
+	assertion, session, err := s.webAuthn.BeginLogin(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin assertion: %w", err)
+	}
+
+	ceremonyID, err := s.saveCeremony(domainUser.ID, domain.WebAuthnPurposeSecondFactor, session, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnOptions{CeremonyID: ceremonyID, Options: assertion}, nil
+}
+
+func (s *WebAuthnService) FinishSecondFactor(req *domain.WebAuthnFinish, client domain.ClientInfo) (*LoginResult, error) {
+	challenge, domainUser, err := s.authService.openTwoFactorChallenge(req.ChallengeToken, client)
+	if err != nil {
+		return nil, err
+	}
+
+	ceremony, session, err := s.takeCeremony(req.CeremonyID, domain.WebAuthnPurposeSecondFactor)
+	if err != nil {
+		return nil, err
+	}
+	if ceremony.UserID != domainUser.ID {
+		return nil, fmt.Errorf("ceremony not found or expired")
+	}
+
+	user, err := s.loadUser(domainUser.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authService.reserveTwoFactorAttempt(challenge); err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, s.authService.failTwoFactorChallenge(challenge, client)
+	}
+
+	credential, err := s.webAuthn.ValidateLogin(user, session.SessionData, parsed)
+	if err != nil || !s.recordAssertion(credential) {
+		return nil, s.authService.failTwoFactorChallenge(challenge, client)
+	}
+
+	return s.authService.completeTwoFactorChallenge(challenge, domainUser, client)
+}
+
+// Passwordless login
+func (s *WebAuthnService) BeginLogin() (*WebAuthnOptions, error) {
+	assertion, session, err := s.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin assertion: %w", err)
+	}
+
+	ceremonyID, err := s.saveCeremony(0, domain.WebAuthnPurposeLogin, session, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnOptions{CeremonyID: ceremonyID, Options: assertion}, nil
+}
+
+func (s *WebAuthnService) FinishLogin(req *domain.WebAuthnFinish, client domain.ClientInfo) (*LoginResult, error) {
+	_, session, err := s.takeCeremony(req.CeremonyID, domain.WebAuthnPurposeLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential: %w", err)
+	}
+
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := s.webAuthnRepo.GetUserIDByHandle(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		return s.loadUser(userID, false)
+	}
+
+	found, credential, err := s.webAuthn.ValidatePasskeyLogin(handler, session.SessionData, parsed)
+	if err != nil || !s.recordAssertion(credential) {
+		return nil, fmt.Errorf("passkey verification failed")
+	}
+
+	user := found.(*webAuthnUser).user
+	if err := s.authService.emailService.CheckLogin(user); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.authService.startSession(user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{Tokens: tokens, User: user}, nil
+}
+
+// loadUser builds the webauthn view of a user. When createHandle is set, a
+// random user handle is assigned if the user does not have one yet.
+func (s *WebAuthnService) loadUser(userID int64, createHandle bool) (*webAuthnUser, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	handle, err := s.webAuthnRepo.GetUserHandle(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(handle) == 0 {
+		if !createHandle {
+			return nil, fmt.Errorf("no security keys registered")
+		}
+
+		handle = make([]byte, webAuthnHandleSize)
+		if _, err := rand.Read(handle); err != nil {
+			return nil, fmt.Errorf("failed to generate user handle: %w", err)
+		}
+		if err := s.webAuthnRepo.SetUserHandle(userID, handle); err != nil {
+			return nil, fmt.Errorf("failed to save user handle: %w", err)
+		}
+	}
+
+	stored, err := s.webAuthnRepo.GetUserCredentials(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, c := range stored {
+		var credential webauthn.Credential
+		if err := json.Unmarshal(c.Data, &credential); err != nil {
+			return nil, fmt.Errorf("failed to decode credential: %w", err)
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return &webAuthnUser{user: user, handle: handle, credentials: credentials}, nil
+}
+
+// recordAssertion persists the updated signature counter. Assertions from
+// authenticators that look cloned are rejected.
+func (s *WebAuthnService) recordAssertion(credential *webauthn.Credential) bool {
+	if credential.Authenticator.CloneWarning {
+		return false
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return false
+	}
+
+	return s.webAuthnRepo.UpdateCredentialUsage(credential.ID, data) == nil
+}
+
+// ceremonyState is what gets stored between the begin and finish calls.
+type ceremonyState struct {
+	SessionData webauthn.SessionData `json:"session_data"`
+	Name        string               `json:"name,omitempty"`
+}
+
+func (s *WebAuthnService) saveCeremony(userID int64, purpose domain.WebAuthnPurpose, session *webauthn.SessionData, name string) (string, error) {
+	data, err := json.Marshal(ceremonyState{SessionData: *session, Name: name})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode ceremony: %w", err)
+	}
+
+	ceremony := &domain.WebAuthnCeremony{
+		ID:          auth.NewTokenID(),
+		UserID:      userID,
+		Purpose:     purpose,
+		SessionData: data,
+		ExpiresAt:   time.Now().Add(webAuthnCeremonyTTL),
+	}
+	if err := s.webAuthnRepo.CreateCeremony(ceremony); err != nil {
+		return "", fmt.Errorf("failed to save ceremony: %w", err)
+	}
+
+	return ceremony.ID, nil
+}
+
+func (s *WebAuthnService) takeCeremony(id string, purpose domain.WebAuthnPurpose) (*domain.WebAuthnCeremony, *ceremonyState, error) {
+	ceremony, err := s.webAuthnRepo.TakeCeremony(id, purpose)
+	if err != nil || time.Now().After(ceremony.ExpiresAt) {
+		return nil, nil, fmt.Errorf("ceremony not found or expired")
+	}
+
+	var state ceremonyState
+	if err := json.Unmarshal(ceremony.SessionData, &state); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode ceremony: %w", err)
+	}
+
+	return ceremony, &state, nil
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/razvan/library-app/internal/domain"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:3000"
+)
+
+// softAuthenticator is a passkey held in memory. It answers registration
+// and assertion options the way a browser and a platform authenticator
+// would, with "none" attestation and a P-256 key.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	counter      uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 32)
+	rand.Read(credentialID)
+
+	return &softAuthenticator{key: key, credentialID: credentialID}
+}
+
+// authenticatorData is the signed part of a response: the RP ID hash,
+// flags (user present and verified) and the signature counter, followed by
+// the credential and its public key when registering.
+func (a *softAuthenticator) authenticatorData(t *testing.T, attested bool) []byte {
+	t.Helper()
+
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	flags := protocol.FlagUserPresent | protocol.FlagUserVerified
+
+	var data bytes.Buffer
+	data.Write(rpIDHash[:])
+	if attested {
+		flags |= protocol.FlagAttestedCredentialData
+	}
+	data.WriteByte(byte(flags))
+	binary.Write(&data, binary.BigEndian, a.counter)
+
+	if attested {
+		publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+			PublicKeyData: webauthncose.PublicKeyData{
+				KeyType:   int64(webauthncose.EllipticKey),
+				Algorithm: int64(webauthncose.AlgES256),
+			},
+			Curve:  int64(webauthncose.P256),
+			XCoord: a.key.X.FillBytes(make([]byte, 32)),
+			YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data.Write(make([]byte, 16)) // AAGUID
+		binary.Write(&data, binary.BigEndian, uint16(len(a.credentialID)))
+		data.Write(a.credentialID)
+		data.Write(publicKey)
+	}
+
+	return data.Bytes()
+}
+
+func clientDataJSON(t *testing.T, ceremonyType string, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremonyType,
+		"challenge": challenge.String(),
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// register answers registration options with a new credential.
+func (a *softAuthenticator) register(t *testing.T, options *WebAuthnOptions) []byte {
+	t.Helper()
+
+	creation := options.Options.(*protocol.CredentialCreation)
+	a.userHandle = []byte(creation.Response.User.ID.(protocol.URLEncodedBase64))
+
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(t, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := base64.RawURLEncoding.EncodeToString
+	credential, err := json.Marshal(map[string]interface{}{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(clientDataJSON(t, "webauthn.create", creation.Response.Challenge)),
+			"attestationObject": encode(attestation),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credential
+}
+
+// assert answers assertion options, signing with the next counter value.
+func (a *softAuthenticator) assert(t *testing.T, options *WebAuthnOptions) []byte {
+	t.Helper()
+
+	assertion := options.Options.(*protocol.CredentialAssertion)
+	a.counter++
+
+	authData := a.authenticatorData(t, false)
+	clientData := clientDataJSON(t, "webauthn.get", assertion.Response.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := base64.RawURLEncoding.EncodeToString
+	credential, err := json.Marshal(map[string]interface{}{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode(a.userHandle),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credential
+}
+
+func newTestWebAuthnService(t *testing.T) (*WebAuthnService, *testServices) {
+	t.Helper()
+
+	services := newTestServices(t)
+	s, err := NewWebAuthnService(WebAuthnConfig{
+		RPID:          testRPID,
+		RPDisplayName: "LibraryApp",
+		RPOrigins:     []string{testOrigin},
+	}, services.webAuthnRepo, services.users, services.auth, services.audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, services
+}
+
+// registerPasskey adds a new passkey to the user.
+func registerPasskey(t *testing.T, s *WebAuthnService, user *domain.User) *softAuthenticator {
+	t.Helper()
+
+	options, err := s.BeginRegistration(user.ID, "Laptop")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	authenticator := newSoftAuthenticator(t)
+	finish := &domain.WebAuthnFinish{CeremonyID: options.CeremonyID, Credential: authenticator.register(t, options)}
+	if _, err := s.FinishRegistration(user.ID, finish, testClient); err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+	return authenticator
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	s, services := newTestWebAuthnService(t)
+	user := services.createUser(t, "reader@example.com", "correct-horse-battery")
+	other := services.createUser(t, "other@example.com", "correct-horse-battery")
+
+	// A ceremony cannot be finished by another user
+	options, err := s.BeginRegistration(user.ID, "Laptop")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	authenticator := newSoftAuthenticator(t)
+	finish := &domain.WebAuthnFinish{CeremonyID: options.CeremonyID, Credential: authenticator.register(t, options)}
+	if _, err := s.FinishRegistration(other.ID, finish, testClient); err == nil || err.Error() != "ceremony not found or expired" {
+		t.Fatalf("FinishRegistration() by another user error = %v", err)
+	}
+
+	options, err = s.BeginRegistration(user.ID, "Laptop")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	finish = &domain.WebAuthnFinish{CeremonyID: options.CeremonyID, Credential: authenticator.register(t, options)}
+	credential, err := s.FinishRegistration(user.ID, finish, testClient)
+	if err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+	if credential.UserID != user.ID || credential.Name != "Laptop" || !bytes.Equal(credential.CredentialID, authenticator.credentialID) {
+		t.Errorf("FinishRegistration() = %+v", credential)
+	}
+
+	// Each ceremony is finished once
+	if _, err := s.FinishRegistration(user.ID, finish, testClient); err == nil || err.Error() != "ceremony not found or expired" {
+		t.Errorf("FinishRegistration() with a used ceremony error = %v", err)
+	}
+
+	credentials, err := s.GetCredentials(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credentials) != 1 {
+		t.Errorf("GetCredentials() returned %d credentials, want 1", len(credentials))
+	}
+}
+
+func TestWebAuthnSecondFactor(t *testing.T) {
+	s, services := newTestWebAuthnService(t)
+	user := services.createUser(t, "reader@example.com", "correct-horse-battery")
+	authenticator := registerPasskey(t, s, user)
+
+	// The password is not enough once a security key is registered
+	login, err := services.auth.Login(&domain.UserLogin{Email: user.Email, Password: "correct-horse-battery"}, testClient)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if login.Tokens != nil || login.ChallengeToken == "" {
+		t.Fatalf("Login() = %+v, want a 2FA challenge", login)
+	}
+	var offered bool
+	for _, method := range login.SecondFactors {
+		offered = offered || method == "webauthn"
+	}
+	if !offered {
+		t.Fatalf("Login() second factors = %v, want webauthn", login.SecondFactors)
+	}
+
+	// A credential the user never registered is a failed attempt
+	options, err := s.BeginSecondFactor(login.ChallengeToken, testClient)
+	if err != nil {
+		t.Fatalf("BeginSecondFactor() error = %v", err)
+	}
+	unknown := newSoftAuthenticator(t)
+	unknown.userHandle = authenticator.userHandle
+	_, err = s.FinishSecondFactor(&domain.WebAuthnFinish{
+		CeremonyID:     options.CeremonyID,
+		ChallengeToken: login.ChallengeToken,
+		Credential:     unknown.assert(t, options),
+	}, testClient)
+	if err == nil || err.Error() != "invalid verification code" {
+		t.Fatalf("FinishSecondFactor() with an unknown credential error = %v", err)
+	}
+
+	options, err = s.BeginSecondFactor(login.ChallengeToken, testClient)
+	if err != nil {
+		t.Fatalf("BeginSecondFactor() error = %v", err)
+	}
+	finish := &domain.WebAuthnFinish{
+		CeremonyID:     options.CeremonyID,
+		ChallengeToken: login.ChallengeToken,
+		Credential:     authenticator.assert(t, options),
+	}
+	result, err := s.FinishSecondFactor(finish, testClient)
+	if err != nil {
+		t.Fatalf("FinishSecondFactor() error = %v", err)
+	}
+	if result.Tokens == nil || result.User.ID != user.ID {
+		t.Errorf("FinishSecondFactor() = %+v, want tokens for user %d", result, user.ID)
+	}
+
+	// The challenge is closed once it is passed
+	if _, err := s.BeginSecondFactor(login.ChallengeToken, testClient); err == nil || err.Error() != "invalid or expired 2FA challenge" {
+		t.Errorf("BeginSecondFactor() with a passed challenge error = %v", err)
+	}
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	s, services := newTestWebAuthnService(t)
+	user := services.createUser(t, "reader@example.com", "")
+	authenticator := registerPasskey(t, s, user)
+
+	options, err := s.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	finish := &domain.WebAuthnFinish{CeremonyID: options.CeremonyID, Credential: authenticator.assert(t, options)}
+	result, err := s.FinishLogin(finish, testClient)
+	if err != nil {
+		t.Fatalf("FinishLogin() error = %v", err)
+	}
+	if result.Tokens == nil || result.User.ID != user.ID {
+		t.Errorf("FinishLogin() = %+v, want tokens for user %d", result, user.ID)
+	}
+
+	// Each ceremony is finished once
+	if _, err := s.FinishLogin(finish, testClient); err == nil || err.Error() != "ceremony not found or expired" {
+		t.Errorf("FinishLogin() with a used ceremony error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		assertion func(options *WebAuthnOptions) []byte
+	}{
+		{
+			// A response signed for an earlier challenge
+			name:      "replayed response",
+			assertion: func(*WebAuthnOptions) []byte { return finish.Credential },
+		},
+		{
+			name: "unknown credential",
+			assertion: func(options *WebAuthnOptions) []byte {
+				unknown := newSoftAuthenticator(t)
+				unknown.userHandle = authenticator.userHandle
+				return unknown.assert(t, options)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, err := s.BeginLogin()
+			if err != nil {
+				t.Fatalf("BeginLogin() error = %v", err)
+			}
+			_, err = s.FinishLogin(&domain.WebAuthnFinish{CeremonyID: options.CeremonyID, Credential: tt.assertion(options)}, testClient)
+			if err == nil || err.Error() != "passkey verification failed" {
+				t.Errorf("FinishLogin() error = %v, want passkey verification failed", err)
+			}
+		})
+	}
+}
@@ -61,3 +61,12 @@ func generateRandomString(length int) string {
 func DeleteFile(filepath string) error {
 	return os.Remove(filepath)
 }
+
+// DeleteUploadedFile removes a file saved by SaveUploadedFile, given the URL
+// it is served under. URLs outside /uploads/ are ignored.
+func DeleteUploadedFile(uploadDir, url string) error {
+	if !strings.HasPrefix(url, "/uploads/") {
+		return nil
+	}
+	return DeleteFile(filepath.Join(uploadDir, filepath.Base(url)))
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustProxyHeaders makes ClientIP honour X-Forwarded-For and X-Real-IP.
+// Only enable it when the API is reachable exclusively through a proxy that
+// sets these headers, otherwise clients can spoof their address.
+var TrustProxyHeaders bool
+
+func ClientIP(r *http.Request) string {
+	if TrustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return strings.TrimSpace(realIP)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
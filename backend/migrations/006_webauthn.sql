@@ -0,0 +1,26 @@
+-- Opaque WebAuthn user handle, generated on first credential registration
+ALTER TABLE users ADD COLUMN IF NOT EXISTS webauthn_handle BYTEA UNIQUE;
+
+-- Registered security keys and passkeys. The full credential record is kept
+-- as JSON so that new authenticator fields do not require schema changes.
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id BYTEA UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    credential JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+-- State of in-flight registration and assertion ceremonies
+CREATE TABLE IF NOT EXISTS webauthn_ceremonies (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('register', 'second_factor', 'login')),
+    session_data JSONB NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,42 @@
+-- External identities (OpenID Connect) linked to local accounts
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- Google accounts used to be stored directly on the user row
+INSERT INTO user_identities (user_id, provider, subject, email)
+SELECT id, 'google', google_id, email FROM users WHERE google_id IS NOT NULL
+ON CONFLICT (provider, subject) DO NOTHING;
+
+ALTER TABLE users DROP COLUMN IF EXISTS google_id;
+
+-- State, nonce and PKCE verifier of in-flight authorization requests
+CREATE TABLE IF NOT EXISTS oidc_states (
+    state VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Identities waiting for the owner of the matching local account to confirm
+-- the link
+CREATE TABLE IF NOT EXISTS oidc_link_requests (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- When the last verification email was sent, used to rate limit resends
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMP WITH TIME ZONE;
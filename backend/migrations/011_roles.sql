@@ -0,0 +1,58 @@
+-- Roles and the permissions they grant
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    granted_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id);
+
+-- Built-in roles
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access, including user and role management'),
+    ('librarian', 'Manages the catalog of books and authors'),
+    ('moderator', 'Moderates comments')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (VALUES
+    ('admin', 'books:write'),
+    ('admin', 'authors:write'),
+    ('admin', 'comments:moderate'),
+    ('admin', 'users:manage'),
+    ('librarian', 'books:write'),
+    ('librarian', 'authors:write'),
+    ('moderator', 'comments:moderate')
+) AS p(role, permission) ON p.role = r.name
+ON CONFLICT DO NOTHING;
+
+-- Existing admins get the admin role, which replaces the is_admin flag
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'users' AND column_name = 'is_admin') THEN
+        INSERT INTO user_roles (user_id, role_id)
+        SELECT u.id, r.id FROM users u, roles r
+        WHERE u.is_admin AND r.name = 'admin'
+        ON CONFLICT DO NOTHING;
+
+        ALTER TABLE users DROP COLUMN is_admin;
+    END IF;
+END $$;
@@ -0,0 +1,5 @@
+-- Suspended accounts cannot log in or use their sessions and API keys
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspension_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
@@ -0,0 +1,16 @@
+-- Data export jobs. Each archive is written to DATA_EXPORT_PATH under
+-- file_name and removed, together with its row, once it expires.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    file_name VARCHAR(100) UNIQUE NOT NULL,
+    file_size BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at ON data_exports(expires_at);
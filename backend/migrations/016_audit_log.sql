@@ -0,0 +1,38 @@
+-- Append-only audit log of security and admin actions. Each row stores the
+-- hash of the row before it (see AuditEvent.ComputeHash). before and after
+-- are kept as text so that the hashed JSON is stored byte for byte.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_user_id BIGINT,
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32),
+    target_id BIGINT,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    before TEXT,
+    after TEXT,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) UNIQUE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor_user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+
+-- Actor and target ids are not foreign keys, so events outlive the users
+-- and books they mention. Rows can never be changed or removed.
+CREATE OR REPLACE FUNCTION reject_audit_event_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION reject_audit_event_change();
+
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_event_change();
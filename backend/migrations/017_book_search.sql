@@ -0,0 +1,70 @@
+-- Full-text search over books. search_vector weighs the title (A) above the
+-- author names (B) and the description (C), and is kept up to date by the
+-- triggers below. pg_trgm catches misspelled titles and author names when
+-- the full-text search finds nothing.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+CREATE OR REPLACE FUNCTION book_search_vector(book_id BIGINT, title TEXT, description TEXT)
+RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+           setweight(to_tsvector('english', COALESCE((
+               SELECT string_agg(a.name, ' ')
+               FROM authors a
+               JOIN book_authors ba ON a.id = ba.author_id
+               WHERE ba.book_id = book_search_vector.book_id
+           ), '')), 'B') ||
+           setweight(to_tsvector('english', COALESCE(description, '')), 'C');
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION update_book_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector = book_search_vector(NEW.id, NEW.title, NEW.description);
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_books_search_vector BEFORE INSERT OR UPDATE OF title, description ON books
+    FOR EACH ROW EXECUTE FUNCTION update_book_search_vector();
+
+-- Linking or unlinking an author changes the book's author names
+CREATE OR REPLACE FUNCTION refresh_book_search_vector_from_link()
+RETURNS TRIGGER AS $$
+DECLARE
+    link RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        link = OLD;
+    ELSE
+        link = NEW;
+    END IF;
+
+    UPDATE books SET search_vector = book_search_vector(id, title, description)
+    WHERE id = link.book_id;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER refresh_books_search_vector_on_link AFTER INSERT OR DELETE ON book_authors
+    FOR EACH ROW EXECUTE FUNCTION refresh_book_search_vector_from_link();
+
+-- Renaming an author changes every one of their books
+CREATE OR REPLACE FUNCTION refresh_book_search_vector_from_author()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE books SET search_vector = book_search_vector(id, title, description)
+    WHERE id IN (SELECT book_id FROM book_authors WHERE author_id = NEW.id);
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER refresh_books_search_vector_on_author AFTER UPDATE OF name ON authors
+    FOR EACH ROW EXECUTE FUNCTION refresh_book_search_vector_from_author();
+
+UPDATE books SET search_vector = book_search_vector(id, title, description);
+
+CREATE INDEX IF NOT EXISTS idx_books_search_vector ON books USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_books_title_trgm ON books USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_authors_name_trgm ON authors USING GIN (name gin_trgm_ops);
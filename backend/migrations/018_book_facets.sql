@@ -0,0 +1,43 @@
+-- Columns and tables the books listing filters, sorts and counts facets on
+
+-- Language of the book as a BCP 47 tag, such as "en" or "pt-BR"
+ALTER TABLE books ADD COLUMN IF NOT EXISTS language VARCHAR(16);
+
+CREATE INDEX IF NOT EXISTS idx_books_language ON books(language);
+CREATE INDEX IF NOT EXISTS idx_books_published_at ON books(published_at);
+
+CREATE TABLE IF NOT EXISTS genres (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS book_genres (
+    book_id BIGINT REFERENCES books(id) ON DELETE CASCADE,
+    genre_id BIGINT REFERENCES genres(id) ON DELETE CASCADE,
+    PRIMARY KEY (book_id, genre_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_genres_genre_id ON book_genres(genre_id);
+
+INSERT INTO genres (name) VALUES
+    ('Fiction'), ('Science Fiction'), ('Fantasy'), ('Mystery'), ('Thriller'),
+    ('Romance'), ('Horror'), ('Historical Fiction'), ('Biography'), ('History'),
+    ('Science'), ('Philosophy'), ('Poetry'), ('Children'), ('Young Adult'),
+    ('Self-Help'), ('Business'), ('Technology')
+ON CONFLICT (name) DO NOTHING;
+
+-- One rating from 1 to 5 stars per user and book
+CREATE TABLE IF NOT EXISTS book_ratings (
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    book_id BIGINT REFERENCES books(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_ratings_book_id ON book_ratings(book_id);
+
+CREATE TRIGGER update_book_ratings_updated_at BEFORE UPDATE ON book_ratings
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,13 @@
+-- Indexes matching the sort keys of the paginated lists, so that seeking to
+-- a cursor is an index range scan however deep the page is
+
+CREATE INDEX IF NOT EXISTS idx_books_created_at_id ON books(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_books_lower_title_id ON books(lower(title), id);
+CREATE INDEX IF NOT EXISTS idx_authors_name_id ON authors(name, id);
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_user_books_user_updated ON user_books(user_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_favorites_user_created ON favorites(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_comments_book_created ON comments(book_id, created_at, id);
+
+-- Superseded by the indexes above
+DROP INDEX IF EXISTS idx_users_created_at;
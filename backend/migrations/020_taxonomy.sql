@@ -0,0 +1,59 @@
+-- Genre hierarchy and free-form tags
+
+-- Genres form a tree, such as Fiction > Science Fiction. A genre with
+-- subgenres cannot be deleted until they are moved or deleted.
+ALTER TABLE genres ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES genres(id) ON DELETE RESTRICT;
+ALTER TABLE genres ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'genres_not_own_parent') THEN
+        ALTER TABLE genres ADD CONSTRAINT genres_not_own_parent CHECK (parent_id <> id);
+    END IF;
+END $$;
+
+-- Names are unique among siblings rather than across the whole tree
+ALTER TABLE genres DROP CONSTRAINT IF EXISTS genres_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_genres_parent_name ON genres(COALESCE(parent_id, 0), lower(name));
+CREATE INDEX IF NOT EXISTS idx_genres_parent_id ON genres(parent_id);
+
+DROP TRIGGER IF EXISTS update_genres_updated_at ON genres;
+CREATE TRIGGER update_genres_updated_at BEFORE UPDATE ON genres
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Arrange the seeded genres under Fiction and Non-fiction
+INSERT INTO genres (name)
+SELECT 'Non-fiction'
+WHERE NOT EXISTS (SELECT 1 FROM genres WHERE parent_id IS NULL AND lower(name) = 'non-fiction');
+
+UPDATE genres g
+SET parent_id = p.id
+FROM genres p
+WHERE g.parent_id IS NULL
+  AND p.parent_id IS NULL
+  AND (
+    (p.name = 'Fiction' AND g.name IN ('Science Fiction', 'Fantasy', 'Mystery', 'Thriller', 'Romance', 'Horror', 'Historical Fiction'))
+    OR (p.name = 'Non-fiction' AND g.name IN ('Biography', 'History', 'Science', 'Philosophy', 'Self-Help', 'Business', 'Technology'))
+  );
+
+-- Tags are free-form labels, unique regardless of case
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags(lower(name));
+
+CREATE TABLE IF NOT EXISTS book_tags (
+    book_id BIGINT REFERENCES books(id) ON DELETE CASCADE,
+    tag_id BIGINT REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (book_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_tags_tag_id ON book_tags(tag_id);
+
+-- Only admins manage the taxonomy; librarians assign existing genres and tags
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'taxonomy:manage' FROM roles WHERE name = 'admin'
+ON CONFLICT DO NOTHING;
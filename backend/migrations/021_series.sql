@@ -0,0 +1,23 @@
+-- Book series, such as The Expanse, with their volumes in reading order
+
+CREATE TABLE IF NOT EXISTS series (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_series_name ON series(lower(name), id);
+
+CREATE TRIGGER update_series_updated_at BEFORE UPDATE ON series
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- A book belongs to at most one series. Positions allow decimals so that a
+-- novella can sit between two volumes, such as 2.5 between books 2 and 3.
+CREATE TABLE IF NOT EXISTS series_books (
+    book_id BIGINT PRIMARY KEY REFERENCES books(id) ON DELETE CASCADE,
+    series_id BIGINT NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    position NUMERIC(6, 2) NOT NULL CHECK (position > 0),
+    UNIQUE (series_id, position)
+);
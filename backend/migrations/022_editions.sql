@@ -0,0 +1,43 @@
+-- Works and editions. A books row is now the work, with its title, authors
+-- and subjects, and comments, ratings and favorites stay on it. Each
+-- printing, translation or format of the work is an edition.
+
+CREATE TABLE IF NOT EXISTS editions (
+    id BIGSERIAL PRIMARY KEY,
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    isbn VARCHAR(13),
+    publisher VARCHAR(255) NOT NULL DEFAULT '',
+    format VARCHAR(20) NOT NULL DEFAULT ''
+        CHECK (format IN ('', 'hardcover', 'paperback', 'ebook', 'audiobook')),
+    language VARCHAR(16),
+    page_count INTEGER CHECK (page_count > 0),
+    published_at DATE,
+    cover_url VARCHAR(500),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The book's first edition, its primary one, comes first by ID
+CREATE INDEX IF NOT EXISTS idx_editions_book_id ON editions(book_id, id);
+CREATE INDEX IF NOT EXISTS idx_editions_isbn ON editions(isbn);
+CREATE INDEX IF NOT EXISTS idx_editions_language ON editions(lower(language));
+CREATE INDEX IF NOT EXISTS idx_editions_published_at ON editions(published_at);
+
+CREATE TRIGGER update_editions_updated_at BEFORE UPDATE ON editions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Every existing book becomes a work with a single edition
+INSERT INTO editions (book_id, isbn, language, published_at, cover_url, created_at)
+SELECT id, NULLIF(isbn, ''), language, published_at, NULLIF(cover_url, ''), created_at
+FROM books
+WHERE NOT EXISTS (SELECT 1 FROM editions WHERE editions.book_id = books.id);
+
+ALTER TABLE books
+    DROP COLUMN IF EXISTS isbn,
+    DROP COLUMN IF EXISTS language,
+    DROP COLUMN IF EXISTS published_at,
+    DROP COLUMN IF EXISTS cover_url;
+
+-- The edition a reader owns or reads, when they say
+ALTER TABLE user_books
+    ADD COLUMN IF NOT EXISTS edition_id BIGINT REFERENCES editions(id) ON DELETE SET NULL;
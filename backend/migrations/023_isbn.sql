@@ -0,0 +1,66 @@
+-- ISBNs are stored as bare ISBN-13s, unique across editions
+
+-- normalize_isbn turns an ISBN-10 or ISBN-13, with or without hyphens or
+-- spaces, into a bare ISBN-13. It returns NULL when the checksum is wrong.
+CREATE OR REPLACE FUNCTION normalize_isbn(isbn TEXT)
+RETURNS TEXT AS $$
+DECLARE
+    digits TEXT := upper(regexp_replace(isbn, '[- ]', '', 'g'));
+    isbn13 TEXT;
+    total INT := 0;
+    i INT;
+BEGIN
+    IF digits ~ '^[0-9]{9}[0-9X]$' THEN
+        FOR i IN 1..10 LOOP
+            total := total + (11 - i) *
+                CASE WHEN substr(digits, i, 1) = 'X' THEN 10 ELSE substr(digits, i, 1)::int END;
+        END LOOP;
+        IF total % 11 <> 0 THEN
+            RETURN NULL;
+        END IF;
+        isbn13 := '978' || substr(digits, 1, 9);
+    ELSIF digits ~ '^[0-9]{13}$' THEN
+        isbn13 := substr(digits, 1, 12);
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    total := 0;
+    FOR i IN 1..12 LOOP
+        total := total + CASE WHEN i % 2 = 0 THEN 3 ELSE 1 END * substr(isbn13, i, 1)::int;
+    END LOOP;
+    isbn13 := isbn13 || ((10 - total % 10) % 10)::text;
+
+    -- An ISBN-13 has to carry the check digit it computes to
+    IF length(digits) = 13 AND digits <> isbn13 THEN
+        RETURN NULL;
+    END IF;
+    RETURN isbn13;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Invalid ISBNs are cleared, and of editions sharing an ISBN only the
+-- oldest keeps it. The others keep the ISBN as it was in legacy_isbn, for
+-- librarians to correct; it is cleared once the edition gets an ISBN.
+ALTER TABLE editions ADD COLUMN IF NOT EXISTS legacy_isbn TEXT;
+
+-- normalize_isbn runs once per edition, and duplicates are found by
+-- numbering the editions of each ISBN.
+WITH normalized AS (
+    SELECT id, normalize_isbn(isbn) AS norm
+    FROM editions
+    WHERE isbn IS NOT NULL
+), ranked AS (
+    SELECT id, norm, row_number() OVER (PARTITION BY norm ORDER BY id) AS position
+    FROM normalized
+)
+UPDATE editions e
+SET legacy_isbn = CASE WHEN r.norm IS NULL OR r.position > 1 THEN e.isbn END,
+    isbn = CASE WHEN r.norm IS NOT NULL AND r.position = 1 THEN r.norm END
+FROM ranked r
+WHERE r.id = e.id;
+
+CREATE INDEX IF NOT EXISTS idx_editions_legacy_isbn ON editions(id) WHERE legacy_isbn IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_editions_isbn;
+CREATE UNIQUE INDEX IF NOT EXISTS editions_isbn_key ON editions(isbn);
@@ -0,0 +1,43 @@
+-- Bulk catalog imports. Each uploaded file is kept in CATALOG_IMPORT_PATH
+-- under file_name until its job finishes.
+CREATE TABLE IF NOT EXISTS catalog_imports (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    format VARCHAR(20) NOT NULL CHECK (format IN ('csv', 'marc21', 'marcxml', 'onix')),
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    mapping JSONB,
+    source_name VARCHAR(255) NOT NULL DEFAULT '',
+    file_name VARCHAR(100) UNIQUE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total INT NOT NULL DEFAULT 0,
+    processed INT NOT NULL DEFAULT 0,
+    created INT NOT NULL DEFAULT 0,
+    updated INT NOT NULL DEFAULT 0,
+    unchanged INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_imports_status ON catalog_imports(status);
+
+-- Records that were skipped, with the reason
+CREATE TABLE IF NOT EXISTS catalog_import_errors (
+    id BIGSERIAL PRIMARY KEY,
+    import_id BIGINT NOT NULL REFERENCES catalog_imports(id) ON DELETE CASCADE,
+    record INT NOT NULL,
+    isbn TEXT, -- as in the file, which may not be a valid ISBN
+    message TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_import_errors_import_id ON catalog_import_errors(import_id, id);
+
+-- Imports match authors by name
+CREATE INDEX IF NOT EXISTS idx_authors_lower_name ON authors(lower(name));
+
+-- Imports create books and authors, so librarians and admins may run them
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'catalog:import' FROM roles WHERE name IN ('admin', 'librarian')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+)
+
+// breachedPasswords is a list of the most common passwords found in public
+// data breaches, one per line.
+//
+//go:embed breached_passwords.txt
+var breachedPasswords string
+
+// PasswordList is a set of passwords that must not be used. Entries are
+// compared case-insensitively.
+type PasswordList struct {
+	passwords map[string]struct{}
+}
+
+// BundledBreachedPasswords returns the breached passwords shipped with the
+// app.
+func BundledBreachedPasswords() *PasswordList {
+	list := &PasswordList{passwords: make(map[string]struct{})}
+	list.read(strings.NewReader(breachedPasswords))
+	return list
+}
+
+// AddFile adds the passwords in a text file, one per line.
+func (l *PasswordList) AddFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return l.read(f)
+}
+
+func (l *PasswordList) Contains(password string) bool {
+	_, ok := l.passwords[strings.ToLower(password)]
+	return ok
+}
+
+func (l *PasswordList) Len() int {
+	return len(l.passwords)
+}
+
+func (l *PasswordList) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			l.passwords[strings.ToLower(password)] = struct{}{}
+		}
+	}
+	return scanner.Err()
+}
@@ -1,64 +1,101 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	TokenTypeAccess             = "access"
+	TokenTypeRefresh            = "refresh"
+	TokenTypeTwoFactorChallenge = "2fa_challenge"
+	TokenTypeEmailVerification  = "email_verification"
+
+	AccessTokenTTL            = 24 * time.Hour
+	RefreshTokenTTL           = 7 * 24 * time.Hour
+	EmailVerificationTokenTTL = 24 * time.Hour
+)
+
 type Claims struct {
-	UserID  int64  `json:"user_id"`
-	Email   string `json:"email"`
-	IsAdmin bool   `json:"is_admin"`
+	UserID      int64    `json:"user_id"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"perms,omitempty"`
+	TokenType   string   `json:"typ"`
+	SessionID   string   `json:"sid"`
 	jwt.RegisteredClaims
 }
 
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+
+	// Server-side bookkeeping for the refresh token, never sent to clients
+	RefreshTokenID   string    `json:"-"`
+	RefreshExpiresAt time.Time `json:"-"`
 }
 
-func GenerateTokenPair(userID int64, email string, isAdmin bool, secret string) (*TokenPair, error) {
+// GenerateTokenPair signs an access and a refresh token with the active key
+// of the key set, so other services can verify access tokens through the
+// JWKS without holding a secret.
+func GenerateTokenPair(userID int64, email string, roles, permissions []string, sessionID string, keys *KeySet) (*TokenPair, error) {
+	now := time.Now()
+
 	// Access token (24 hours)
 	accessClaims := &Claims{
-		UserID:  userID,
-		Email:   email,
-		IsAdmin: isAdmin,
+		UserID:      userID,
+		Email:       email,
+		Roles:       roles,
+		Permissions: permissions,
+		TokenType:   TokenTypeAccess,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        NewTokenID(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(secret))
+	accessTokenString, err := keys.sign(accessClaims)
 	if err != nil {
 		return nil, err
 	}
 
 	// Refresh token (7 days)
+	refreshExpiresAt := now.Add(RefreshTokenTTL)
 	refreshClaims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		TokenType: TokenTypeRefresh,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        NewTokenID(),
+			ExpiresAt: jwt.NewNumericDate(refreshExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(secret))
+	refreshTokenString, err := keys.sign(refreshClaims)
 	if err != nil {
 		return nil, err
 	}
 
 	return &TokenPair{
-		AccessToken:  accessTokenString,
-		RefreshToken: refreshTokenString,
+		AccessToken:      accessTokenString,
+		RefreshToken:     refreshTokenString,
+		RefreshTokenID:   refreshClaims.ID,
+		RefreshExpiresAt: refreshExpiresAt,
 	}, nil
 }
 
+// ValidateToken validates a token signed with the HMAC secret. Tokens that
+// only this server ever reads, such as 2FA challenges and email verification
+// links, use the secret, so services trusting the JWKS can never accept them.
 func ValidateToken(tokenString, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -77,3 +114,92 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// GenerateChallengeToken signs a short-lived token proving that the user
+// passed the password check. The challenge id is stored in the jti claim.
+func GenerateChallengeToken(userID int64, challengeID string, expiresAt time.Time, secret string) (string, error) {
+	claims := &Claims{
+		UserID:    userID,
+		TokenType: TokenTypeTwoFactorChallenge,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        challengeID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// ValidateChallengeToken validates a token issued by GenerateChallengeToken.
+func ValidateChallengeToken(tokenString, secret string) (*Claims, error) {
+	claims, err := ValidateToken(tokenString, secret)
+	return checkTokenType(claims, err, TokenTypeTwoFactorChallenge)
+}
+
+// GenerateEmailVerificationToken signs a token confirming that the user can
+// read mail sent to the given address. It stops working if the address on
+// the account changes.
+func GenerateEmailVerificationToken(userID int64, email, secret string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID:    userID,
+		Email:     email,
+		TokenType: TokenTypeEmailVerification,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        NewTokenID(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(EmailVerificationTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// ValidateEmailVerificationToken validates a token issued by GenerateEmailVerificationToken.
+func ValidateEmailVerificationToken(tokenString, secret string) (*Claims, error) {
+	claims, err := ValidateToken(tokenString, secret)
+	return checkTokenType(claims, err, TokenTypeEmailVerification)
+}
+
+// ValidateAccessToken validates a token and makes sure it was issued as an access token.
+func ValidateAccessToken(tokenString string, keys *KeySet) (*Claims, error) {
+	claims, err := keys.parse(tokenString)
+	return checkTokenType(claims, err, TokenTypeAccess)
+}
+
+// ValidateRefreshToken validates a token and makes sure it was issued as a refresh token.
+func ValidateRefreshToken(tokenString string, keys *KeySet) (*Claims, error) {
+	claims, err := keys.parse(tokenString)
+	return checkTokenType(claims, err, TokenTypeRefresh)
+}
+
+func checkTokenType(claims *Claims, err error, tokenType string) (*Claims, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != tokenType {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
+}
+
+// NewTokenID returns a random identifier suitable for the jti claim.
+func NewTokenID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// HashToken returns the hex SHA-256 digest of an opaque token, so that
+// tokens which are only ever looked up can be stored hashed.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSAKeyBits = 2048
+
+// KeySet signs tokens with one active private key and verifies them with any
+// of its public keys. Rotating keys means adding the new key everywhere
+// first, then making it active, and removing the old one once all tokens
+// signed with it have expired.
+type KeySet struct {
+	activeID string
+	signer   crypto.Signer
+	keys     map[string]verificationKey
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet reads every <kid>.pem file in dir. Private keys (PKCS#8, or
+// PKCS#1 for RSA) can sign and verify; public keys (PKIX) of retired keys
+// can only verify. Ed25519 keys sign with EdDSA, RSA keys with RS256.
+func LoadKeySet(dir, activeID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no .pem keys found in %s", dir)
+	}
+
+	ks := &KeySet{keys: make(map[string]verificationKey)}
+	signers := make(map[string]crypto.Signer)
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		signer, public, err := parseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		method, err := signingMethodFor(public)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", kid, err)
+		}
+
+		ks.keys[kid] = verificationKey{method: method, public: public}
+		if signer != nil {
+			signers[kid] = signer
+		}
+	}
+
+	// With a single private key there is nothing to choose from
+	if activeID == "" && len(signers) == 1 {
+		for kid := range signers {
+			activeID = kid
+		}
+	}
+
+	signer, ok := signers[activeID]
+	if activeID == "" {
+		return nil, fmt.Errorf("%d private keys found in %s, choose the active one", len(signers), dir)
+	}
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q is not a private key in %s", activeID, dir)
+	}
+
+	ks.activeID = activeID
+	ks.signer = signer
+	return ks, nil
+}
+
+// GenerateKeySet returns a key set with a new Ed25519 key that only lives in
+// memory. Tokens signed with it stop working when the process exits, so it is
+// only meant for development.
+func GenerateKeySet() (*KeySet, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := "dev-" + NewTokenID()[:8]
+	return &KeySet{
+		activeID: kid,
+		signer:   private,
+		keys: map[string]verificationKey{
+			kid: {method: jwt.SigningMethodEdDSA, public: public},
+		},
+	}, nil
+}
+
+func parseKey(data []byte) (crypto.Signer, crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("no PEM data found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, nil, errors.New("unsupported private key type")
+		}
+		return signer, signer.Public(), nil
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return key, key.Public(), nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, key, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+func signingMethodFor(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := public.(type) {
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA keys must have at least %d bits", minRSAKeyBits)
+		}
+		return jwt.SigningMethodRS256, nil
+	default:
+		return nil, errors.New("only Ed25519 and RSA keys are supported")
+	}
+}
+
+// ActiveKeyID returns the kid of the key new tokens are signed with.
+func (ks *KeySet) ActiveKeyID() string {
+	return ks.activeID
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.keys[ks.activeID].method, claims)
+	token.Header["kid"] = ks.activeID
+	return token.SignedString(ks.signer)
+}
+
+func (ks *KeySet) parse(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		// Never let the token pick the algorithm for a key
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("invalid signing method")
+		}
+		return key.public, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid token")
+}
+
+// JWKS returns the public keys, for services that verify tokens themselves.
+func (ks *KeySet) JWKS() JWKS {
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		key := ks.keys[kid]
+		jwk := JWK{KeyID: kid, Algorithm: key.method.Alg(), Use: "sig"}
+
+		switch public := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
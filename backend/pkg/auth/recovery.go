@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+const (
+	RecoveryCodeCount = 10
+
+	// Unambiguous lowercase alphabet: no 0/o, 1/l/i
+	recoveryCodeCharset = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeHalf    = 5
+)
+
+// GenerateRecoveryCodes returns a set of random one-time codes formatted as
+// "xxxxx-xxxxx".
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, count)
+	for i := range codes {
+		b := make([]byte, recoveryCodeHalf*2)
+		for j := range b {
+			c, err := randomCharacter()
+			if err != nil {
+				return nil, err
+			}
+			b[j] = c
+		}
+		codes[i] = string(b[:recoveryCodeHalf]) + "-" + string(b[recoveryCodeHalf:])
+	}
+
+	return codes, nil
+}
+
+// randomCharacter picks a character of the charset uniformly. Taking a
+// random byte modulo the charset size would favor its first characters.
+func randomCharacter() (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeCharset))))
+	if err != nil {
+		return 0, err
+	}
+	return recoveryCodeCharset[n.Int64()], nil
+}
+
+// HashRecoveryCode normalizes a recovery code and hashes it for storage.
+// Codes carry enough entropy that a fast hash is sufficient.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(code)
+	normalized = strings.NewReplacer("-", "", " ", "").Replace(normalized)
+
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
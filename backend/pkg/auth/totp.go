@@ -1,13 +1,22 @@
 package auth
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
+	"image/png"
+	"time"
 
 	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 )
 
+const (
+	totpPeriod = 30
+	totpSkew   = 1
+)
+
 func GenerateTOTPSecret(email, issuer string) (string, string, error) {
 	key, err := totp.Generate(totp.GenerateOpts{
 		Issuer:      issuer,
@@ -18,14 +27,17 @@ func GenerateTOTPSecret(email, issuer string) (string, string, error) {
 	}
 
 	// Convert to PNG QR code
-	var buf []byte
+	var buf bytes.Buffer
 	img, err := key.Image(200, 200)
 	if err != nil {
 		return "", "", err
 	}
+	if err := png.Encode(&buf, img); err != nil {
+		return "", "", err
+	}
 
 	// Convert image to base64
-	qrCode := base64.StdEncoding.EncodeToString(buf)
+	qrCode := base64.StdEncoding.EncodeToString(buf.Bytes())
 
 	return key.Secret(), fmt.Sprintf("data:image/png;base64,%s", qrCode), nil
 }
@@ -34,6 +46,35 @@ func ValidateTOTP(code, secret string) bool {
 	return totp.Validate(code, secret)
 }
 
+// ValidateTOTPStep validates a code and returns the time step it matched.
+// Steps at or before lastStep are rejected so that a code that was already
+// accepted cannot be replayed within its validity window.
+func ValidateTOTPStep(code, secret string, lastStep int64) (int64, bool) {
+	now := time.Now().Unix() / totpPeriod
+	opts := totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), opts)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
 func GenerateTOTPURL(secret, email, issuer string) string {
 	return fmt.Sprintf("otpauth://totp/%s:%s?secret=%s&issuer=%s",
 		issuer, email, secret, issuer)
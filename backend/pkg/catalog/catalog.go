@@ -0,0 +1,199 @@
+// Package catalog reads bibliographic records from the files publishers and
+// other library systems exchange: CSV, MARC21 (binary and MARCXML) and
+// ONIX 3.0.
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Formats of catalog files
+const (
+	FormatCSV     = "csv"
+	FormatMARC21  = "marc21"
+	FormatMARCXML = "marcxml"
+	FormatONIX    = "onix"
+)
+
+// Record is one edition of a book as read from a file. Fields the file does
+// not have are left empty.
+type Record struct {
+	// Number is the position of the record in the file, starting at 1. For
+	// CSV it is the line the row starts on, which is the row number a
+	// spreadsheet shows, the header being row 1.
+	Number      int
+	ISBN        string
+	Title       string
+	Description string
+	Authors     []string
+	Publisher   string
+	// Format is hardcover, paperback, ebook or audiobook.
+	Format   string
+	Language string
+	// PageCount is 0 when unknown.
+	PageCount int
+	// PublishedAt is YYYY-MM-DD. Files that only give the year or month
+	// are completed with the first day.
+	PublishedAt string
+}
+
+// RecordError is a record that could not be read. Only the record is
+// skipped, and reading continues with the next one.
+type RecordError struct {
+	Number int
+	ISBN   string
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d: %v", e.Number, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads the records of a file in order.
+type Reader interface {
+	// Read returns the next record, or io.EOF after the last one. A
+	// *RecordError only concerns that record; any other error means the
+	// rest of the file cannot be read.
+	Read() (*Record, error)
+}
+
+var ErrUnknownFormat = errors.New("unknown catalog format")
+
+// NewReader reads a file of the format. The mapping is only used for CSV,
+// see NewCSVReader.
+func NewReader(format string, r io.Reader, mapping Mapping) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(r, mapping)
+	case FormatMARC21:
+		return NewMARCReader(r), nil
+	case FormatMARCXML:
+		return NewMARCXMLReader(r), nil
+	case FormatONIX:
+		return NewONIXReader(r), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// cleanText collapses runs of whitespace and trims the ISBD punctuation
+// that catalogers put at the end of a field, as in "Dune /".
+func cleanText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.TrimRight(s, " /:;,=")
+}
+
+// cleanName cleans a person's name and turns the inverted form "Herbert,
+// Frank" into "Frank Herbert".
+func cleanName(name string) string {
+	name = cleanText(name)
+	if strings.HasSuffix(name, ".") && !endsWithInitial(name) {
+		name = strings.TrimSuffix(name, ".")
+	}
+	name = strings.TrimRight(name, " ,")
+
+	if last, first, ok := strings.Cut(name, ", "); ok && !strings.Contains(first, ",") {
+		name = first + " " + last
+	}
+	return name
+}
+
+// endsWithInitial tells whether a final full stop belongs to an initial, as
+// in "Le Guin, Ursula K.", rather than ending the field.
+func endsWithInitial(name string) bool {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return false
+	}
+	return len([]rune(strings.TrimSuffix(fields[len(fields)-1], "."))) == 1
+}
+
+var (
+	datePattern = regexp.MustCompile(`^(\d{4})(?:-?(\d{2}))?(?:-?(\d{2}))?$`)
+	yearPattern = regexp.MustCompile(`\b(1[5-9]\d{2}|20\d{2})\b`)
+)
+
+// normalizeDate accepts YYYY, YYYY-MM and YYYY-MM-DD, with or without
+// hyphens, and returns YYYY-MM-DD.
+func normalizeDate(date string) (string, bool) {
+	match := datePattern.FindStringSubmatch(strings.TrimSpace(date))
+	if match == nil {
+		return "", false
+	}
+
+	month, day := match[2], match[3]
+	if month == "" {
+		month = "01"
+	}
+	if day == "" {
+		day = "01"
+	}
+	return match[1] + "-" + month + "-" + day, true
+}
+
+// findYear returns the first plausible year in text such as "c2005." as
+// YYYY-01-01.
+func findYear(text string) string {
+	if year := yearPattern.FindString(text); year != "" {
+		return year + "-01-01"
+	}
+	return ""
+}
+
+// normalizeLanguage turns ISO 639 codes, including the three letter ones
+// MARC and ONIX use, into the shortest BCP 47 tag, such as "en" for "eng".
+// Codes for no, several or an undetermined language are dropped.
+func normalizeLanguage(code string) string {
+	code = strings.TrimSpace(code)
+	switch strings.ToLower(code) {
+	case "", "und", "mul", "zxx", "mis":
+		return ""
+	}
+
+	tag, err := language.Parse(code)
+	if err != nil {
+		return code
+	}
+	return tag.String()
+}
+
+// formatWords are the words that give away an edition's format in MARC
+// ISBN qualifiers and CSV files.
+var formatWords = []struct {
+	format string
+	words  []string
+}{
+	{"audiobook", []string{"audio", "cd", "mp3"}},
+	{"ebook", []string{"ebook", "e-book", "electronic", "epub", "pdf", "kindle", "online"}},
+	{"paperback", []string{"paperback", "pbk", "softcover", "paper"}},
+	{"hardcover", []string{"hardcover", "hardback", "hbk", "hc", "cloth", "hard"}},
+}
+
+// normalizeFormat recognizes the format in a free-form description such as
+// "pbk." or "Hardback".
+func normalizeFormat(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-')
+	})
+
+	for _, candidate := range formatWords {
+		for _, word := range words {
+			for _, known := range candidate.words {
+				if word == known {
+					return candidate.format
+				}
+			}
+		}
+	}
+	return ""
+}
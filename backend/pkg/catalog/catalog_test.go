@@ -0,0 +1,136 @@
+package catalog
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// readAll reads the records and record errors of a file until io.EOF.
+func readAll(t *testing.T, reader Reader) ([]*Record, []*RecordError) {
+	t.Helper()
+
+	var records []*Record
+	var recordErrors []*RecordError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records, recordErrors
+		}
+		var recordErr *RecordError
+		if errors.As(err, &recordErr) {
+			recordErrors = append(recordErrors, recordErr)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func assertRecord(t *testing.T, got, want *Record) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("record =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Herbert, Frank", want: "Frank Herbert"},
+		{name: "Herbert, Frank.", want: "Frank Herbert"},
+		{name: "Le Guin, Ursula K.", want: "Ursula K. Le Guin"},
+		{name: "Le Guin, Ursula K.,", want: "Ursula K. Le Guin"},
+		{name: "Tolkien, J. R. R.", want: "J. R. R. Tolkien"},
+		{name: "Ursula K. Le Guin", want: "Ursula K. Le Guin"},
+		{name: "  Frank   Herbert /", want: "Frank Herbert"},
+		{name: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := cleanName(tt.name); got != tt.want {
+			t.Errorf("cleanName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+		ok   bool
+	}{
+		{date: "2019", want: "2019-01-01", ok: true},
+		{date: "2019-03", want: "2019-03-01", ok: true},
+		{date: "201903", want: "2019-03-01", ok: true},
+		{date: "2019-03-15", want: "2019-03-15", ok: true},
+		{date: "20190315", want: "2019-03-15", ok: true},
+		{date: " 2019 ", want: "2019-01-01", ok: true},
+		{date: "2019-3-15"},
+		{date: "March 2019"},
+		{date: "19"},
+		{date: ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := normalizeDate(tt.date)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("normalizeDate(%q) = %q, %v, want %q, %v", tt.date, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeFormat(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "pbk.", want: "paperback"},
+		{text: "Paperback", want: "paperback"},
+		{text: "Hardback", want: "hardcover"},
+		{text: "(hbk.)", want: "hardcover"},
+		{text: "e-book", want: "ebook"},
+		{text: "EPUB", want: "ebook"},
+		{text: "Audio CD", want: "audiobook"},
+		{text: "large print", want: ""},
+		{text: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeFormat(tt.text); got != tt.want {
+			t.Errorf("normalizeFormat(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "eng", want: "en"},
+		{code: "fre", want: "fr"},
+		{code: "de", want: "de"},
+		{code: "und", want: ""},
+		{code: "mul", want: ""},
+		{code: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeLanguage(tt.code); got != tt.want {
+			t.Errorf("normalizeLanguage(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestNewReaderUnknownFormat(t *testing.T) {
+	if _, err := NewReader("xlsx", nil, nil); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("NewReader() error = %v, want ErrUnknownFormat", err)
+	}
+}
@@ -0,0 +1,191 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Fields a CSV column can be mapped to
+const (
+	FieldISBN        = "isbn"
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldAuthors     = "authors"
+	FieldPublisher   = "publisher"
+	FieldFormat      = "format"
+	FieldLanguage    = "language"
+	FieldPageCount   = "page_count"
+	FieldPublishedAt = "published_at"
+)
+
+var csvFields = []string{
+	FieldISBN, FieldTitle, FieldDescription, FieldAuthors, FieldPublisher,
+	FieldFormat, FieldLanguage, FieldPageCount, FieldPublishedAt,
+}
+
+// requiredFields must have a column, since records cannot be imported
+// without them.
+var requiredFields = []string{FieldISBN, FieldTitle, FieldAuthors}
+
+// Mapping maps record fields to the CSV columns they are read from, by
+// header name, such as {"isbn": "ISBN13", "authors": "Author(s)"}. Fields
+// that are not mapped are read from the column named after the field.
+// Header names are matched case-insensitively.
+type Mapping map[string]string
+
+// Validate checks that every mapped field exists.
+func (m Mapping) Validate() error {
+	for field := range m {
+		if !slices.Contains(csvFields, field) {
+			return fmt.Errorf("unknown field %q in the column mapping", field)
+		}
+	}
+	return nil
+}
+
+// authorSeparator separates the authors in the authors column.
+const authorSeparator = ";"
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+// NewCSVReader reads a comma-separated file with a header row. Several
+// authors are separated by semicolons in their column. It fails when a
+// column of the mapping, or one of the isbn, title and authors columns, is
+// missing from the header.
+func NewCSVReader(r io.Reader, mapping Mapping) (Reader, error) {
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CSV header: %w", err)
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := positions[name]; !ok {
+			positions[name] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	for _, field := range csvFields {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+
+		position, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		if ok {
+			columns[field] = position
+		} else if mapped {
+			return nil, fmt.Errorf("column %q mapped to %s is not in the CSV header", name, field)
+		}
+	}
+
+	for _, field := range requiredFields {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("the CSV file has no %s column; map one with the column mapping", field)
+		}
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvReader) Read() (*Record, error) {
+	row, err := r.reader.Read()
+	for err == nil && isBlankRow(row) {
+		row, err = r.reader.Read()
+	}
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &RecordError{Number: parseErr.StartLine, Err: parseErr.Err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	line, _ := r.reader.FieldPos(0)
+
+	value := func(field string) string {
+		position, ok := r.columns[field]
+		if !ok || position >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[position])
+	}
+
+	record := &Record{
+		Number:      line,
+		ISBN:        value(FieldISBN),
+		Title:       cleanText(value(FieldTitle)),
+		Description: strings.TrimSpace(value(FieldDescription)),
+		Publisher:   cleanText(value(FieldPublisher)),
+		Language:    normalizeLanguage(value(FieldLanguage)),
+	}
+
+	for _, author := range strings.Split(value(FieldAuthors), authorSeparator) {
+		if author = cleanName(author); author != "" {
+			record.Authors = append(record.Authors, author)
+		}
+	}
+
+	if format := value(FieldFormat); format != "" {
+		if record.Format = normalizeFormat(format); record.Format == "" {
+			return nil, r.recordError(record, "unknown format %q", format)
+		}
+	}
+
+	if pageCount := value(FieldPageCount); pageCount != "" {
+		if record.PageCount, err = strconv.Atoi(pageCount); err != nil {
+			return nil, r.recordError(record, "invalid page count %q", pageCount)
+		}
+	}
+
+	if publishedAt := value(FieldPublishedAt); publishedAt != "" {
+		var ok bool
+		if record.PublishedAt, ok = normalizeDate(publishedAt); !ok {
+			return nil, r.recordError(record, "invalid publication date %q, use YYYY-MM-DD", publishedAt)
+		}
+	}
+
+	return record, nil
+}
+
+func (r *csvReader) recordError(record *Record, format string, args ...interface{}) error {
+	return &RecordError{Number: record.Number, ISBN: record.ISBN, Err: fmt.Errorf(format, args...)}
+}
+
+// isBlankRow tells whether a row is empty, such as the trailing lines of
+// files saved by spreadsheets.
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCSVReader(t *testing.T) {
+	file := "\ufeffISBN13,Title,Author(s),Format,Page_Count,Published_At,Language,Publisher\n" +
+		"978-0-441-17271-9,Dune,\"Herbert, Frank\",pbk.,617,1990-09,eng,Ace\n" +
+		",,,,,,,\n" +
+		"9780765386748,Good Omens,\"Pratchett, Terry; Gaiman, Neil\",,,,,\n" +
+		"9780441172719,Dune,Frank Herbert,,many,,,\n" +
+		"9780441172719,Dune,Frank Herbert,,,next year,,\n" +
+		"9780441172719,Dune,Frank Herbert,scroll,,,,\n"
+
+	reader, err := NewCSVReader(strings.NewReader(file), Mapping{FieldISBN: "isbn13", FieldAuthors: "Author(s)"})
+	if err != nil {
+		t.Fatalf("NewCSVReader() error = %v", err)
+	}
+	records, recordErrors := readAll(t, reader)
+
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+	assertRecord(t, records[0], &Record{
+		Number:      2,
+		ISBN:        "978-0-441-17271-9",
+		Title:       "Dune",
+		Authors:     []string{"Frank Herbert"},
+		Publisher:   "Ace",
+		Format:      "paperback",
+		Language:    "en",
+		PageCount:   617,
+		PublishedAt: "1990-09-01",
+	})
+	// The blank row is skipped, but counts for the line number
+	assertRecord(t, records[1], &Record{
+		Number:  4,
+		ISBN:    "9780765386748",
+		Title:   "Good Omens",
+		Authors: []string{"Terry Pratchett", "Neil Gaiman"},
+	})
+
+	wantErrors := []struct {
+		line    int
+		message string
+	}{
+		{line: 5, message: `invalid page count "many"`},
+		{line: 6, message: `invalid publication date "next year", use YYYY-MM-DD`},
+		{line: 7, message: `unknown format "scroll"`},
+	}
+	if len(recordErrors) != len(wantErrors) {
+		t.Fatalf("got %d record errors, want %d", len(recordErrors), len(wantErrors))
+	}
+	for i, want := range wantErrors {
+		got := recordErrors[i]
+		if got.Number != want.line || got.ISBN != "9780441172719" || got.Err.Error() != want.message {
+			t.Errorf("record error %d = %d %q %v, want line %d: %s", i, got.Number, got.ISBN, got.Err, want.line, want.message)
+		}
+	}
+}
+
+func TestCSVReaderHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		mapping Mapping
+		wantErr string
+	}{
+		{
+			name:    "empty file",
+			file:    "",
+			wantErr: "the CSV file is empty",
+		},
+		{
+			name:    "unknown field",
+			file:    "isbn,title,authors\n",
+			mapping: Mapping{"subtitle": "Subtitle"},
+			wantErr: `unknown field "subtitle" in the column mapping`,
+		},
+		{
+			name:    "mapped column missing",
+			file:    "isbn,title,authors\n",
+			mapping: Mapping{FieldISBN: "EAN"},
+			wantErr: `column "EAN" mapped to isbn is not in the CSV header`,
+		},
+		{
+			name:    "required column missing",
+			file:    "isbn,title,author\n",
+			wantErr: "the CSV file has no authors column; map one with the column mapping",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCSVReader(strings.NewReader(tt.file), tt.mapping)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewCSVReader() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
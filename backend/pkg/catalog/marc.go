@@ -0,0 +1,338 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/razvan/library-app/pkg/validator"
+)
+
+// Layout of binary MARC21 (ISO 2709) records
+const (
+	marcRecordTerminator = 0x1D
+	marcFieldTerminator  = 0x1E
+	marcSubfieldDelim    = 0x1F
+	marcLeaderLength     = 24
+	marcDirectoryEntry   = 12
+)
+
+// marcRecord holds the fields of a MARC21 record that are imported, whether
+// it was read from ISO 2709 or MARCXML.
+type marcRecord struct {
+	control map[string]string
+	fields  []marcField
+}
+
+type marcField struct {
+	tag       string
+	ind2      byte
+	subfields []marcSubfield
+}
+
+type marcSubfield struct {
+	code  byte
+	value string
+}
+
+func (f marcField) subfield(code byte) string {
+	for _, subfield := range f.subfields {
+		if subfield.code == code {
+			return subfield.value
+		}
+	}
+	return ""
+}
+
+func (r *marcRecord) field(tag string) (marcField, bool) {
+	for _, field := range r.fields {
+		if field.tag == tag {
+			return field, true
+		}
+	}
+	return marcField{}, false
+}
+
+func (r *marcRecord) all(tag string) []marcField {
+	var fields []marcField
+	for _, field := range r.fields {
+		if field.tag == tag {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+type marcReader struct {
+	reader *bufio.Reader
+	number int
+}
+
+// NewMARCReader reads binary MARC21 records (ISO 2709). Records must be
+// encoded in UTF-8, as marked in position 09 of their leader; MARC-8
+// records are only read when they are plain ASCII.
+func NewMARCReader(r io.Reader) Reader {
+	return &marcReader{reader: bufio.NewReader(r)}
+}
+
+func (r *marcReader) Read() (*Record, error) {
+	data, err := r.reader.ReadBytes(marcRecordTerminator)
+	if err == io.EOF {
+		// Ignore whitespace after the last record
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil, io.EOF
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Records are often separated by line breaks as well
+	data = bytes.TrimLeft(data, "\r\n")
+
+	r.number++
+	record, err := parseMARC(data)
+	if err != nil {
+		return nil, &RecordError{Number: r.number, Err: err}
+	}
+	return record.toRecord(r.number), nil
+}
+
+var errMARC8 = errors.New("the record is MARC-8 encoded, convert the file to UTF-8")
+
+// parseMARC parses one ISO 2709 record: a leader, a directory of the
+// fields' tags, lengths and offsets, and the fields themselves.
+func parseMARC(data []byte) (*marcRecord, error) {
+	if len(data) < marcLeaderLength {
+		return nil, errors.New("the record is too short")
+	}
+
+	leader := string(data[:marcLeaderLength])
+	base, err := strconv.Atoi(leader[12:17])
+	if err != nil || base <= marcLeaderLength || base > len(data) {
+		return nil, errors.New("the record has an invalid leader")
+	}
+	if leader[9] != 'a' && !isASCII(data) {
+		return nil, errMARC8
+	}
+	if !utf8.Valid(data) {
+		return nil, errors.New("the record is not valid UTF-8")
+	}
+
+	record := &marcRecord{control: make(map[string]string)}
+	directory := data[marcLeaderLength : base-1]
+	for len(directory) >= marcDirectoryEntry {
+		entry := string(directory[:marcDirectoryEntry])
+		directory = directory[marcDirectoryEntry:]
+
+		tag := entry[:3]
+		length, err1 := strconv.Atoi(entry[3:7])
+		start, err2 := strconv.Atoi(entry[7:12])
+		if err1 != nil || err2 != nil || start < 0 || length < 0 || length > len(data)-base-start {
+			return nil, fmt.Errorf("field %s is out of the record's bounds", tag)
+		}
+
+		value := bytes.TrimRight(data[base+start:base+start+length], string(rune(marcFieldTerminator)))
+		if strings.HasPrefix(tag, "00") {
+			record.control[tag] = string(value)
+			continue
+		}
+
+		field := marcField{tag: tag}
+		if len(value) >= 2 {
+			field.ind2 = value[1]
+			value = value[2:]
+		}
+		for _, subfield := range bytes.Split(value, []byte{marcSubfieldDelim}) {
+			if len(subfield) > 1 {
+				field.subfields = append(field.subfields, marcSubfield{code: subfield[0], value: string(subfield[1:])})
+			}
+		}
+		record.fields = append(record.fields, field)
+	}
+
+	return record, nil
+}
+
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// marcXMLRecord is a record of the MARC21 slim schema.
+type marcXMLRecord struct {
+	ControlFields []struct {
+		Tag   string `xml:"tag,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"controlfield"`
+	DataFields []struct {
+		Tag       string `xml:"tag,attr"`
+		Ind2      string `xml:"ind2,attr"`
+		Subfields []struct {
+			Code  string `xml:"code,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"subfield"`
+	} `xml:"datafield"`
+}
+
+type marcXMLReader struct {
+	decoder *xml.Decoder
+	number  int
+}
+
+// NewMARCXMLReader reads the record elements of a MARCXML file, either a
+// collection of records or a single one.
+func NewMARCXMLReader(r io.Reader) Reader {
+	return &marcXMLReader{decoder: xml.NewDecoder(r)}
+}
+
+func (r *marcXMLReader) Read() (*Record, error) {
+	start, err := nextElement(r.decoder, "record")
+	if err != nil {
+		return nil, err
+	}
+
+	var element marcXMLRecord
+	if err := r.decoder.DecodeElement(&element, start); err != nil {
+		return nil, fmt.Errorf("invalid MARCXML: %w", err)
+	}
+
+	r.number++
+	record := &marcRecord{control: make(map[string]string)}
+	for _, control := range element.ControlFields {
+		record.control[control.Tag] = control.Value
+	}
+	for _, data := range element.DataFields {
+		field := marcField{tag: data.Tag}
+		if data.Ind2 != "" {
+			field.ind2 = data.Ind2[0]
+		}
+		for _, subfield := range data.Subfields {
+			if subfield.Code != "" {
+				field.subfields = append(field.subfields, marcSubfield{code: subfield.Code[0], value: subfield.Value})
+			}
+		}
+		record.fields = append(record.fields, field)
+	}
+
+	return record.toRecord(r.number), nil
+}
+
+// nextElement skips to the next start of an element with the local name,
+// in any namespace.
+func nextElement(decoder *xml.Decoder, name string) (*xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == name {
+			return &start, nil
+		}
+	}
+}
+
+var pagesPattern = regexp.MustCompile(`(\d+)\s*(?:p\b|p\.|pages)`)
+
+// toRecord reads the edition from the fields libraries fill in for books:
+// 020 ISBN, 100 and 700 personal authors, 245 title, 264 or 260 publisher,
+// 300 extent, 520 summary, and the date and language from 008 or 041.
+func (r *marcRecord) toRecord(number int) *Record {
+	record := &Record{Number: number}
+
+	// A record lists the ISBNs of all formats of the edition. The first
+	// valid one is taken, with the format from its qualifier.
+	for _, field := range r.all("020") {
+		isbn, qualifier, _ := strings.Cut(strings.TrimSpace(field.subfield('a')), " ")
+		if isbn == "" {
+			continue
+		}
+		if record.ISBN == "" {
+			record.ISBN = isbn
+		}
+		if _, err := validator.NormalizeISBN(isbn); err == nil {
+			record.ISBN = isbn
+			record.Format = normalizeFormat(field.subfield('q') + " " + qualifier)
+			break
+		}
+	}
+
+	if field, ok := r.field("245"); ok {
+		title := cleanText(field.subfield('a'))
+		if subtitle := cleanText(field.subfield('b')); subtitle != "" {
+			title += ": " + subtitle
+		}
+		record.Title = strings.TrimSuffix(title, ".")
+	}
+
+	for _, tag := range []string{"100", "700"} {
+		for _, field := range r.all(tag) {
+			if name := cleanName(field.subfield('a')); name != "" {
+				record.Authors = append(record.Authors, name)
+			}
+		}
+	}
+
+	var summaries []string
+	for _, field := range r.all("520") {
+		if summary := strings.TrimSpace(field.subfield('a')); summary != "" {
+			summaries = append(summaries, summary)
+		}
+	}
+	record.Description = strings.Join(summaries, "\n\n")
+
+	publication, ok := r.publicationField()
+	if ok {
+		record.Publisher = cleanText(publication.subfield('b'))
+	}
+
+	// 008 holds the date at 07-10 and the language at 35-37
+	fixed := r.control["008"]
+	if len(fixed) >= 11 {
+		if date, ok := normalizeDate(fixed[7:11]); ok {
+			record.PublishedAt = date
+		}
+	}
+	if record.PublishedAt == "" && ok {
+		record.PublishedAt = findYear(publication.subfield('c'))
+	}
+
+	if len(fixed) >= 38 {
+		record.Language = normalizeLanguage(fixed[35:38])
+	}
+	if field, ok := r.field("041"); ok && record.Language == "" {
+		record.Language = normalizeLanguage(field.subfield('a'))
+	}
+
+	if field, ok := r.field("300"); ok {
+		if match := pagesPattern.FindStringSubmatch(field.subfield('a')); match != nil {
+			record.PageCount, _ = strconv.Atoi(match[1])
+		}
+	}
+
+	return record
+}
+
+// publicationField returns the 264 publication statement, or the older 260
+// one.
+func (r *marcRecord) publicationField() (marcField, bool) {
+	for _, field := range r.all("264") {
+		if field.ind2 == '1' {
+			return field, true
+		}
+	}
+	return r.field("260")
+}
@@ -0,0 +1,300 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// marcTestField is a field of a test record. The values of data fields
+// start with their two indicators, followed by the subfields.
+type marcTestField struct {
+	tag   string
+	value string
+}
+
+// buildMARC encodes an ISO 2709 record whose leader marks the character
+// encoding: 'a' for UTF-8 or ' ' for MARC-8.
+func buildMARC(encoding byte, fields ...marcTestField) []byte {
+	var directory, body bytes.Buffer
+	for _, field := range fields {
+		value := field.value + string(rune(marcFieldTerminator))
+		fmt.Fprintf(&directory, "%s%04d%05d", field.tag, len(value), body.Len())
+		body.WriteString(value)
+	}
+	directory.WriteByte(marcFieldTerminator)
+
+	base := marcLeaderLength + directory.Len()
+	length := base + body.Len() + 1
+	leader := fmt.Sprintf("%05dnam %c22%05d   4500", length, encoding, base)
+
+	return append([]byte(leader+directory.String()+body.String()), marcRecordTerminator)
+}
+
+// subfields joins subfields given as code and value, such as "aDune".
+func subfields(indicators string, values ...string) string {
+	return indicators + string(rune(marcSubfieldDelim)) + strings.Join(values, string(rune(marcSubfieldDelim)))
+}
+
+// The Dispossessed, as a library catalogs it
+var dispossessedFields = []marcTestField{
+	{"001", "12345"},
+	{"008", "740101s1974    nyu" + strings.Repeat(" ", 17) + "eng d"},
+	{"020", subfields("  ", "a0060125632 (hbk.)")},
+	{"020", subfields("  ", "a9780060512750", "qpaperback")},
+	{"100", subfields("1 ", "aLe Guin, Ursula K.,", "eauthor.")},
+	{"245", subfields("14", "aThe dispossessed :", "ban ambiguous utopia /", "cUrsula K. Le Guin.")},
+	{"264", subfields(" 1", "aNew York :", "bHarper & Row,", "c1974.")},
+	{"300", subfields("  ", "a341 pages ;", "c22 cm")},
+	{"520", subfields("  ", "aShevek, a physicist, travels to the planet his people left.")},
+	{"700", subfields("1 ", "aSmith, Jane.", "eeditor.")},
+}
+
+var dispossessed = &Record{
+	Number:      1,
+	ISBN:        "0060125632",
+	Title:       "The dispossessed: an ambiguous utopia",
+	Description: "Shevek, a physicist, travels to the planet his people left.",
+	Authors:     []string{"Ursula K. Le Guin", "Jane Smith"},
+	Publisher:   "Harper & Row",
+	Format:      "hardcover",
+	Language:    "en",
+	PageCount:   341,
+	PublishedAt: "1974-01-01",
+}
+
+func TestMARCReader(t *testing.T) {
+	records, recordErrors := readAll(t, NewMARCReader(bytes.NewReader(buildMARC('a', dispossessedFields...))))
+	if len(records) != 1 || len(recordErrors) != 0 {
+		t.Fatalf("read %d records and %v, want 1 record", len(records), recordErrors)
+	}
+	assertRecord(t, records[0], dispossessed)
+}
+
+func TestMARCReaderSeparators(t *testing.T) {
+	record := buildMARC('a', dispossessedFields...)
+
+	var file bytes.Buffer
+	file.Write(record)
+	file.WriteString("\r\n")
+	file.Write(record)
+	file.WriteString("\n")
+	file.Write(record)
+	file.WriteString("\r\n \n\t")
+
+	records, recordErrors := readAll(t, NewMARCReader(&file))
+	if len(records) != 3 || len(recordErrors) != 0 {
+		t.Fatalf("read %d records and %v, want 3 records", len(records), recordErrors)
+	}
+	for i, record := range records {
+		if record.Number != i+1 || record.Title != dispossessed.Title {
+			t.Errorf("record %d = %d %q", i, record.Number, record.Title)
+		}
+	}
+}
+
+func TestMARCReaderEncoding(t *testing.T) {
+	title := marcTestField{"245", subfields("10", "aLes Misérables")}
+	author := marcTestField{"100", subfields("1 ", "aHugo, Victor.")}
+
+	tests := []struct {
+		name      string
+		record    []byte
+		wantTitle string
+		wantErr   string
+	}{
+		{
+			name:      "UTF-8",
+			record:    buildMARC('a', title, author),
+			wantTitle: "Les Misérables",
+		},
+		{
+			name:      "MARC-8 in plain ASCII",
+			record:    buildMARC(' ', marcTestField{"245", subfields("10", "aLes Miserables")}, author),
+			wantTitle: "Les Miserables",
+		},
+		{
+			name:    "MARC-8 with diacritics",
+			record:  buildMARC(' ', marcTestField{"245", subfields("10", "aLes Mise\xe2rables")}, author),
+			wantErr: errMARC8.Error(),
+		},
+		{
+			name:    "UTF-8 leader with invalid UTF-8",
+			record:  buildMARC('a', marcTestField{"245", subfields("10", "aLes Mis\xe9rables")}, author),
+			wantErr: "the record is not valid UTF-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := NewMARCReader(bytes.NewReader(tt.record)).Read()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != "record 1: "+tt.wantErr {
+					t.Errorf("Read() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if record.Title != tt.wantTitle || len(record.Authors) != 1 || record.Authors[0] != "Victor Hugo" {
+				t.Errorf("Read() = %q by %v", record.Title, record.Authors)
+			}
+		})
+	}
+}
+
+func TestMARCReaderMalformed(t *testing.T) {
+	valid := buildMARC('a', dispossessedFields...)
+
+	// tamper returns a copy of the valid record with the bytes at offset
+	// replaced
+	tamper := func(offset int, replacement string) []byte {
+		record := bytes.Clone(valid)
+		copy(record[offset:], replacement)
+		return record
+	}
+	// The directory starts after the leader; the first entry is 001's, and
+	// its length and start are at 3-6 and 7-11 of the entry
+	const firstEntry = marcLeaderLength
+
+	tests := []struct {
+		name    string
+		record  []byte
+		wantErr string
+	}{
+		{
+			name:    "too short",
+			record:  append([]byte("00010nam a22"), marcRecordTerminator),
+			wantErr: "the record is too short",
+		},
+		{
+			name:    "base address not a number",
+			record:  tamper(12, "0x0AB"),
+			wantErr: "the record has an invalid leader",
+		},
+		{
+			name:    "base address inside the leader",
+			record:  tamper(12, "00010"),
+			wantErr: "the record has an invalid leader",
+		},
+		{
+			name:    "base address past the end",
+			record:  tamper(12, "99999"),
+			wantErr: "the record has an invalid leader",
+		},
+		{
+			name:    "field longer than the record",
+			record:  tamper(firstEntry+3, "9999"),
+			wantErr: "field 001 is out of the record's bounds",
+		},
+		{
+			name:    "field starting past the end",
+			record:  tamper(firstEntry+7, "99999"),
+			wantErr: "field 001 is out of the record's bounds",
+		},
+		{
+			name:    "negative field length",
+			record:  tamper(firstEntry+3, "-001"),
+			wantErr: "field 001 is out of the record's bounds",
+		},
+		{
+			name:    "negative field start",
+			record:  tamper(firstEntry+7, "-0001"),
+			wantErr: "field 001 is out of the record's bounds",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The malformed record is skipped and the next one is read
+			file := append(bytes.Clone(tt.record), valid...)
+			records, recordErrors := readAll(t, NewMARCReader(bytes.NewReader(file)))
+
+			if len(recordErrors) != 1 || recordErrors[0].Number != 1 || recordErrors[0].Err.Error() != tt.wantErr {
+				t.Fatalf("record errors = %v, want record 1: %s", recordErrors, tt.wantErr)
+			}
+			if len(records) != 1 || records[0].Number != 2 {
+				t.Errorf("read %d records after the malformed one, want record 2", len(records))
+			}
+		})
+	}
+}
+
+const marcXMLRecordFixture = `
+  <record>
+    <leader>00000nam a2200000 i 4500</leader>
+    <controlfield tag="008">740101s1974    nyu                 eng d</controlfield>
+    <datafield tag="020" ind1=" " ind2=" ">
+      <subfield code="a">0060125632 (hbk.)</subfield>
+    </datafield>
+    <datafield tag="100" ind1="1" ind2=" ">
+      <subfield code="a">Le Guin, Ursula K.,</subfield>
+      <subfield code="e">author.</subfield>
+    </datafield>
+    <datafield tag="245" ind1="1" ind2="4">
+      <subfield code="a">The dispossessed :</subfield>
+      <subfield code="b">an ambiguous utopia /</subfield>
+    </datafield>
+    <datafield tag="260" ind1=" " ind2=" ">
+      <subfield code="b">Harper &amp; Row,</subfield>
+      <subfield code="c">c1974.</subfield>
+    </datafield>
+  </record>`
+
+func TestMARCXMLReader(t *testing.T) {
+	want := &Record{
+		ISBN:        "0060125632",
+		Title:       "The dispossessed: an ambiguous utopia",
+		Authors:     []string{"Ursula K. Le Guin"},
+		Publisher:   "Harper & Row",
+		Format:      "hardcover",
+		Language:    "en",
+		PublishedAt: "1974-01-01",
+	}
+
+	tests := []struct {
+		name  string
+		file  string
+		count int
+	}{
+		{
+			name: "collection",
+			file: `<?xml version="1.0" encoding="UTF-8"?>
+<collection xmlns="http://www.loc.gov/MARC21/slim">` + marcXMLRecordFixture + marcXMLRecordFixture + `
+</collection>`,
+			count: 2,
+		},
+		{
+			name:  "single record",
+			file:  `<?xml version="1.0"?>` + strings.Replace(marcXMLRecordFixture, "<record>", `<record xmlns="http://www.loc.gov/MARC21/slim">`, 1),
+			count: 1,
+		},
+		{
+			name:  "prefixed namespace",
+			file:  `<marc:collection xmlns:marc="http://www.loc.gov/MARC21/slim">` + strings.NewReplacer("</", "</marc:", "<", "<marc:").Replace(marcXMLRecordFixture) + `</marc:collection>`,
+			count: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, recordErrors := readAll(t, NewMARCXMLReader(strings.NewReader(tt.file)))
+			if len(records) != tt.count || len(recordErrors) != 0 {
+				t.Fatalf("read %d records and %v, want %d records", len(records), recordErrors, tt.count)
+			}
+			for i, record := range records {
+				want.Number = i + 1
+				assertRecord(t, record, want)
+			}
+		})
+	}
+}
+
+func TestMARCXMLReaderInvalid(t *testing.T) {
+	reader := NewMARCXMLReader(strings.NewReader(`<collection><record><datafield tag="245">`))
+	if _, err := reader.Read(); err == nil || !strings.HasPrefix(err.Error(), "invalid MARCXML") {
+		t.Errorf("Read() error = %v, want invalid MARCXML", err)
+	}
+}
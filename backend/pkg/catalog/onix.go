@@ -0,0 +1,254 @@
+package catalog
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// onixProduct is the part of an ONIX 3.0 Product, in reference tags, that
+// is imported.
+type onixProduct struct {
+	NotificationType   string `xml:"NotificationType"`
+	ProductIdentifiers []struct {
+		Type  string `xml:"ProductIDType"`
+		Value string `xml:"IDValue"`
+	} `xml:"ProductIdentifier"`
+	Descriptive struct {
+		ProductForm string `xml:"ProductForm"`
+		Titles      []struct {
+			Type     string `xml:"TitleType"`
+			Elements []struct {
+				Level         string `xml:"TitleElementLevel"`
+				Text          string `xml:"TitleText"`
+				Prefix        string `xml:"TitlePrefix"`
+				WithoutPrefix string `xml:"TitleWithoutPrefix"`
+				Subtitle      string `xml:"Subtitle"`
+			} `xml:"TitleElement"`
+		} `xml:"TitleDetail"`
+		Contributors []struct {
+			Roles          []string `xml:"ContributorRole"`
+			PersonName     string   `xml:"PersonName"`
+			NameInverted   string   `xml:"PersonNameInverted"`
+			NamesBeforeKey string   `xml:"NamesBeforeKey"`
+			KeyNames       string   `xml:"KeyNames"`
+			CorporateName  string   `xml:"CorporateName"`
+		} `xml:"Contributor"`
+		Languages []struct {
+			Role string `xml:"LanguageRole"`
+			Code string `xml:"LanguageCode"`
+		} `xml:"Language"`
+		Extents []struct {
+			Type  string `xml:"ExtentType"`
+			Value string `xml:"ExtentValue"`
+			Unit  string `xml:"ExtentUnit"`
+		} `xml:"Extent"`
+	} `xml:"DescriptiveDetail"`
+	Texts []struct {
+		Type string `xml:"TextType"`
+		Text struct {
+			Content string `xml:",innerxml"`
+		} `xml:"Text"`
+	} `xml:"CollateralDetail>TextContent"`
+	Publishing struct {
+		Publishers []struct {
+			Role string `xml:"PublishingRole"`
+			Name string `xml:"PublisherName"`
+		} `xml:"Publisher"`
+		Dates []struct {
+			Role string `xml:"PublishingDateRole"`
+			Date string `xml:"Date"`
+		} `xml:"PublishingDate"`
+	} `xml:"PublishingDetail"`
+}
+
+// ONIX code list values that are imported
+const (
+	onixDeleteNotification = "05"
+	onixISBN10             = "02"
+	onixISBN13             = "15"
+	onixDistinctiveTitle   = "01"
+	onixProductLevel       = "01"
+	onixByAuthor           = "A01"
+	onixLanguageOfText     = "01"
+	onixMainContentPages   = "00"
+	onixPages              = "03"
+	onixDescription        = "03"
+	onixShortDescription   = "02"
+	onixPublisher          = "01"
+	onixPublicationDate    = "01"
+)
+
+// onixFormats maps ONIX product forms (code list 150) to edition formats.
+var onixFormats = map[string]string{
+	"BB": "hardcover",
+	"BC": "paperback",
+	"EA": "ebook",
+	"EB": "ebook",
+	"EC": "ebook",
+	"ED": "ebook",
+	"AA": "audiobook",
+	"AC": "audiobook",
+	"AE": "audiobook",
+	"AJ": "audiobook",
+	"AN": "audiobook",
+}
+
+type onixReader struct {
+	decoder *xml.Decoder
+	number  int
+}
+
+// NewONIXReader reads the products of an ONIX 3.0 message with reference
+// tag names. Products that notify a deletion are reported as record errors,
+// since imports never delete books.
+func NewONIXReader(r io.Reader) Reader {
+	return &onixReader{decoder: xml.NewDecoder(r)}
+}
+
+func (r *onixReader) Read() (*Record, error) {
+	start, err := nextElement(r.decoder, "Product")
+	if err != nil {
+		return nil, err
+	}
+
+	var product onixProduct
+	if err := r.decoder.DecodeElement(&product, start); err != nil {
+		return nil, fmt.Errorf("invalid ONIX: %w", err)
+	}
+
+	r.number++
+	record := product.toRecord(r.number)
+	if product.NotificationType == onixDeleteNotification {
+		return nil, &RecordError{Number: r.number, ISBN: record.ISBN, Err: errors.New("deletion notices are not imported")}
+	}
+	return record, nil
+}
+
+var (
+	htmlTags   = regexp.MustCompile(`<[^>]*>`)
+	htmlBreaks = regexp.MustCompile(`(?i)</p>|<br\s*/?>`)
+)
+
+func (p *onixProduct) toRecord(number int) *Record {
+	record := &Record{Number: number}
+
+	// Prefer the ISBN-13 when both are given
+	for _, id := range p.ProductIdentifiers {
+		switch id.Type {
+		case onixISBN13:
+			record.ISBN = strings.TrimSpace(id.Value)
+		case onixISBN10:
+			if record.ISBN == "" {
+				record.ISBN = strings.TrimSpace(id.Value)
+			}
+		}
+	}
+
+	detail := &p.Descriptive
+	record.Format = onixFormats[detail.ProductForm]
+
+	for _, title := range detail.Titles {
+		if title.Type != onixDistinctiveTitle {
+			continue
+		}
+		for _, element := range title.Elements {
+			if element.Level != onixProductLevel {
+				continue
+			}
+			text := element.Text
+			if text == "" {
+				text = strings.TrimSpace(element.Prefix + " " + element.WithoutPrefix)
+			}
+			if element.Subtitle != "" {
+				text += ": " + element.Subtitle
+			}
+			record.Title = cleanText(text)
+		}
+	}
+
+	for _, contributor := range detail.Contributors {
+		if !slices.Contains(contributor.Roles, onixByAuthor) {
+			continue
+		}
+
+		var name string
+		switch {
+		case contributor.PersonName != "":
+			name = contributor.PersonName
+		case contributor.KeyNames != "":
+			name = contributor.NamesBeforeKey + " " + contributor.KeyNames
+		case contributor.NameInverted != "":
+			name = contributor.NameInverted
+		default:
+			name = contributor.CorporateName
+		}
+		if name = cleanName(name); name != "" {
+			record.Authors = append(record.Authors, name)
+		}
+	}
+
+	for _, lang := range detail.Languages {
+		if lang.Role == onixLanguageOfText {
+			record.Language = normalizeLanguage(lang.Code)
+			break
+		}
+	}
+
+	for _, extent := range detail.Extents {
+		if extent.Type == onixMainContentPages && extent.Unit == onixPages {
+			record.PageCount, _ = strconv.Atoi(strings.TrimSpace(extent.Value))
+		}
+	}
+
+	// The full description, or else the short one
+	for _, textType := range []string{onixDescription, onixShortDescription} {
+		for _, text := range p.Texts {
+			if text.Type == textType && record.Description == "" {
+				record.Description = onixText(text.Text.Content)
+			}
+		}
+	}
+
+	for _, publisher := range p.Publishing.Publishers {
+		if publisher.Role == onixPublisher {
+			record.Publisher = cleanText(publisher.Name)
+			break
+		}
+	}
+
+	for _, date := range p.Publishing.Dates {
+		if date.Role == onixPublicationDate {
+			record.PublishedAt, _ = normalizeDate(date.Date)
+			break
+		}
+	}
+
+	return record
+}
+
+// onixText turns the content of a Text element, which may be plain text,
+// XHTML or escaped HTML, into plain text that keeps its paragraphs.
+func onixText(content string) string {
+	text := strings.NewReplacer("<![CDATA[", "", "]]>", "").Replace(content)
+	if !strings.Contains(text, "<") {
+		text = html.UnescapeString(text)
+	}
+
+	text = htmlBreaks.ReplaceAllString(text, "\n\n")
+	text = html.UnescapeString(htmlTags.ReplaceAllString(text, ""))
+
+	var paragraphs []string
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if paragraph = strings.Join(strings.Fields(paragraph), " "); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
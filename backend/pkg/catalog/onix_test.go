@@ -0,0 +1,176 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+)
+
+const onixMessage = `<?xml version="1.0" encoding="UTF-8"?>
+<ONIXMessage release="3.0" xmlns="http://ns.editeur.org/onix/3.0/reference">
+  <Header>
+    <Sender><SenderName>Publisher</SenderName></Sender>
+  </Header>
+  <Product>
+    <RecordReference>com.example.1</RecordReference>
+    <NotificationType>03</NotificationType>
+    <ProductIdentifier><ProductIDType>02</ProductIDType><IDValue>0441172717</IDValue></ProductIdentifier>
+    <ProductIdentifier><ProductIDType>15</ProductIDType><IDValue>9780441172719</IDValue></ProductIdentifier>
+    <DescriptiveDetail>
+      <ProductComposition>00</ProductComposition>
+      <ProductForm>BC</ProductForm>
+      <TitleDetail>
+        <TitleType>01</TitleType>
+        <TitleElement>
+          <TitleElementLevel>01</TitleElementLevel>
+          <TitleText>Dune</TitleText>
+          <Subtitle>Deluxe Edition</Subtitle>
+        </TitleElement>
+      </TitleDetail>
+      <TitleDetail>
+        <TitleType>05</TitleType>
+        <TitleElement><TitleElementLevel>01</TitleElementLevel><TitleText>Abridged title</TitleText></TitleElement>
+      </TitleDetail>
+      <Contributor>
+        <SequenceNumber>1</SequenceNumber>
+        <ContributorRole>A01</ContributorRole>
+        <PersonName>Frank Herbert</PersonName>
+      </Contributor>
+      <Contributor>
+        <SequenceNumber>2</SequenceNumber>
+        <ContributorRole>A12</ContributorRole>
+        <PersonName>John Schoenherr</PersonName>
+      </Contributor>
+      <Contributor>
+        <SequenceNumber>3</SequenceNumber>
+        <ContributorRole>B06</ContributorRole>
+        <ContributorRole>A01</ContributorRole>
+        <NamesBeforeKey>Brian</NamesBeforeKey>
+        <KeyNames>Herbert</KeyNames>
+      </Contributor>
+      <Contributor>
+        <SequenceNumber>4</SequenceNumber>
+        <ContributorRole>A01</ContributorRole>
+        <PersonNameInverted>Anderson, Kevin J.</PersonNameInverted>
+      </Contributor>
+      <Language><LanguageRole>02</LanguageRole><LanguageCode>fre</LanguageCode></Language>
+      <Language><LanguageRole>01</LanguageRole><LanguageCode>eng</LanguageCode></Language>
+      <Extent><ExtentType>11</ExtentType><ExtentValue>600</ExtentValue><ExtentUnit>03</ExtentUnit></Extent>
+      <Extent><ExtentType>00</ExtentType><ExtentValue>617</ExtentValue><ExtentUnit>03</ExtentUnit></Extent>
+    </DescriptiveDetail>
+    <CollateralDetail>
+      <TextContent>
+        <TextType>02</TextType>
+        <ContentAudience>00</ContentAudience>
+        <Text>A short description.</Text>
+      </TextContent>
+      <TextContent>
+        <TextType>03</TextType>
+        <ContentAudience>00</ContentAudience>
+        <Text textformat="05"><p>Set on the desert planet <em>Arrakis</em>.</p><p>A &amp; B.</p></Text>
+      </TextContent>
+    </CollateralDetail>
+    <PublishingDetail>
+      <Publisher><PublishingRole>02</PublishingRole><PublisherName>Co-publisher</PublisherName></Publisher>
+      <Publisher><PublishingRole>01</PublishingRole><PublisherName>Ace Books</PublisherName></Publisher>
+      <PublishingDate><PublishingDateRole>09</PublishingDateRole><Date>20190101</Date></PublishingDate>
+      <PublishingDate><PublishingDateRole>01</PublishingDateRole><Date dateformat="01">199009</Date></PublishingDate>
+    </PublishingDetail>
+  </Product>
+  <Product>
+    <RecordReference>com.example.2</RecordReference>
+    <NotificationType>05</NotificationType>
+    <ProductIdentifier><ProductIDType>15</ProductIDType><IDValue>9780765386748</IDValue></ProductIdentifier>
+  </Product>
+  <Product>
+    <RecordReference>com.example.3</RecordReference>
+    <NotificationType>03</NotificationType>
+    <ProductIdentifier><ProductIDType>02</ProductIDType><IDValue>0-306-40615-2</IDValue></ProductIdentifier>
+    <DescriptiveDetail>
+      <ProductForm>EB</ProductForm>
+      <TitleDetail>
+        <TitleType>01</TitleType>
+        <TitleElement>
+          <TitleElementLevel>01</TitleElementLevel>
+          <TitlePrefix>The</TitlePrefix>
+          <TitleWithoutPrefix>Left Hand of Darkness</TitleWithoutPrefix>
+        </TitleElement>
+      </TitleDetail>
+      <Contributor>
+        <ContributorRole>A01</ContributorRole>
+        <PersonNameInverted>Le Guin, Ursula K.</PersonNameInverted>
+      </Contributor>
+      <Language><LanguageRole>01</LanguageRole><LanguageCode>und</LanguageCode></Language>
+    </DescriptiveDetail>
+    <CollateralDetail>
+      <TextContent>
+        <TextType>03</TextType>
+        <Text textformat="02">&lt;p&gt;Escaped &amp;amp; HTML&lt;/p&gt;</Text>
+      </TextContent>
+    </CollateralDetail>
+    <PublishingDetail>
+      <PublishingDate><PublishingDateRole>01</PublishingDateRole><Date>2019-3</Date></PublishingDate>
+    </PublishingDetail>
+  </Product>
+</ONIXMessage>`
+
+func TestONIXReader(t *testing.T) {
+	records, recordErrors := readAll(t, NewONIXReader(strings.NewReader(onixMessage)))
+
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+	assertRecord(t, records[0], &Record{
+		Number:      1,
+		ISBN:        "9780441172719",
+		Title:       "Dune: Deluxe Edition",
+		Description: "Set on the desert planet Arrakis.\n\nA & B.",
+		Authors:     []string{"Frank Herbert", "Brian Herbert", "Kevin J. Anderson"},
+		Publisher:   "Ace Books",
+		Format:      "paperback",
+		Language:    "en",
+		PageCount:   617,
+		PublishedAt: "1990-09-01",
+	})
+	// An undetermined language and an invalid date are left out
+	assertRecord(t, records[1], &Record{
+		Number:      3,
+		ISBN:        "0-306-40615-2",
+		Title:       "The Left Hand of Darkness",
+		Description: "Escaped & HTML",
+		Authors:     []string{"Ursula K. Le Guin"},
+		Format:      "ebook",
+	})
+
+	if len(recordErrors) != 1 {
+		t.Fatalf("got %d record errors, want 1", len(recordErrors))
+	}
+	if err := recordErrors[0]; err.Number != 2 || err.ISBN != "9780765386748" || err.Err.Error() != "deletion notices are not imported" {
+		t.Errorf("record error = %d %q %v", err.Number, err.ISBN, err.Err)
+	}
+}
+
+func TestONIXReaderInvalid(t *testing.T) {
+	reader := NewONIXReader(strings.NewReader(`<ONIXMessage><Product><DescriptiveDetail></Product>`))
+	if _, err := reader.Read(); err == nil || !strings.HasPrefix(err.Error(), "invalid ONIX") {
+		t.Errorf("Read() error = %v, want invalid ONIX", err)
+	}
+}
+
+func TestONIXText(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "Plain   text\n over lines", want: "Plain text over lines"},
+		{content: "<p>One</p><p>Two<br/>Three</p>", want: "One\n\nTwo\n\nThree"},
+		{content: "&lt;p&gt;Escaped&lt;/p&gt;", want: "Escaped"},
+		{content: "<![CDATA[<b>Bold</b> text]]>", want: "Bold text"},
+		{content: "Fish &amp; chips", want: "Fish & chips"},
+	}
+
+	for _, tt := range tests {
+		if got := onixText(tt.content); got != tt.want {
+			t.Errorf("onixText(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
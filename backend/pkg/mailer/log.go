@@ -0,0 +1,18 @@
+package mailer
+
+import (
+	"log"
+)
+
+// LogMailer writes messages to the application log instead of sending them.
+// It is meant for local development.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("MAIL to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
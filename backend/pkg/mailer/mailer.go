@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"fmt"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+type Config struct {
+	Driver   string // "smtp" or "log"
+	From     string
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// New returns the mailer selected by cfg.Driver.
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return NewLogMailer(), nil
+	case "smtp":
+		if cfg.Host == "" || cfg.From == "" {
+			return nil, fmt.Errorf("smtp mailer needs a host and a from address")
+		}
+		return NewSMTPMailer(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
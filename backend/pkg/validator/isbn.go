@@ -0,0 +1,88 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var ErrInvalidISBN = errors.New("invalid ISBN")
+
+// NormalizeISBN checks an ISBN-10 or ISBN-13, with or without hyphens or
+// spaces, and returns it as a bare ISBN-13.
+func NormalizeISBN(isbn string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		if r == 'x' {
+			return 'X'
+		}
+		return r
+	}, isbn)
+
+	switch len(digits) {
+	case 10:
+		if !validISBN10(digits) {
+			return "", ErrInvalidISBN
+		}
+		isbn13 := "978" + digits[:9]
+		return isbn13 + string(isbn13CheckDigit(isbn13)), nil
+	case 13:
+		if !allDigits(digits) || isbn13CheckDigit(digits[:12]) != digits[12] {
+			return "", ErrInvalidISBN
+		}
+		return digits, nil
+	default:
+		return "", ErrInvalidISBN
+	}
+}
+
+// validISBN10 checks the weighted sum of an ISBN-10, whose check digit may
+// be X for 10.
+func validISBN10(isbn string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var value int
+		switch c := isbn[i]; {
+		case c >= '0' && c <= '9':
+			value = int(c - '0')
+		case c == 'X' && i == 9:
+			value = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * value
+	}
+	return sum%11 == 0
+}
+
+// isbn13CheckDigit computes the check digit of the first 12 digits of an
+// ISBN-13, weighted 1 and 3 alternately.
+func isbn13CheckDigit(first12 string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(first12[i]-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateISBN replaces the built-in isbn tag, which rejects hyphens.
+func validateISBN(fl validator.FieldLevel) bool {
+	_, err := NormalizeISBN(fl.Field().String())
+	return err == nil
+}
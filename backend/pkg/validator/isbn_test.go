@@ -0,0 +1,116 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		name  string
+		isbn  string
+		want  string
+		valid bool
+	}{
+		{name: "ISBN-10", isbn: "0306406152", want: "9780306406157", valid: true},
+		{name: "ISBN-10 with X check digit", isbn: "080442957X", want: "9780804429573", valid: true},
+		{name: "ISBN-10 with lowercase x", isbn: "080442957x", want: "9780804429573", valid: true},
+		{name: "ISBN-13", isbn: "9780306406157", want: "9780306406157", valid: true},
+		{name: "hyphenated ISBN-10", isbn: "0-306-40615-2", want: "9780306406157", valid: true},
+		{name: "hyphenated ISBN-13", isbn: "978-0-306-40615-7", want: "9780306406157", valid: true},
+		{name: "space separated", isbn: "978 0 306 40615 7", want: "9780306406157", valid: true},
+		{name: "wrong ISBN-10 check digit", isbn: "0306406153"},
+		{name: "wrong ISBN-13 check digit", isbn: "9780306406158"},
+		{name: "X before the check digit", isbn: "08044295X7"},
+		{name: "X in an ISBN-13", isbn: "978080442957X"},
+		{name: "empty", isbn: ""},
+		{name: "nine digits", isbn: "030640615"},
+		{name: "eleven digits", isbn: "03064061522"},
+		{name: "twelve digits", isbn: "978030640615"},
+		{name: "fourteen digits", isbn: "97803064061570"},
+		{name: "letters", isbn: "978030640615A"},
+		{name: "non-ASCII digits", isbn: "٠٣٠٦٤٠٦١٥٢"},
+		{name: "non-ASCII within ten bytes", isbn: "03064061é"},
+		{name: "other separators", isbn: "0.306.40615.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeISBN(tt.isbn)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidISBN) {
+					t.Errorf("NormalizeISBN(%q) = %q, %v, want ErrInvalidISBN", tt.isbn, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeISBN(%q) = %q, %v, want %q", tt.isbn, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// isbn10 converts a 978 ISBN-13 back to an ISBN-10.
+func isbn10(isbn13 string) string {
+	first9 := isbn13[3:12]
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += (10 - i) * int(first9[i]-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return first9 + "X"
+	}
+	return first9 + string(rune('0'+check))
+}
+
+func TestNormalizeISBNRoundTrip(t *testing.T) {
+	for _, isbn := range []string{"0306406152", "080442957X", "0140449132", "0000000000"} {
+		isbn13, err := NormalizeISBN(isbn)
+		if err != nil {
+			t.Fatalf("NormalizeISBN(%q) error = %v", isbn, err)
+		}
+		if !strings.HasPrefix(isbn13, "978") {
+			t.Errorf("NormalizeISBN(%q) = %q, want a 978 prefix", isbn, isbn13)
+		}
+		if again, err := NormalizeISBN(isbn13); err != nil || again != isbn13 {
+			t.Errorf("NormalizeISBN(%q) = %q, %v, want it unchanged", isbn13, again, err)
+		}
+		if back := isbn10(isbn13); back != isbn {
+			t.Errorf("%q converts back to %q, want %q", isbn13, back, isbn)
+		}
+	}
+}
+
+func TestISBNTag(t *testing.T) {
+	type edition struct {
+		ISBN string `validate:"omitempty,isbn"`
+	}
+
+	tests := []struct {
+		isbn  string
+		valid bool
+	}{
+		// The built-in tag rejects hyphens and spaces
+		{isbn: "978-0-306-40615-7", valid: true},
+		{isbn: "0 306 40615 2", valid: true},
+		{isbn: "080442957x", valid: true},
+		{isbn: "9780306406157", valid: true},
+		{isbn: "", valid: true},
+		{isbn: "9780306406158"},
+		{isbn: "not an isbn"},
+	}
+
+	for _, tt := range tests {
+		err := Validate(&edition{ISBN: tt.isbn})
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("Validate(%q) error = %v, want valid %v", tt.isbn, err, tt.valid)
+		}
+		if err != nil {
+			if fields := InvalidFields(err); len(fields) != 1 || fields[0] != "ISBN" {
+				t.Errorf("InvalidFields() = %v, want [ISBN]", fields)
+			}
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package validator
 
 import (
+	"errors"
+
 	"github.com/go-playground/validator/v10"
 )
 
@@ -8,6 +10,7 @@ var validate *validator.Validate
 
 func init() {
 	validate = validator.New()
+	validate.RegisterValidation("isbn", validateISBN)
 }
 
 func Validate(i interface{}) error {
@@ -17,3 +20,18 @@ func Validate(i interface{}) error {
 func GetValidator() *validator.Validate {
 	return validate
 }
+
+// InvalidFields returns the names of the struct fields that failed in an
+// error returned by Validate.
+func InvalidFields(err error) []string {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return nil
+	}
+
+	fields := make([]string, len(fieldErrors))
+	for i, fieldError := range fieldErrors {
+		fields[i] = fieldError.StructField()
+	}
+	return fields
+}
@@ -15,6 +15,29 @@ const router = createRouter({
       component: () => import('@/views/auth/LoginView.vue'),
       meta: { guest: true }
     },
+    {
+      path: '/auth/oidc/:provider/callback',
+      name: 'oidc-callback',
+      component: () => import('@/views/auth/OIDCCallbackView.vue'),
+      meta: { guest: true }
+    },
+    {
+      path: '/verify-email',
+      name: 'verify-email',
+      component: () => import('@/views/auth/VerifyEmailView.vue')
+    },
+    {
+      path: '/forgot-password',
+      name: 'forgot-password',
+      component: () => import('@/views/auth/ForgotPasswordView.vue'),
+      meta: { guest: true }
+    },
+    {
+      path: '/reset-password',
+      name: 'reset-password',
+      component: () => import('@/views/auth/ResetPasswordView.vue'),
+      meta: { guest: true }
+    },
     {
       path: '/register',
       name: 'register',
@@ -31,6 +54,21 @@ const router = createRouter({
       name: 'book-detail',
       component: () => import('@/views/books/BookDetailView.vue')
     },
+    {
+      path: '/genres/:id',
+      name: 'genre-books',
+      component: () => import('@/views/books/TaxonomyBooksView.vue')
+    },
+    {
+      path: '/tags/:id',
+      name: 'tag-books',
+      component: () => import('@/views/books/TaxonomyBooksView.vue')
+    },
+    {
+      path: '/series/:id',
+      name: 'series-books',
+      component: () => import('@/views/books/SeriesView.vue')
+    },
     {
       path: '/my-library',
       name: 'my-library',
@@ -53,25 +91,55 @@ const router = createRouter({
       path: '/admin',
       name: 'admin',
       component: () => import('@/views/admin/AdminDashboard.vue'),
-      meta: { requiresAuth: true, requiresAdmin: true }
+      meta: { requiresAuth: true, permission: 'books:write' }
     },
     {
       path: '/admin/books/new',
       name: 'admin-book-new',
       component: () => import('@/views/admin/BookForm.vue'),
-      meta: { requiresAuth: true, requiresAdmin: true }
+      meta: { requiresAuth: true, permission: 'books:write' }
     },
     {
       path: '/admin/books/:id/edit',
       name: 'admin-book-edit',
       component: () => import('@/views/admin/BookForm.vue'),
-      meta: { requiresAuth: true, requiresAdmin: true }
+      meta: { requiresAuth: true, permission: 'books:write' }
     },
     {
       path: '/admin/authors',
       name: 'admin-authors',
       component: () => import('@/views/admin/AuthorsView.vue'),
-      meta: { requiresAuth: true, requiresAdmin: true }
+      meta: { requiresAuth: true, permission: 'authors:write' }
+    },
+    {
+      path: '/admin/series',
+      name: 'admin-series',
+      component: () => import('@/views/admin/SeriesView.vue'),
+      meta: { requiresAuth: true, permission: 'books:write' }
+    },
+    {
+      path: '/admin/imports',
+      name: 'admin-imports',
+      component: () => import('@/views/admin/ImportsView.vue'),
+      meta: { requiresAuth: true, permission: 'catalog:import' }
+    },
+    {
+      path: '/admin/taxonomy',
+      name: 'admin-taxonomy',
+      component: () => import('@/views/admin/TaxonomyView.vue'),
+      meta: { requiresAuth: true, permission: 'taxonomy:manage' }
+    },
+    {
+      path: '/admin/users',
+      name: 'admin-users',
+      component: () => import('@/views/admin/UsersView.vue'),
+      meta: { requiresAuth: true, permission: 'users:manage' }
+    },
+    {
+      path: '/admin/audit',
+      name: 'admin-audit',
+      component: () => import('@/views/admin/AuditLogView.vue'),
+      meta: { requiresAuth: true, permission: 'users:manage' }
     }
   ]
 })
@@ -81,7 +149,7 @@ router.beforeEach((to, from, next) => {
 
   if (to.meta.requiresAuth && !authStore.isAuthenticated) {
     next({ name: 'login', query: { redirect: to.fullPath } })
-  } else if (to.meta.requiresAdmin && !authStore.isAdmin) {
+  } else if (to.meta.permission && !authStore.hasPermission(to.meta.permission)) {
     next({ name: 'home' })
   } else if (to.meta.guest && authStore.isAuthenticated) {
     next({ name: 'home' })
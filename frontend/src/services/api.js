@@ -39,11 +39,50 @@ export const authAPI = {
   register: (data) => api.post('/auth/register', data),
   login: (data) => api.post('/auth/login', data),
   getMe: () => api.get('/auth/me'),
+  updateMe: (data) => api.patch('/auth/me', data),
+  deleteMe: (data) => api.delete('/auth/me', { data }),
+  uploadAvatar: (file) => {
+    const formData = new FormData()
+    formData.append('avatar', file)
+    return api.post('/auth/me/avatar', formData, {
+      headers: { 'Content-Type': 'multipart/form-data' }
+    })
+  },
+  getExports: () => api.get('/auth/me/exports'),
+  requestExport: () => api.post('/auth/me/exports'),
+  downloadExport: (id) => api.get(`/auth/me/exports/${id}/download`, { responseType: 'blob' }),
+  verifyEmail: (token) => api.post('/auth/verify-email', { token }),
+  resendVerification: (email) => api.post('/auth/verify-email/resend', { email }),
+  forgotPassword: (email) => api.post('/auth/password/forgot', { email }),
+  resetPassword: (token, password) => api.post('/auth/password/reset', { token, password }),
+  changePassword: (data) => api.post('/auth/password/change', data),
+  logout: () => api.post('/auth/logout'),
+  logoutAll: () => api.post('/auth/logout-all'),
+  getSessions: () => api.get('/auth/sessions'),
+  revokeSession: (sessionId) => api.delete(`/auth/sessions/${sessionId}`),
+  getAPIKeys: () => api.get('/auth/api-keys'),
+  createAPIKey: (data) => api.post('/auth/api-keys', data),
+  revokeAPIKey: (keyId) => api.delete(`/auth/api-keys/${keyId}`),
   setup2FA: () => api.post('/auth/2fa/setup'),
   verify2FASetup: (code) => api.post('/auth/2fa/verify', { code }),
-  verify2FALogin: (userId, code) => api.post('/auth/2fa/verify-login', { user_id: userId, code }),
+  verify2FALogin: (challengeToken, code) => {
+    // Anything other than a 6-digit TOTP code is treated as a recovery code
+    const body = /^\d{6}$/.test(code)
+      ? { challenge_token: challengeToken, code }
+      : { challenge_token: challengeToken, recovery_code: code }
+    return api.post('/auth/2fa/verify-login', body)
+  },
   disable2FA: (code) => api.post('/auth/2fa/disable', { code }),
-  makeAdmin: (userId) => api.post(`/auth/users/${userId}/make-admin`)
+  regenerateRecoveryCodes: (code) => api.post('/auth/2fa/recovery-codes', { code }),
+  getRoles: () => api.get('/auth/roles'),
+  assignRole: (userId, role) => api.post(`/auth/users/${userId}/roles`, { role }),
+  revokeRole: (userId, role) => api.delete(`/auth/users/${userId}/roles/${role}`),
+  getOIDCProviders: () => api.get('/auth/oidc/providers'),
+  startOIDC: (provider) => api.get(`/auth/oidc/${provider}/start`),
+  completeOIDC: (provider, params) => api.get(`/auth/oidc/${provider}/callback`, { params }),
+  confirmOIDCLink: (linkToken, password) =>
+    api.post('/auth/oidc/link/confirm', { link_token: linkToken, password }),
+  linkOIDCIdentity: (linkToken) => api.post('/auth/me/identities', { link_token: linkToken })
 }
 
 // Books API
@@ -51,6 +90,7 @@ export const booksAPI = {
   getAll: (params) => api.get('/books', { params }),
   search: (query, params) => api.get('/books/search', { params: { q: query, ...params } }),
   getById: (id) => api.get(`/books/${id}`),
+  getByISBN: (isbn) => api.get(`/books/isbn/${encodeURIComponent(isbn)}`),
   create: (data) => api.post('/books', data),
   update: (id, data) => api.put(`/books/${id}`, data),
   delete: (id) => api.delete(`/books/${id}`),
@@ -63,6 +103,21 @@ export const booksAPI = {
   }
 }
 
+// Editions API
+export const editionsAPI = {
+  getByBook: (bookId) => api.get(`/books/${bookId}/editions`),
+  create: (bookId, data) => api.post(`/books/${bookId}/editions`, data),
+  update: (id, data) => api.put(`/editions/${id}`, data),
+  delete: (id) => api.delete(`/editions/${id}`),
+  uploadCover: (id, file) => {
+    const formData = new FormData()
+    formData.append('cover', file)
+    return api.post(`/editions/${id}/cover`, formData, {
+      headers: { 'Content-Type': 'multipart/form-data' }
+    })
+  }
+}
+
 // Authors API
 export const authorsAPI = {
   getAll: (params) => api.get('/authors', { params }),
@@ -72,22 +127,90 @@ export const authorsAPI = {
   delete: (id) => api.delete(`/authors/${id}`)
 }
 
+// Catalog imports API
+export const importsAPI = {
+  getAll: (params) => api.get('/imports', { params }),
+  getById: (id) => api.get(`/imports/${id}`),
+  getErrors: (id, params) => api.get(`/imports/${id}/errors`, { params }),
+  start: (file, { format, dryRun, mapping }) => {
+    const formData = new FormData()
+    formData.append('file', file)
+    formData.append('format', format)
+    formData.append('dry_run', dryRun ? 'true' : 'false')
+    if (mapping && Object.keys(mapping).length > 0) {
+      formData.append('mapping', JSON.stringify(mapping))
+    }
+    return api.post('/imports', formData, {
+      headers: { 'Content-Type': 'multipart/form-data' }
+    })
+  }
+}
+
+// Genres and tags API
+export const taxonomyAPI = {
+  getGenres: () => api.get('/genres'),
+  getGenre: (id) => api.get(`/genres/${id}`),
+  getGenreBooks: (id, params) => api.get(`/genres/${id}/books`, { params }),
+  createGenre: (data) => api.post('/genres', data),
+  updateGenre: (id, data) => api.put(`/genres/${id}`, data),
+  deleteGenre: (id) => api.delete(`/genres/${id}`),
+  getTags: (params) => api.get('/tags', { params }),
+  getTag: (id) => api.get(`/tags/${id}`),
+  getTagBooks: (id, params) => api.get(`/tags/${id}/books`, { params }),
+  createTag: (data) => api.post('/tags', data),
+  updateTag: (id, data) => api.put(`/tags/${id}`, data),
+  deleteTag: (id) => api.delete(`/tags/${id}`)
+}
+
+// Series API
+export const seriesAPI = {
+  getAll: (params) => api.get('/series', { params }),
+  getById: (id) => api.get(`/series/${id}`),
+  getBooks: (id, params) => api.get(`/series/${id}/books`, { params }),
+  create: (data) => api.post('/series', data),
+  update: (id, data) => api.put(`/series/${id}`, data),
+  delete: (id) => api.delete(`/series/${id}`),
+  setVolume: (id, bookId, position) => api.put(`/series/${id}/books/${bookId}`, { position }),
+  removeVolume: (id, bookId) => api.delete(`/series/${id}/books/${bookId}`)
+}
+
 // User Books API
 export const userBooksAPI = {
-  getReadingList: (status) => api.get('/user/reading-list', { params: { status } }),
-  addToReadingList: (bookId, status) => api.post(`/user/books/${bookId}/reading-list`, { status }),
+  getReadingList: (params) => api.get('/user/reading-list', { params }),
+  getSeriesProgress: () => api.get('/user/series'),
+  addToReadingList: (bookId, status, editionId = null) =>
+    api.post(`/user/books/${bookId}/reading-list`, { status, edition_id: editionId }),
   removeFromReadingList: (bookId) => api.delete(`/user/books/${bookId}/reading-list`),
-  getFavorites: () => api.get('/user/favorites'),
+  getFavorites: (params) => api.get('/user/favorites', { params }),
   addToFavorites: (bookId) => api.post(`/user/books/${bookId}/favorites`),
-  removeFromFavorites: (bookId) => api.delete(`/user/books/${bookId}/favorites`)
+  removeFromFavorites: (bookId) => api.delete(`/user/books/${bookId}/favorites`),
+  getRating: (bookId) => api.get(`/user/books/${bookId}/rating`),
+  rate: (bookId, rating) => api.put(`/user/books/${bookId}/rating`, { rating }),
+  removeRating: (bookId) => api.delete(`/user/books/${bookId}/rating`)
 }
 
 // Comments API
 export const commentsAPI = {
-  getBookComments: (bookId) => api.get(`/books/${bookId}/comments`),
+  getBookComments: (bookId, params) => api.get(`/books/${bookId}/comments`, { params }),
   create: (bookId, content) => api.post(`/books/${bookId}/comments`, { content }),
   update: (commentId, content) => api.put(`/comments/${commentId}`, { content }),
   delete: (commentId) => api.delete(`/comments/${commentId}`)
 }
 
+// Admin user management API
+export const adminUsersAPI = {
+  list: (params) => api.get('/admin/users', { params }),
+  get: (userId) => api.get(`/admin/users/${userId}`),
+  demote: (userId) => api.post(`/admin/users/${userId}/demote`),
+  suspend: (userId, reason) => api.post(`/admin/users/${userId}/suspend`, { reason }),
+  unsuspend: (userId) => api.post(`/admin/users/${userId}/unsuspend`),
+  forcePasswordReset: (userId) => api.post(`/admin/users/${userId}/password-reset`),
+  delete: (userId) => api.delete(`/admin/users/${userId}`)
+}
+
+export const adminAuditAPI = {
+  list: (params) => api.get('/admin/audit', { params }),
+  verify: () => api.get('/admin/audit/verify')
+}
+
 export default api
@@ -6,16 +6,44 @@ export const useAuthStore = defineStore('auth', () => {
   const user = ref(null)
   const token = ref(localStorage.getItem('access_token') || null)
   const requires2FA = ref(false)
-  const tempUserId = ref(null)
+  const challengeToken = ref(null)
+  const linkToken = ref(null)
 
   const isAuthenticated = computed(() => !!token.value)
-  const isAdmin = computed(() => user.value?.is_admin || false)
+  const permissions = computed(() => user.value?.permissions || [])
+  const canManageCatalog = computed(() =>
+    hasPermission('books:write') || hasPermission('authors:write')
+  )
+
+  function hasPermission(permission) {
+    return permissions.value.includes(permission)
+  }
 
   function setAuth(authData) {
     token.value = authData.tokens.access_token
     user.value = authData.user
     localStorage.setItem('access_token', authData.tokens.access_token)
     localStorage.setItem('user', JSON.stringify(authData.user))
+    linkPendingIdentity()
+  }
+
+  // Accounts without a password confirm a provider link by signing in
+  // another way; the link request waits in sessionStorage until then
+  function keepLinkForSignIn() {
+    sessionStorage.setItem('pending_link_token', linkToken.value)
+    linkToken.value = null
+  }
+
+  async function linkPendingIdentity() {
+    const pending = sessionStorage.getItem('pending_link_token')
+    if (!pending) return
+
+    sessionStorage.removeItem('pending_link_token')
+    try {
+      await authAPI.linkOIDCIdentity(pending)
+    } catch (error) {
+      // The request expired or belongs to another account; nothing to link
+    }
   }
 
   async function register(credentials) {
@@ -23,31 +51,68 @@ export const useAuthStore = defineStore('auth', () => {
     return response.data
   }
 
-  async function login(credentials) {
-    const response = await authAPI.login(credentials)
-
-    if (response.data.data.requires_2fa) {
+  function handleLoginResponse(data) {
+    if (data.requires_2fa) {
       requires2FA.value = true
-      tempUserId.value = response.data.data.user_id
+      challengeToken.value = data.challenge_token
       return { requires2FA: true }
     }
 
-    setAuth(response.data.data)
+    setAuth(data)
     return { requires2FA: false }
   }
 
+  async function login(credentials) {
+    const response = await authAPI.login(credentials)
+    return handleLoginResponse(response.data.data)
+  }
+
+  async function startOIDCLogin(provider) {
+    const response = await authAPI.startOIDC(provider)
+    window.location.href = response.data.data.authorization_url
+  }
+
+  async function completeOIDCLogin(provider, params) {
+    const response = await authAPI.completeOIDC(provider, params)
+    const data = response.data.data
+
+    if (data.requires_link_confirmation) {
+      linkToken.value = data.link_token
+      return { requiresLink: true, email: data.email }
+    }
+
+    return handleLoginResponse(data)
+  }
+
+  async function confirmOIDCLink(password) {
+    const response = await authAPI.confirmOIDCLink(linkToken.value, password)
+    linkToken.value = null
+    return handleLoginResponse(response.data.data)
+  }
+
   async function verify2FALogin(code) {
-    const response = await authAPI.verify2FALogin(tempUserId.value, code)
-    setAuth({ tokens: response.data.data.tokens, user: user.value })
+    const response = await authAPI.verify2FALogin(challengeToken.value, code)
+    setAuth(response.data.data)
     requires2FA.value = false
-    tempUserId.value = null
+    challengeToken.value = null
   }
 
   async function logout() {
+    if (token.value) {
+      try {
+        await authAPI.logout()
+      } catch (error) {
+        // The session may already be gone; clear local state anyway
+      }
+    }
+    clearAuth()
+  }
+
+  function clearAuth() {
     token.value = null
     user.value = null
     requires2FA.value = false
-    tempUserId.value = null
+    challengeToken.value = null
     localStorage.removeItem('access_token')
     localStorage.removeItem('user')
   }
@@ -62,16 +127,47 @@ export const useAuthStore = defineStore('auth', () => {
     }
   }
 
+  function setUser(data) {
+    user.value = data
+    localStorage.setItem('user', JSON.stringify(data))
+  }
+
+  async function updateProfile(data) {
+    const response = await authAPI.updateMe(data)
+    setUser(response.data.data)
+    return response.data.data
+  }
+
+  async function uploadAvatar(file) {
+    const response = await authAPI.uploadAvatar(file)
+    setUser({ ...user.value, avatar_url: response.data.data.avatar_url })
+  }
+
+  async function deleteAccount(data) {
+    const response = await authAPI.deleteMe(data)
+    // Every session was revoked, so there is nothing left to log out
+    clearAuth()
+    return response.data.data
+  }
+
+  async function changePassword(data) {
+    const response = await authAPI.changePassword(data)
+    // Every other session was revoked, continue with the new one
+    token.value = response.data.data.tokens.access_token
+    localStorage.setItem('access_token', token.value)
+  }
+
   async function setup2FA() {
     const response = await authAPI.setup2FA()
     return response.data.data
   }
 
   async function verify2FASetup(code) {
-    await authAPI.verify2FASetup(code)
+    const response = await authAPI.verify2FASetup(code)
     if (user.value) {
       user.value.two_factor_enabled = true
     }
+    return response.data.data.recovery_codes
   }
 
   async function disable2FA(code) {
@@ -96,12 +192,22 @@ export const useAuthStore = defineStore('auth', () => {
     token,
     requires2FA,
     isAuthenticated,
-    isAdmin,
+    permissions,
+    canManageCatalog,
+    hasPermission,
     register,
     login,
+    startOIDCLogin,
+    completeOIDCLogin,
+    confirmOIDCLink,
+    keepLinkForSignIn,
     verify2FALogin,
     logout,
     fetchUser,
+    updateProfile,
+    uploadAvatar,
+    deleteAccount,
+    changePassword,
     setup2FA,
     verify2FASetup,
     disable2FA
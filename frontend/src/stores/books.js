@@ -4,34 +4,60 @@ import { booksAPI, authorsAPI } from '@/services/api'
 
 export const useBooksStore = defineStore('books', () => {
   const books = ref([])
+  const total = ref(0)
+  const facets = ref(null)
+  const nextCursor = ref('')
+  const prevCursor = ref('')
   const currentBook = ref(null)
   const authors = ref([])
   const loading = ref(false)
   const error = ref(null)
 
-  async function fetchBooks(page = 1, pageSize = 20) {
+  function setPage(page) {
+    books.value = page.items
+    total.value = page.total
+    nextCursor.value = page.next_cursor || ''
+    prevCursor.value = page.prev_cursor || ''
+  }
+
+  function clearPage() {
+    books.value = []
+    total.value = 0
+    nextCursor.value = ''
+    prevCursor.value = ''
+  }
+
+  // filters holds the optional filter and sort query parameters, and cursor
+  // is the next_cursor or prev_cursor of the page shown
+  async function fetchBooks(pageSize = 20, filters = {}, cursor = '') {
     loading.value = true
     error.value = null
     try {
-      const response = await booksAPI.getAll({ page, page_size: pageSize })
-      books.value = response.data.data || []
+      const params = { ...filters, page_size: pageSize }
+      if (cursor) params.cursor = cursor
+      const response = await booksAPI.getAll(params)
+      setPage(response.data.data)
+      facets.value = response.data.data.facets
     } catch (err) {
       error.value = err.message
-      books.value = []
+      clearPage()
+      facets.value = null
     } finally {
       loading.value = false
     }
   }
 
-  async function searchBooks(query, page = 1, pageSize = 20) {
+  async function searchBooks(query, pageSize = 20, cursor = '') {
     loading.value = true
     error.value = null
     try {
-      const response = await booksAPI.search(query, { page, page_size: pageSize })
-      books.value = response.data.data || []
+      const params = { page_size: pageSize }
+      if (cursor) params.cursor = cursor
+      const response = await booksAPI.search(query, params)
+      setPage(response.data.data)
     } catch (err) {
       error.value = err.message
-      books.value = []
+      clearPage()
     } finally {
       loading.value = false
     }
@@ -108,10 +134,10 @@ export const useBooksStore = defineStore('books', () => {
     }
   }
 
-  async function fetchAuthors(page = 1, pageSize = 50) {
+  async function fetchAuthors(pageSize = 100) {
     try {
-      const response = await authorsAPI.getAll({ page, page_size: pageSize })
-      authors.value = response.data.data || []
+      const response = await authorsAPI.getAll({ page_size: pageSize })
+      authors.value = response.data.data.items
     } catch (err) {
       error.value = err.message
       authors.value = []
@@ -131,6 +157,10 @@ export const useBooksStore = defineStore('books', () => {
 
   return {
     books,
+    total,
+    facets,
+    nextCursor,
+    prevCursor,
     currentBook,
     authors,
     loading,
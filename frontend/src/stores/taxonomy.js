@@ -0,0 +1,97 @@
+import { defineStore } from 'pinia'
+import { ref, computed } from 'vue'
+import { taxonomyAPI } from '@/services/api'
+
+export const useTaxonomyStore = defineStore('taxonomy', () => {
+  const genres = ref([])
+  const tags = ref([])
+  const loading = ref(false)
+  const error = ref(null)
+
+  // Genres in tree order, each with its depth below the top level, for
+  // indented lists and selects
+  const genreTree = computed(() => {
+    const children = new Map()
+    for (const genre of genres.value) {
+      const parent = genre.parent_id || 0
+      if (!children.has(parent)) children.set(parent, [])
+      children.get(parent).push(genre)
+    }
+
+    const ordered = []
+    const visit = (parent, depth) => {
+      for (const genre of children.get(parent) || []) {
+        ordered.push({ ...genre, depth })
+        visit(genre.id, depth + 1)
+      }
+    }
+    visit(0, 0)
+    return ordered
+  })
+
+  async function fetchGenres() {
+    loading.value = true
+    error.value = null
+    try {
+      const response = await taxonomyAPI.getGenres()
+      genres.value = response.data.data.items
+    } catch (err) {
+      error.value = err.message
+      genres.value = []
+    } finally {
+      loading.value = false
+    }
+  }
+
+  async function fetchTags(pageSize = 100, query = '') {
+    loading.value = true
+    error.value = null
+    try {
+      const params = { page_size: pageSize }
+      if (query) params.q = query
+      const response = await taxonomyAPI.getTags(params)
+      tags.value = response.data.data.items
+    } catch (err) {
+      error.value = err.message
+      tags.value = []
+    } finally {
+      loading.value = false
+    }
+  }
+
+  async function saveGenre(id, data) {
+    const response = id ? await taxonomyAPI.updateGenre(id, data) : await taxonomyAPI.createGenre(data)
+    await fetchGenres()
+    return response.data
+  }
+
+  async function deleteGenre(id) {
+    await taxonomyAPI.deleteGenre(id)
+    genres.value = genres.value.filter(g => g.id !== id)
+  }
+
+  async function saveTag(id, data) {
+    const response = id ? await taxonomyAPI.updateTag(id, data) : await taxonomyAPI.createTag(data)
+    await fetchTags()
+    return response.data
+  }
+
+  async function deleteTag(id) {
+    await taxonomyAPI.deleteTag(id)
+    tags.value = tags.value.filter(t => t.id !== id)
+  }
+
+  return {
+    genres,
+    tags,
+    loading,
+    error,
+    genreTree,
+    fetchGenres,
+    fetchTags,
+    saveGenre,
+    deleteGenre,
+    saveTag,
+    deleteTag
+  }
+})
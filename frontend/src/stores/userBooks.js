@@ -6,15 +6,23 @@ export const useUserBooksStore = defineStore('userBooks', () => {
   const readingList = ref([])
   const favorites = ref([])
   const comments = ref([])
+
+  // Cursors of the next page of each list, empty once everything is loaded
+  const readingListCursor = ref('')
+  const favoritesCursor = ref('')
+  const commentsCursor = ref('')
   const loading = ref(false)
   const error = ref(null)
 
-  async function fetchReadingList(status = '') {
+  // Passing the cursor of the next page appends it to the list
+  async function fetchReadingList(status = '', cursor = '') {
     loading.value = true
     error.value = null
     try {
-      const response = await userBooksAPI.getReadingList(status)
-      readingList.value = response.data.data || []
+      const response = await userBooksAPI.getReadingList(pageParams({ status }, cursor))
+      const page = response.data.data
+      readingList.value = cursor ? [...readingList.value, ...page.items] : page.items
+      readingListCursor.value = page.next_cursor || ''
     } catch (err) {
       error.value = err.message
       readingList.value = []
@@ -23,9 +31,9 @@ export const useUserBooksStore = defineStore('userBooks', () => {
     }
   }
 
-  async function addToReadingList(bookId, status) {
+  async function addToReadingList(bookId, status, editionId = null) {
     try {
-      await userBooksAPI.addToReadingList(bookId, status)
+      await userBooksAPI.addToReadingList(bookId, status, editionId)
       await fetchReadingList()
     } catch (err) {
       error.value = err.message
@@ -43,12 +51,14 @@ export const useUserBooksStore = defineStore('userBooks', () => {
     }
   }
 
-  async function fetchFavorites() {
+  async function fetchFavorites(cursor = '') {
     loading.value = true
     error.value = null
     try {
-      const response = await userBooksAPI.getFavorites()
-      favorites.value = response.data.data || []
+      const response = await userBooksAPI.getFavorites(pageParams({}, cursor))
+      const page = response.data.data
+      favorites.value = cursor ? [...favorites.value, ...page.items] : page.items
+      favoritesCursor.value = page.next_cursor || ''
     } catch (err) {
       error.value = err.message
       favorites.value = []
@@ -77,12 +87,14 @@ export const useUserBooksStore = defineStore('userBooks', () => {
     }
   }
 
-  async function fetchBookComments(bookId) {
+  async function fetchBookComments(bookId, cursor = '') {
     loading.value = true
     error.value = null
     try {
-      const response = await commentsAPI.getBookComments(bookId)
-      comments.value = response.data.data || []
+      const response = await commentsAPI.getBookComments(bookId, pageParams({}, cursor))
+      const page = response.data.data
+      comments.value = cursor ? [...comments.value, ...page.items] : page.items
+      commentsCursor.value = page.next_cursor || ''
     } catch (err) {
       error.value = err.message
       comments.value = []
@@ -112,10 +124,17 @@ export const useUserBooksStore = defineStore('userBooks', () => {
     }
   }
 
+  function pageParams(params, cursor) {
+    return cursor ? { ...params, cursor } : params
+  }
+
   return {
     readingList,
     favorites,
     comments,
+    readingListCursor,
+    favoritesCursor,
+    commentsCursor,
     loading,
     error,
     fetchReadingList,